@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// pruneInterval is how often audit_retention_days and
+// channel_log_retention_days are applied
+const pruneInterval = 24 * time.Hour
+
+// AuditEntry records an administrative change to a showtime
+type AuditEntry struct {
+	At         time.Time
+	Actor      string
+	Action     string
+	ShowtimeID string
+	Details    string
+}
+
+// audit records an administrative action in the audit log. Failures are only
+// logged since the action itself has already happened.
+func (bot *command) audit(actor, action, showtimeID, details string) {
+	log.Printf("Audit: %s %s %s: %s", actor, action, showtimeID, details)
+
+	entry := AuditEntry{
+		At:         time.Now().UTC(),
+		Actor:      actor,
+		Action:     action,
+		ShowtimeID: showtimeID,
+		Details:    details,
+	}
+	if err := bot.store.Audit(entry); err != nil {
+		log.Printf("Error writing audit log: %v", err)
+	}
+}
+
+// runAuditPruning applies audit_retention_days once a day. The setting is
+// read on every pass so it can be enabled by a config reload.
+func (bot *CinemaBot) runAuditPruning() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		bot.mu.RLock()
+		bot.snapshot().pruneAudit(now.UTC())
+		bot.mu.RUnlock()
+	}
+}
+
+// pruneAudit deletes audit log entries older than audit_retention_days, doing
+// nothing when it is unset
+func (bot *command) pruneAudit(now time.Time) {
+	days := bot.config.AuditRetentionDays
+	if days <= 0 {
+		return
+	}
+
+	pruned, err := bot.store.PruneAudit(now.AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("Error pruning audit log: %v", err)
+		return
+	}
+	log.Printf("Pruned %s older than %s from the audit log", pluralize(pruned, "row"), pluralize(days, "day"))
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// defaultChannelLogRetentionDays is how long channel_log rows are kept when
+// channel_log_retention_days is unset
+const defaultChannelLogRetentionDays = 7
+
+// ChannelMessage is a line said in the channel, kept for moderation context
+// when log_channel_messages is on
+type ChannelMessage struct {
+	At      time.Time
+	Channel string
+	Nick    string
+	Host    string
+	Message string
+}
+
+// logChannelMessage records a channel message. Failures are only logged so a
+// database problem never stops commands from being answered.
+func (bot *CinemaBot) logChannelMessage(channel, nick, host, message string, at time.Time) {
+	entry := ChannelMessage{At: at, Channel: channel, Nick: nick, Host: host, Message: message}
+	if err := bot.store.LogChannelMessage(entry); err != nil {
+		log.Printf("Error writing channel log: %v", err)
+	}
+}
+
+// runChannelLogPruning applies channel_log_retention_days once a day. It runs
+// even with logging off so that turning it off doesn't keep old rows forever.
+func (bot *CinemaBot) runChannelLogPruning() {
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		bot.mu.RLock()
+		bot.snapshot().pruneChannelLog(now.UTC())
+		bot.mu.RUnlock()
+	}
+}
+
+// pruneChannelLog deletes channel log entries older than the retention period
+func (bot *command) pruneChannelLog(now time.Time) {
+	days := bot.config.ChannelLogRetentionDays
+	if days <= 0 {
+		days = defaultChannelLogRetentionDays
+	}
+
+	pruned, err := bot.store.PruneChannelLog(now.AddDate(0, 0, -days))
+	if err != nil {
+		log.Printf("Error pruning channel log: %v", err)
+		return
+	}
+	if pruned > 0 {
+		log.Printf("Pruned %s older than %s from the channel log", pluralize(pruned, "row"), pluralize(days, "day"))
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHandlePrivmsg_LogChannelMessages(t *testing.T) {
+	bot, _ := newTestBot()
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", "anyone seen Vertigo?")
+	if log := bot.store.(*memoryStore).channelLog; len(log) != 0 {
+		t.Fatalf("expected nothing logged by default, got %+v", log)
+	}
+
+	bot.config.LogChannelMessages = true
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", "anyone seen Vertigo?")
+	bot.handlePrivmsg("#testchan", "bob", "example.com", ".uptime")
+	bot.handlePrivmsg("#elsewhere", "carol", "example.org", "hello")
+
+	log := bot.store.(*memoryStore).channelLog
+	if len(log) != 2 {
+		t.Fatalf("expected both channel messages logged, got %+v", log)
+	}
+	if log[0].Channel != "#testchan" || log[0].Nick != "alice" || log[0].Host != "user/alice" || log[0].Message != "anyone seen Vertigo?" || log[0].At.IsZero() {
+		t.Errorf("unexpected entry %+v", log[0])
+	}
+	if log[1].Nick != "bob" || log[1].Message != ".uptime" {
+		t.Errorf("expected commands to be logged too, got %+v", log[1])
+	}
+}
+
+func TestPruneChannelLog(t *testing.T) {
+	bot, _ := newTestBot()
+	now := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	for _, age := range []int{30, 8, 6, 0} {
+		bot.store.LogChannelMessage(ChannelMessage{At: now.AddDate(0, 0, -age), Channel: "#testchan", Nick: "alice"})
+	}
+
+	bot.pruneChannelLog(now)
+	if log := bot.store.(*memoryStore).channelLog; len(log) != 2 {
+		t.Errorf("expected the default retention to keep 2 rows, got %+v", log)
+	}
+
+	bot.config.ChannelLogRetentionDays = 1
+	bot.pruneChannelLog(now)
+	if log := bot.store.(*memoryStore).channelLog; len(log) != 1 || !log[0].At.Equal(now) {
+		t.Errorf("expected only today's row, got %+v", log)
+	}
+}
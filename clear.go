@@ -0,0 +1,89 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+// clearTokenTTL is how long a -clear confirmation token stays valid
+const clearTokenTTL = 60 * time.Second
+
+// clearRequest is a -clear waiting for its confirmation token. Commands run
+// without holding mu, so it has its own lock. store is the database the token
+// was issued for, so it can't confirm a wipe of another one.
+type clearRequest struct {
+	mu      sync.Mutex
+	token   string
+	nick    string
+	expires time.Time
+	store   ShowtimeStore
+}
+
+// cancel forgets any pending token
+func (r *clearRequest) cancel() {
+	r.mu.Lock()
+	r.token = ""
+	r.store = nil
+	r.mu.Unlock()
+}
+
+// clearShowtimes deletes every showtime, but only in two steps: a bare -clear
+// replies with a random token, and the same nick must send -clear=token
+// within clearTokenTTL, against the same database, for the wipe to happen
+func (bot *command) clearShowtimes(args []string, nick string, now time.Time) {
+	token := flagValue(args, "-clear")
+	if token == "" {
+		bot.requestClear(nick, now)
+		return
+	}
+
+	bot.pendingClear.mu.Lock()
+	valid := bot.pendingClear.token != "" && token == bot.pendingClear.token &&
+		nick == bot.pendingClear.nick && now.Before(bot.pendingClear.expires) &&
+		bot.pendingClear.store == bot.store
+	if valid {
+		bot.pendingClear.token = ""
+		bot.pendingClear.store = nil
+	}
+	bot.pendingClear.mu.Unlock()
+
+	if !valid {
+		bot.sender.Privmsg(bot.config.Channel, "That confirmation token is wrong or has expired. Send .showtime -clear to get a new one.")
+		return
+	}
+
+	deleted, err := bot.store.DeleteAll()
+	if err != nil {
+		log.Printf("Error clearing showtimes: %v", err)
+		bot.replyError(err, "Error clearing showtimes.")
+		return
+	}
+
+	bot.audit(nick, "clear", "", fmt.Sprintf("deleted %d showtime(s)", deleted))
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Cleared the schedule: deleted %s.", pluralize(deleted, "showtime")))
+}
+
+// requestClear hands nick a fresh confirmation token, replacing any earlier one
+func (bot *command) requestClear(nick string, now time.Time) {
+	buf := make([]byte, 4)
+	if _, err := rand.Read(buf); err != nil {
+		log.Printf("Error generating clear token: %v", err)
+		bot.sender.Privmsg(bot.config.Channel, "Error generating a confirmation token.")
+		return
+	}
+	token := hex.EncodeToString(buf)
+
+	bot.pendingClear.mu.Lock()
+	bot.pendingClear.token = token
+	bot.pendingClear.nick = nick
+	bot.pendingClear.expires = now.Add(clearTokenTTL)
+	bot.pendingClear.store = bot.store
+	bot.pendingClear.mu.Unlock()
+
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: this deletes every showtime and reminder. To confirm, send .showtime -clear=%s within %s.",
+		nick, token, pluralize(int(clearTokenTTL.Seconds()), "second")))
+}
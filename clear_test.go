@@ -0,0 +1,97 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClearShowtimes(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: now, CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: now, CreatedBy: "bob"})
+	bot.store.AddReminder("a", "carol")
+
+	bot.clearShowtimes(bot.parseArgs(".showtime -clear"), "admin", now)
+	token := bot.pendingClear.token
+	if len(token) != 8 || !strings.Contains(sender.messages[0], ".showtime -clear="+token+" within 60 seconds") {
+		t.Fatalf("expected a confirmation token, got %q in %v", token, sender.messages)
+	}
+	if len(bot.store.(*memoryStore).showtimes) != 2 {
+		t.Fatal("expected nothing deleted before confirming")
+	}
+
+	bot.clearShowtimes(bot.parseArgs(".showtime -clear="+token), "admin", now.Add(30*time.Second))
+	if len(bot.store.(*memoryStore).showtimes) != 0 || len(bot.store.(*memoryStore).reminders) != 0 {
+		t.Error("expected every showtime and reminder deleted")
+	}
+	if sender.messages[1] != "Cleared the schedule: deleted 2 showtimes." {
+		t.Errorf("unexpected reply %q", sender.messages[1])
+	}
+	if audit := bot.store.(*memoryStore).audit; len(audit) != 1 || audit[0].Action != "clear" || audit[0].Details != "deleted 2 showtime(s)" {
+		t.Errorf("unexpected audit log %+v", audit)
+	}
+
+	bot.clearShowtimes(bot.parseArgs(".showtime -clear="+token), "admin", now.Add(40*time.Second))
+	if sender.messages[2] != "That confirmation token is wrong or has expired. Send .showtime -clear to get a new one." {
+		t.Errorf("expected a used token to be rejected, got %q", sender.messages[2])
+	}
+}
+
+func TestClearShowtimes_Rejected(t *testing.T) {
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		nick  string
+		token func(string) string
+		at    time.Time
+	}{
+		{"wrong token", "admin", func(string) string { return "deadbeef" }, now},
+		{"other nick", "mallory", func(token string) string { return token }, now},
+		{"expired", "admin", func(token string) string { return token }, now.Add(clearTokenTTL)},
+	}
+	for _, tt := range tests {
+		bot, _ := newTestBot()
+		bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: now, CreatedBy: "alice"})
+
+		bot.clearShowtimes(bot.parseArgs(".showtime -clear"), "admin", now)
+		bot.clearShowtimes(bot.parseArgs(fmt.Sprintf(".showtime -clear=%s", tt.token(bot.pendingClear.token))), tt.nick, tt.at)
+		if len(bot.store.(*memoryStore).showtimes) != 1 {
+			t.Errorf("%s: expected the schedule to survive", tt.name)
+		}
+	}
+}
+
+func TestClearShowtimes_OtherDatabase(t *testing.T) {
+	bot, sender := newSQLiteTestBot(t)
+	bot.config.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"admin": true}}
+	bot.config.StagingDatabasePath = filepath.Join(t.TempDir(), "staging.db")
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2099, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.handlePrivmsg("#testchan", "admin", "user/admin", ".staging on")
+	bot.handlePrivmsg("#testchan", "admin", "user/admin", ".showtime -clear")
+	token := bot.pendingClear.token
+	if token == "" {
+		t.Fatalf("expected a token on staging, got %v", sender.messages)
+	}
+	bot.handlePrivmsg("#testchan", "admin", "user/admin", ".staging off")
+	bot.handlePrivmsg("#testchan", "admin", "user/admin", ".showtime -clear="+token)
+
+	if existing, _ := bot.store.GetByID("a"); existing == nil {
+		t.Error("expected a token issued on staging not to clear production")
+	}
+	if last := sender.messages[len(sender.messages)-1]; !strings.HasPrefix(last, "That confirmation token is wrong") {
+		t.Errorf("expected the token to be rejected, got %q", last)
+	}
+
+	// Without toggling in between, a token still only works on its own store
+	other := &command{CinemaBot: bot.CinemaBot, sender: sender, store: newMemoryStore(), config: bot.config}
+	other.clearShowtimes(bot.parseArgs(".showtime -clear"), "admin", time.Now())
+	bot.clearShowtimes(bot.parseArgs(".showtime -clear="+bot.pendingClear.token), "admin", time.Now())
+	if existing, _ := bot.store.GetByID("a"); existing == nil {
+		t.Error("expected a token to be refused against another store")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+// clockCheckTimeout is how long a .clockcheck waits for the server's
+// RPL_TIME before a late reply is ignored
+const clockCheckTimeout = 30 * time.Second
+
+// clockCheck is a .clockcheck waiting for RPL_TIME. The reply arrives on the
+// IRC goroutine, so it has its own lock. sender is the command's, so the
+// answer follows the quiet and staging state the check was asked in.
+type clockCheck struct {
+	mu     sync.Mutex
+	sentAt time.Time
+	sender Sender
+}
+
+// serverTimeLayouts are the RPL_TIME formats of common servers, tried in order
+// after normalizing whitespace; layouts without a zone are read as UTC
+var serverTimeLayouts = []struct {
+	layout    string
+	precision time.Duration
+}{
+	{"Monday January 2 2006 -- 15:04:05 -07:00", time.Second},
+	{"Monday January 2 2006 -- 15:04 -07:00", time.Minute},
+	{"Mon Jan 2 2006 15:04:05", time.Second},
+	{time.UnixDate, time.Second},
+	{time.RFC1123Z, time.Second},
+	{time.RFC3339, time.Second},
+}
+
+// handleClockCheckCommand asks the server for its time with TIME; the answer
+// is reported by handleTimeReply
+func (bot *command) handleClockCheckCommand(now time.Time) {
+	if bot.conn == nil || !bot.conn.Connected() {
+		bot.sender.Privmsg(bot.config.Channel, "Not connected to a server, so there's no clock to compare with.")
+		return
+	}
+
+	bot.pendingClockCheck.mu.Lock()
+	bot.pendingClockCheck.sentAt = now
+	bot.pendingClockCheck.sender = bot.sender
+	bot.pendingClockCheck.mu.Unlock()
+	bot.conn.SendRaw("TIME")
+}
+
+// handleTimeReply answers a pending .clockcheck with the server's RPL_TIME
+func (bot *CinemaBot) handleTimeReply(e *irc.Event) {
+	received := time.Now().UTC()
+
+	bot.mu.RLock()
+	defer bot.mu.RUnlock()
+	bot.snapshot().reportClockSkew(e.Arguments, received)
+}
+
+// reportClockSkew compares the time in an RPL_TIME reply's arguments with our
+// clock halfway between sending TIME and receiving the reply
+func (bot *command) reportClockSkew(args []string, received time.Time) {
+	bot.pendingClockCheck.mu.Lock()
+	sentAt, sender := bot.pendingClockCheck.sentAt, bot.pendingClockCheck.sender
+	bot.pendingClockCheck.sentAt, bot.pendingClockCheck.sender = time.Time{}, nil
+	bot.pendingClockCheck.mu.Unlock()
+	if sentAt.IsZero() || received.Sub(sentAt) > clockCheckTimeout {
+		return
+	}
+
+	if len(args) < 3 {
+		sender.Privmsg(bot.config.Channel, "The server's TIME reply was empty.")
+		return
+	}
+	server := args[1]
+	serverTime, precision, ok := parseServerTime(args[2:])
+	if !ok {
+		log.Printf("Unrecognized RPL_TIME from %s: %q", server, args[2:])
+		sender.Privmsg(bot.config.Channel, fmt.Sprintf("Couldn't read the time %s sent: %s", server, args[len(args)-1]))
+		return
+	}
+
+	roundTrip := received.Sub(sentAt)
+	skew := sentAt.Add(roundTrip / 2).Sub(serverTime)
+	unit := "second"
+	if precision == time.Minute {
+		unit = "minute"
+	}
+	var verdict string
+	switch {
+	case skew.Abs() < precision:
+		verdict = "the clocks agree to within a " + unit
+	case skew > 0:
+		verdict = fmt.Sprintf("our clock is %s ahead", skew.Round(time.Millisecond))
+	default:
+		verdict = fmt.Sprintf("our clock is %s behind", (-skew).Round(time.Millisecond))
+	}
+	sender.Privmsg(bot.config.Channel, fmt.Sprintf("Clock check against %s: %s (round trip %s).", server, verdict, roundTrip.Round(time.Millisecond)))
+}
+
+// parseServerTime reads RPL_TIME arguments after the server name: a Unix
+// timestamp when the server includes one, otherwise the human readable text
+func parseServerTime(args []string) (t time.Time, precision time.Duration, ok bool) {
+	for _, arg := range args[:len(args)-1] {
+		if seconds, err := strconv.ParseInt(arg, 10, 64); err == nil && seconds > 1e9 {
+			return time.Unix(seconds, 0).UTC(), time.Second, true
+		}
+	}
+
+	text := strings.Join(strings.Fields(args[len(args)-1]), " ")
+	for _, format := range serverTimeLayouts {
+		if parsed, err := time.Parse(format.layout, text); err == nil {
+			return parsed.UTC(), format.precision, true
+		}
+	}
+	return time.Time{}, 0, false
+}
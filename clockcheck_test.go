@@ -0,0 +1,93 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+func TestParseServerTime(t *testing.T) {
+	expected := time.Date(2026, 10, 15, 8, 40, 12, 0, time.UTC)
+	tests := []struct {
+		args      []string
+		precision time.Duration
+	}{
+		{[]string{"Thursday October 15 2026 -- 10:40:12 +02:00"}, time.Second},
+		{[]string{"Thu Oct 15 2026 08:40:12"}, time.Second},
+		{[]string{"1792053612", "0", "Thursday October 15 2026 -- 08:40 +00:00"}, time.Second},
+	}
+	for _, tt := range tests {
+		got, precision, ok := parseServerTime(tt.args)
+		if !ok || !got.Equal(expected) || precision != tt.precision {
+			t.Errorf("parseServerTime(%q): expected %v, got %v (%v, %v)", tt.args, expected, got, precision, ok)
+		}
+	}
+
+	if got, precision, ok := parseServerTime([]string{"Thursday October 15 2026 -- 08:40 +00:00"}); !ok || !got.Equal(expected.Truncate(time.Minute)) || precision != time.Minute {
+		t.Errorf("expected minute precision, got %v (%v, %v)", got, precision, ok)
+	}
+	if _, _, ok := parseServerTime([]string{"teatime"}); ok {
+		t.Errorf("expected unrecognized text to fail")
+	}
+}
+
+func TestReportClockSkew(t *testing.T) {
+	bot, sender := newTestBot()
+	server := time.Date(2026, 10, 15, 8, 40, 12, 0, time.UTC)
+	reply := []string{"cinemabot", "irc.example.net", "Thu Oct 15 2026 08:40:12"}
+
+	pending := func(sentAt time.Time) {
+		bot.pendingClockCheck.sentAt = sentAt
+		bot.pendingClockCheck.sender = sender
+	}
+
+	bot.reportClockSkew(reply, server)
+	pending(server.Add(4*time.Second + 400*time.Millisecond))
+	bot.reportClockSkew(reply, server.Add(4*time.Second+600*time.Millisecond))
+	pending(server.Add(-3 * time.Second))
+	bot.reportClockSkew(reply, server.Add(-3*time.Second+100*time.Millisecond))
+	pending(server.Add(-200 * time.Millisecond))
+	bot.reportClockSkew(reply, server.Add(200*time.Millisecond))
+
+	expected := []string{
+		"Clock check against irc.example.net: our clock is 4.5s ahead (round trip 200ms).",
+		"Clock check against irc.example.net: our clock is 2.95s behind (round trip 100ms).",
+		"Clock check against irc.example.net: the clocks agree to within a second (round trip 400ms).",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleClockCheckCommand_NotConnected(t *testing.T) {
+	bot, sender := newTestBot()
+
+	bot.handleClockCheckCommand(time.Now().UTC())
+
+	expected := []string{"Not connected to a server, so there's no clock to compare with."}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleTimeReply_CommandSender(t *testing.T) {
+	bot, sender := newTestBot()
+	reply := []string{"cinemabot", "irc.example.net", time.Now().UTC().Format("Mon Jan 2 2006 15:04:05")}
+
+	// Asked while quiet: nothing is said, even once quiet mode is over
+	bot.pendingClockCheck.sentAt = time.Now().UTC()
+	bot.pendingClockCheck.sender = discardSender{}
+	bot.handleTimeReply(&irc.Event{Code: "391", Arguments: reply})
+	if len(sender.messages) != 0 {
+		t.Errorf("expected no reply to a check made while quiet, got %v", sender.messages)
+	}
+
+	bot.pendingClockCheck.sentAt = time.Now().UTC()
+	bot.pendingClockCheck.sender = taggedSender{sender, stagingTag}
+	bot.handleTimeReply(&irc.Event{Code: "391", Arguments: reply})
+	if len(sender.messages) != 1 || !strings.HasPrefix(sender.messages[0], stagingTag+"Clock check against irc.example.net") {
+		t.Errorf("expected a tagged reply to a check made on staging, got %v", sender.messages)
+	}
+}
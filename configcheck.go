@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templatePlaceholder matches {name} placeholders in message templates
+var templatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+
+// checkConfig loads configFile the way the bot does and returns every
+// problem found with it. Nothing is opened or connected.
+func checkConfig(configFile string) []string {
+	bot := &CinemaBot{configFile: configFile}
+	if err := bot.loadConfig(configFile); err != nil {
+		return []string{err.Error()}
+	}
+	return bot.config.problems()
+}
+
+// problems reports settings that load fine but can't work
+func (c Config) problems() []string {
+	var problems []string
+
+	if host, port, err := net.SplitHostPort(c.Server); err != nil {
+		problems = append(problems, fmt.Sprintf("server %q is not host:port", c.Server))
+	} else if host == "" {
+		problems = append(problems, fmt.Sprintf("server %q has no host", c.Server))
+	} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		problems = append(problems, fmt.Sprintf("server %q has an invalid port", c.Server))
+	}
+
+	if c.Nick == "" || strings.ContainsAny(c.Nick, " ,*?!@") {
+		problems = append(problems, fmt.Sprintf("nick %q is not a valid IRC nick", c.Nick))
+	}
+	for _, nick := range c.AlternateNicks {
+		if nick == "" || strings.ContainsAny(nick, " ,*?!@") {
+			problems = append(problems, fmt.Sprintf("alternate nick %q is not a valid IRC nick", nick))
+		}
+	}
+	if !isChannelName(c.Channel) || strings.ContainsAny(c.Channel, " ,\a") {
+		problems = append(problems, fmt.Sprintf("channel %q is not a valid channel name", c.Channel))
+	}
+	for _, channel := range c.InviteChannels {
+		if !isChannelName(channel) {
+			problems = append(problems, fmt.Sprintf("invite_channels entry %q is not a channel", channel))
+		}
+	}
+	if c.NotifyChannel != "" && !isChannelName(c.NotifyChannel) {
+		problems = append(problems, fmt.Sprintf("notify_channel %q is not a channel", c.NotifyChannel))
+	}
+	for channel := range c.ChannelCommands {
+		if !isChannelName(channel) {
+			problems = append(problems, fmt.Sprintf("channel_commands key %q is not a channel", channel))
+		}
+	}
+
+	for _, command := range []struct {
+		name     string
+		template string
+	}{
+		{"identify_command", c.NickServ.IdentifyCommand},
+		{"ghost_command", c.NickServ.GhostCommand},
+	} {
+		for _, placeholder := range templatePlaceholder.FindAllString(command.template, -1) {
+			if placeholder != "{nick}" && placeholder != "{password}" {
+				problems = append(problems, fmt.Sprintf("nickserv.%s has unknown placeholder %s", command.name, placeholder))
+			}
+		}
+	}
+	if c.NickServ.IdentifyCommand != "" && c.NickServ.Password != "" &&
+		!strings.Contains(c.NickServ.IdentifyCommand, "{password}") {
+		problems = append(problems, "nickserv.identify_command does not use {password}")
+	}
+
+	for _, hook := range c.Webhooks {
+		if parsed, err := url.Parse(hook); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problems = append(problems, fmt.Sprintf("webhook %q is not an http(s) URL", hook))
+		}
+	}
+
+	if c.StagingDatabasePath != "" && c.StagingDatabasePath == c.DatabasePath {
+		problems = append(problems, "staging_database_path is the same file as database_path")
+	}
+
+	if c.PublicBaseURL != "" {
+		if parsed, err := url.Parse(c.PublicBaseURL); err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			problems = append(problems, fmt.Sprintf("public_base_url %q is not an http(s) URL", c.PublicBaseURL))
+		}
+	}
+
+	if c.JustStartedSeconds != nil && *c.JustStartedSeconds < 0 {
+		problems = append(problems, "just_started_seconds must not be negative")
+	}
+	for _, setting := range []struct {
+		name  string
+		value int
+	}{
+		{"ping_timeout_seconds", c.PingTimeoutSeconds},
+		{"query_timeout_seconds", c.QueryTimeoutSeconds},
+		{"keepalive_seconds", c.KeepAliveSeconds},
+		{"current_window_hours", c.CurrentWindowHours},
+		{"soon_threshold_minutes", c.SoonThresholdMinutes},
+		{"rows_per_message", c.RowsPerMessage},
+		{"max_list_entries", c.MaxListEntries},
+		{"maintenance_interval_hours", c.MaintenanceIntervalHours},
+		{"inactivity_reminder_days", c.InactivityReminderDays},
+		{"max_id_length", c.MaxIDLength},
+		{"audit_retention_days", c.AuditRetentionDays},
+		{"channel_log_retention_days", c.ChannelLogRetentionDays},
+		{"slow_command_ms", c.SlowCommandMillis},
+	} {
+		if setting.value < 0 {
+			problems = append(problems, fmt.Sprintf("%s must not be negative", setting.name))
+		}
+	}
+
+	return problems
+}
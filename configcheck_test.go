@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTestConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestCheckConfig_Valid(t *testing.T) {
+	path := writeTestConfig(t, "config.json", `{
+		"server": "irc.example.com:6697",
+		"nick": "testbot",
+		"channel": "#testchan",
+		"nickserv": { "password": "secret", "identify_command": "IDENTIFY {nick} {password}" },
+		"webhooks": ["https://example.com/hook"],
+		"display_timezone": "Europe/London"
+	}`)
+
+	if problems := checkConfig(path); len(problems) != 0 {
+		t.Errorf("expected no problems, got %v", problems)
+	}
+}
+
+func TestCheckConfig_Problems(t *testing.T) {
+	path := writeTestConfig(t, "config.yaml", `
+server: irc.example.com
+nick: "bad nick"
+alternate_nicks: ["bot!"]
+channel: testchan
+nickserv:
+  password: secret
+  identify_command: "IDENTIFY {pass}"
+  ghost_command: "GHOST {user} {password}"
+invite_channels: [movies]
+channel_commands:
+  general: [showtime]
+webhooks: ["ftp://example.com"]
+rows_per_message: -1
+`)
+
+	expected := []string{
+		`server "irc.example.com" is not host:port`,
+		`nick "bad nick" is not a valid IRC nick`,
+		`alternate nick "bot!" is not a valid IRC nick`,
+		`channel "testchan" is not a valid channel name`,
+		`invite_channels entry "movies" is not a channel`,
+		`channel_commands key "general" is not a channel`,
+		`nickserv.identify_command has unknown placeholder {pass}`,
+		`nickserv.ghost_command has unknown placeholder {user}`,
+		`nickserv.identify_command does not use {password}`,
+		`webhook "ftp://example.com" is not an http(s) URL`,
+		`rows_per_message must not be negative`,
+	}
+	if problems := checkConfig(path); !equalStringSlices(problems, expected) {
+		t.Errorf("expected %v, got %v", expected, problems)
+	}
+}
+
+func TestCheckConfig_LoadError(t *testing.T) {
+	path := writeTestConfig(t, "config.json", `{"server": "irc.example.com:6667", "display_timezone": "Mars/Olympus"}`)
+
+	problems := checkConfig(path)
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "invalid display_timezone") {
+		t.Errorf("expected a display_timezone problem, got %v", problems)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// digestInterval is how often the digest loop checks whether it is time to
+// post; the digest goes out on the first check at or after digest_time
+const digestInterval = time.Minute
+
+// runDigest posts the daily digest at digest_time. The settings are read on
+// every pass so the digest can be enabled or moved by a config reload.
+func (bot *CinemaBot) runDigest() {
+	ticker := time.NewTicker(digestInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		now = now.UTC()
+		bot.mu.RLock()
+		if cmd := bot.snapshot(); cmd.digestDue(now) {
+			cmd.postDigest(now)
+		}
+		bot.mu.RUnlock()
+	}
+}
+
+// digestDue reports whether now falls in the digestInterval starting at
+// today's digest_time
+func (bot *command) digestDue(now time.Time) bool {
+	if bot.config.DigestTime == "" {
+		return false
+	}
+	clock, err := time.Parse("15:04", bot.config.DigestTime)
+	if err != nil {
+		return false
+	}
+	at := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+	return !now.Before(at) && now.Before(at.Add(digestInterval))
+}
+
+// postDigest sends a one-line summary of the showtimes in the 24 hours from
+// now. An empty day is only announced with digest_when_empty.
+func (bot *command) postDigest(now time.Time) {
+	if bot.quiet(now) {
+		return
+	}
+
+	showtimes, err := bot.store.List(ShowtimeFilter{From: now, To: now.Add(24*time.Hour - time.Second)})
+	if err != nil {
+		log.Printf("Error listing showtimes for digest: %v", err)
+		return
+	}
+	scheduled := showtimes[:0]
+	for _, showtime := range showtimes {
+		if !showtime.Cancelled {
+			scheduled = append(scheduled, showtime)
+		}
+	}
+	showtimes = scheduled
+
+	if len(showtimes) == 0 {
+		if bot.config.DigestWhenEmpty {
+			bot.sender.Privmsg(bot.config.Channel, "Nothing scheduled in the next 24 hours.")
+		}
+		return
+	}
+
+	items := make([]string, len(showtimes))
+	for i, showtime := range showtimes {
+		items[i] = fmt.Sprintf("[%s] %s at %s", showtime.ID, showtime.Title,
+			showtime.DateTime.In(bot.displayLocation()).Format("15:04 MST"))
+	}
+	items[0] = "Coming up in the next 24 hours: " + items[0]
+	for _, chunk := range chunkItems(items, " | ", bot.channelBytes()) {
+		bot.sender.Privmsg(bot.config.Channel, chunk)
+	}
+}
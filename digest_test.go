@@ -0,0 +1,62 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDigestDue(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	morning := time.Date(2025, 6, 13, 9, 0, 0, 0, time.UTC)
+	if bot.digestDue(morning) {
+		t.Error("expected no digest without digest_time")
+	}
+
+	bot.config.DigestTime = "09:00"
+	tests := []struct {
+		now      time.Time
+		expected bool
+	}{
+		{morning.Add(-time.Second), false},
+		{morning, true},
+		{morning.Add(59 * time.Second), true},
+		{morning.Add(digestInterval), false},
+		{morning.Add(12 * time.Hour), false},
+	}
+	for _, tt := range tests {
+		if got := bot.digestDue(tt.now); got != tt.expected {
+			t.Errorf("digestDue(%s): expected %v, got %v", tt.now.Format("15:04:05"), tt.expected, got)
+		}
+	}
+}
+
+func TestPostDigest(t *testing.T) {
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, time.UTC)
+
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: now.Add(10 * time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: now.Add(13 * time.Hour), CreatedBy: "bob"})
+	bot.store.Create(Showtime{ID: "c", Title: "Psycho", DateTime: now.Add(30 * time.Hour), CreatedBy: "bob"})
+	bot.postDigest(now)
+
+	expected := []string{"Coming up in the next 24 hours: [a] Casablanca at 19:00 UTC | [b] Vertigo at 22:00 UTC"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestPostDigest_Empty(t *testing.T) {
+	now := time.Date(2025, 6, 13, 9, 0, 0, 0, time.UTC)
+
+	bot, sender := newTestBot()
+	bot.postDigest(now)
+	if len(sender.messages) != 0 {
+		t.Errorf("expected silence on an empty day, got %v", sender.messages)
+	}
+
+	bot.config.DigestWhenEmpty = true
+	bot.postDigest(now)
+	if expected := []string{"Nothing scheduled in the next 24 hours."}; !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
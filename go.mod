@@ -5,6 +5,7 @@ go 1.20
 require (
 	github.com/mattn/go-sqlite3 v1.14.28
 	github.com/thoj/go-ircevent v0.0.0-20210723090443-73e444401d64
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -0,0 +1,218 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var icsClient = &http.Client{Timeout: 10 * time.Second}
+
+// maxICSBytes bounds how much of a calendar is read
+const maxICSBytes = 5 << 20
+
+// icsEvent is the subset of a VEVENT the importer uses
+type icsEvent struct {
+	UID     string
+	Summary string
+	Start   time.Time
+}
+
+// importICS loads VEVENTs from a URL or local path into showtimes created by
+// nick, skipping events without a usable start and ids that already exist.
+// UIDs that aren't valid ids are slugified, or skipped when even that fails.
+func (bot *command) importICS(args []string, nick string) {
+	source := flagValue(args, "-import-ics")
+	if source == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -import-ics=\"url or path\"")
+		return
+	}
+
+	events, unusable, err := readICS(source)
+	if err != nil {
+		log.Printf("Error importing %s: %v", source, err)
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Error reading calendar: %v", err))
+		return
+	}
+
+	now := time.Now().UTC()
+	var showtimes []Showtime
+	for _, event := range events {
+		title := bot.sanitizeTitle(event.Summary)
+		if title == "" {
+			unusable++
+			continue
+		}
+		// UIDs such as "abc123@google.com" rarely make valid ids as they are
+		id := bot.storableID(event.UID, title)
+		if id == "" {
+			unusable++
+			continue
+		}
+		showtimes = append(showtimes, Showtime{
+			ID:        id,
+			Title:     title,
+			DateTime:  event.Start,
+			CreatedBy: nick,
+			CreatedAt: now,
+		})
+	}
+
+	created, err := bot.store.CreateAll(showtimes)
+	if err != nil {
+		log.Printf("Error importing showtimes: %v", err)
+		bot.replyError(err, "Error importing showtimes.")
+		return
+	}
+
+	message := fmt.Sprintf("Imported %s.", pluralize(len(created), "showtime"))
+	if existing := len(showtimes) - len(created); existing > 0 {
+		message += fmt.Sprintf(" Skipped %d with existing ids.", existing)
+	}
+	if unusable > 0 {
+		message += fmt.Sprintf(" Skipped %d without a title, id or start time.", unusable)
+	}
+	bot.sender.Privmsg(bot.config.Channel, message)
+
+	for _, showtime := range created {
+		bot.notify(Event{Kind: "created", Showtime: showtime})
+	}
+}
+
+// readICS fetches source over HTTP(S) or reads it from disk and parses it
+func readICS(source string) ([]icsEvent, int, error) {
+	var body io.ReadCloser
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		resp, err := icsClient.Get(source)
+		if err != nil {
+			return nil, 0, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, 0, fmt.Errorf("unexpected status %s", resp.Status)
+		}
+		body = resp.Body
+	} else {
+		file, err := os.Open(source)
+		if err != nil {
+			return nil, 0, err
+		}
+		body = file
+	}
+	defer body.Close()
+
+	return parseICS(io.LimitReader(body, maxICSBytes))
+}
+
+// parseICS returns the VEVENTs in an iCalendar stream that have a UID and a
+// timed DTSTART, along with how many events were skipped for lacking them
+func parseICS(r io.Reader) ([]icsEvent, int, error) {
+	var events []icsEvent
+	skipped := 0
+
+	lines, err := unfoldICSLines(r)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var event *icsEvent
+	usable := false
+	for _, line := range lines {
+		name, params, value := splitICSLine(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &icsEvent{}
+			usable = true
+		case name == "END" && value == "VEVENT":
+			if event != nil {
+				if usable && event.UID != "" && !event.Start.IsZero() {
+					events = append(events, *event)
+				} else {
+					skipped++
+				}
+			}
+			event = nil
+		case event == nil:
+			// Outside an event
+		case name == "UID":
+			event.UID = strings.TrimSpace(value)
+		case name == "SUMMARY":
+			event.Summary = unescapeICSText(value)
+		case name == "DTSTART":
+			start, err := parseICSTime(value, params)
+			if err != nil {
+				usable = false
+				continue
+			}
+			event.Start = start
+		}
+	}
+
+	return events, skipped, nil
+}
+
+// unfoldICSLines splits r into content lines, joining folded continuation
+// lines that start with a space or tab
+func unfoldICSLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxICSBytes)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitICSLine splits "NAME;PARAM=x:value" into its name, parameters and value
+func splitICSLine(line string) (name string, params map[string]string, value string) {
+	head, value, _ := strings.Cut(line, ":")
+	parts := strings.Split(head, ";")
+	params = make(map[string]string)
+	for _, param := range parts[1:] {
+		if key, val, ok := strings.Cut(param, "="); ok {
+			params[strings.ToUpper(key)] = strings.Trim(val, "\"")
+		}
+	}
+	return strings.ToUpper(parts[0]), params, value
+}
+
+// parseICSTime parses a DTSTART value. UTC ("Z") and TZID times are
+// supported; floating times are taken as UTC like every other time the bot
+// is given. All-day dates have no start time and are rejected.
+func parseICSTime(value string, params map[string]string) (time.Time, error) {
+	if params["VALUE"] == "DATE" {
+		return time.Time{}, fmt.Errorf("all-day event")
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+
+	location := time.UTC
+	if tzid := params["TZID"]; tzid != "" {
+		loaded, err := time.LoadLocation(tzid)
+		if err != nil {
+			return time.Time{}, err
+		}
+		location = loaded
+	}
+	start, err := time.ParseInLocation("20060102T150405", value, location)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return start.UTC(), nil
+}
+
+// unescapeICSText undoes iCalendar TEXT escaping
+func unescapeICSText(value string) string {
+	return strings.NewReplacer(`\n`, " ", `\N`, " ", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:casablanca\r\n" +
+	"SUMMARY:Casablanca\\, restored\r\n" +
+	"DTSTART:20250613T190000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:vertigo\r\n" +
+	"SUMMARY:Vertigo (director's\r\n" +
+	"  cut)\r\n" +
+	"DTSTART;TZID=America/New_York:20250614T200000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:allday\r\n" +
+	"SUMMARY:Festival\r\n" +
+	"DTSTART;VALUE=DATE:20250615\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:No uid\r\n" +
+	"DTSTART:20250616T190000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICS(t *testing.T) {
+	events, skipped, err := parseICS(strings.NewReader(testCalendar))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if skipped != 2 {
+		t.Errorf("expected 2 skipped events, got %d", skipped)
+	}
+
+	expected := []icsEvent{
+		{UID: "casablanca", Summary: "Casablanca, restored", Start: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)},
+		{UID: "vertigo", Summary: "Vertigo (director's cut)", Start: time.Date(2025, 6, 15, 0, 0, 0, 0, time.UTC)},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("expected %d events, got %+v", len(expected), events)
+	}
+	for i := range expected {
+		if events[i].UID != expected[i].UID || events[i].Summary != expected[i].Summary || !events[i].Start.Equal(expected[i].Start) {
+			t.Errorf("expected %+v, got %+v", expected[i], events[i])
+		}
+	}
+}
+
+func TestImportICS_URL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, testCalendar)
+	}))
+	defer server.Close()
+
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "vertigo", Title: "Already here", DateTime: time.Date(2025, 6, 1, 19, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+
+	bot.importICS([]string{".showtime", "-import-ics=" + server.URL}, "alice")
+
+	expected := "Imported 1 showtime. Skipped 1 with existing ids. Skipped 2 without a title, id or start time."
+	if len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+	showtime, _ := bot.store.GetByID("casablanca")
+	if showtime == nil || showtime.Title != "Casablanca, restored" || showtime.CreatedBy != "alice" {
+		t.Errorf("unexpected imported showtime %+v", showtime)
+	}
+}
+
+func TestImportICS_File(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "schedule.ics")
+	if err := os.WriteFile(path, []byte(testCalendar), 0o600); err != nil {
+		t.Fatalf("failed to write calendar: %v", err)
+	}
+
+	bot, sender := newTestBot()
+	bot.importICS([]string{".showtime", "-import-ics=" + path}, "alice")
+
+	if expected := "Imported 2 showtimes. Skipped 2 without a title, id or start time."; len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+}
+
+func TestImportICS_UIDsMadeValid(t *testing.T) {
+	calendar := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\n" +
+		"UID:3f2a9c@google.com\r\n" +
+		"SUMMARY:Casablanca\r\n" +
+		"DTSTART:20250613T190000Z\r\n" +
+		"END:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	path := filepath.Join(t.TempDir(), "schedule.ics")
+	if err := os.WriteFile(path, []byte(calendar), 0o600); err != nil {
+		t.Fatalf("failed to write calendar: %v", err)
+	}
+
+	bot, sender := newTestBot()
+	bot.importICS([]string{".showtime", "-import-ics=" + path}, "alice")
+
+	if expected := "Imported 1 showtime."; len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+	if showtime, _ := bot.store.GetByID("3f2a9c-google-com"); showtime == nil {
+		t.Errorf("expected the UID to be slugified, got %v", bot.store.(*memoryStore).showtimes)
+	}
+}
+
+func TestImportICS_Errors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	bot, sender := newTestBot()
+	bot.importICS([]string{".showtime", "-import-ics="}, "alice")
+	bot.importICS([]string{".showtime", "-import-ics=" + server.URL}, "alice")
+
+	if len(sender.messages) != 2 || sender.messages[0] != `Usage: .showtime -import-ics="url or path"` ||
+		!strings.HasPrefix(sender.messages[1], "Error reading calendar: unexpected status 404") {
+		t.Errorf("unexpected replies %v", sender.messages)
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// defaultMaxIDLength caps showtime ids when max_id_length is unset
+const defaultMaxIDLength = 32
+
+// defaultIDPattern is what ids are checked against when id_pattern is unset:
+// letters, digits, '.', '-' and '_', starting with a letter or digit, so they
+// never need quoting in -delete="id" and friends
+var defaultIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// validateID checks a new showtime id against id_pattern and max_id_length.
+// The error message is suitable for replying and suggests a valid id made
+// from id itself or, failing that, from title.
+func (bot *command) validateID(id, title string) error {
+	maxLength := bot.maxIDLength()
+	pattern := bot.config.idPattern
+	if pattern == nil {
+		pattern = defaultIDPattern
+	}
+
+	var problem string
+	switch {
+	case utf8.RuneCountInString(id) > maxLength:
+		problem = fmt.Sprintf("ids are at most %d characters", maxLength)
+	case !pattern.MatchString(id):
+		problem = fmt.Sprintf("ids must match %s", pattern)
+		if bot.config.idPattern == nil {
+			problem = "ids may only contain letters, digits, '.', '-' and '_'"
+		}
+	default:
+		return nil
+	}
+
+	message := fmt.Sprintf("Invalid id '%s': %s.", id, problem)
+	slug := slugify(id, maxLength)
+	if slug == "" {
+		slug = slugify(title, maxLength)
+	}
+	if slug != "" && slug != id && pattern.MatchString(slug) {
+		message += fmt.Sprintf(" Try -id=\"%s\".", slug)
+	}
+	return fmt.Errorf("%s", message)
+}
+
+// maxIDLength returns max_id_length, or its default when unset
+func (bot *command) maxIDLength() int {
+	if bot.config.MaxIDLength <= 0 {
+		return defaultMaxIDLength
+	}
+	return bot.config.MaxIDLength
+}
+
+// storableID returns id when it passes validateID, else its slug when that
+// does, else "". Ids that come from elsewhere than -id, such as calendar UIDs
+// and clones, go through it before being inserted.
+func (bot *command) storableID(id, title string) string {
+	if bot.validateID(id, title) == nil {
+		return id
+	}
+	if slug := slugify(id, bot.maxIDLength()); slug != "" && bot.validateID(slug, title) == nil {
+		return slug
+	}
+	return ""
+}
+
+// withSuffix appends suffix to base, shortening base so the result fits in
+// max_id_length. It returns "" when not even one character of base fits.
+func (bot *command) withSuffix(base, suffix string) string {
+	room := bot.maxIDLength() - len(suffix)
+	if room < 1 {
+		return ""
+	}
+	if len(base) > room {
+		base = strings.TrimRight(base[:room], "-")
+	}
+	return base + suffix
+}
+
+// maxIDSuffix bounds how many numbered variants of a slug idFromTitle tries
+const maxIDSuffix = 100
+
+// idFromTitle derives an unused id from title for a create without -id: its
+// slug, or the slug with "-2", "-3"... appended when that is taken. It returns
+// "" when the title has nothing to slugify or every variant is taken, or
+// when max_id_length leaves no room for a suffix.
+func (bot *command) idFromTitle(title string) (string, error) {
+	base := slugify(title, bot.maxIDLength())
+	if base == "" {
+		return "", nil
+	}
+
+	for n := 1; n <= maxIDSuffix; n++ {
+		id := base
+		if n > 1 {
+			if id = bot.withSuffix(base, fmt.Sprintf("-%d", n)); id == "" {
+				break
+			}
+		}
+
+		existing, err := bot.store.GetByID(id)
+		if err != nil {
+			return "", err
+		}
+		if existing == nil {
+			return id, nil
+		}
+	}
+	return "", nil
+}
+
+// slugify turns a title into an id: lowercase ASCII letters and digits, with
+// every run of anything else replaced by a single dash, at most maxLength long
+func slugify(title string, maxLength int) string {
+	var slug strings.Builder
+	dash := false
+	for _, char := range strings.ToLower(title) {
+		if (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9') {
+			if dash && slug.Len() > 0 {
+				slug.WriteByte('-')
+			}
+			slug.WriteRune(char)
+			dash = false
+		} else {
+			dash = true
+		}
+	}
+
+	result := slug.String()
+	if len(result) > maxLength {
+		result = strings.TrimRight(result[:maxLength], "-")
+	}
+	return result
+}
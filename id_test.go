@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateID(t *testing.T) {
+	bot, _ := newTestBot()
+	tests := []struct {
+		id       string
+		title    string
+		expected string
+	}{
+		{"movie1", "Casablanca", ""},
+		{"sat-night_2.0", "Casablanca", ""},
+		{"sat night", "Vertigo", `Invalid id 'sat night': ids may only contain letters, digits, '.', '-' and '_'. Try -id="sat-night".`},
+		{"-dash", "Vertigo", `Invalid id '-dash': ids may only contain letters, digits, '.', '-' and '_'. Try -id="dash".`},
+		{"???", "North by Northwest", `Invalid id '???': ids may only contain letters, digits, '.', '-' and '_'. Try -id="north-by-northwest".`},
+		{strings.Repeat("a", 33), "Vertigo", "Invalid id '" + strings.Repeat("a", 33) + "': ids are at most 32 characters. Try -id=\"" + strings.Repeat("a", 32) + "\"."},
+	}
+	for _, tt := range tests {
+		got := ""
+		if err := bot.validateID(tt.id, tt.title); err != nil {
+			got = err.Error()
+		}
+		if got != tt.expected {
+			t.Errorf("validateID(%q): expected %q, got %q", tt.id, tt.expected, got)
+		}
+	}
+}
+
+func TestValidateID_Configured(t *testing.T) {
+	bot, _ := newTestBot()
+	bot.config.IDPattern = "^[a-z]+[0-9]*$"
+	bot.config.idPattern = regexp.MustCompile(bot.config.IDPattern)
+	bot.config.MaxIDLength = 8
+
+	if err := bot.validateID("movie12", "Casablanca"); err != nil {
+		t.Errorf("expected movie12 to be valid, got %v", err)
+	}
+	if err := bot.validateID("Movie", "Casablanca"); err == nil || err.Error() != "Invalid id 'Movie': ids must match ^[a-z]+[0-9]*$. Try -id=\"movie\"." {
+		t.Errorf("expected a pattern error, got %v", err)
+	}
+	if err := bot.validateID("casablanca", "Casablanca"); err == nil || err.Error() != "Invalid id 'casablanca': ids are at most 8 characters. Try -id=\"casablan\"." {
+		t.Errorf("expected a length error, got %v", err)
+	}
+}
+
+func TestSlugify(t *testing.T) {
+	tests := []struct {
+		title    string
+		expected string
+	}{
+		{"Casablanca", "casablanca"},
+		{"North by Northwest", "north-by-northwest"},
+		{"  Dr. Strangelove, or: How I Learned  ", "dr-strangelove-or-how-i-learned"},
+		{"2001: A Space Odyssey", "2001-a-space-odyssey"},
+		{"Amélie", "am-lie"},
+		{"!!!", ""},
+		{"The Good, the Bad and the Ugly", "the-good-the-bad-and-the-ugly"},
+		{"Once Upon a Time in the West and Beyond", "once-upon-a-time-in-the-west-and"},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.title, defaultMaxIDLength); got != tt.expected {
+			t.Errorf("slugify(%q): expected %q, got %q", tt.title, tt.expected, got)
+		}
+	}
+}
+
+func TestCreateShowtime_InvalidID(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id="casa blanca" -title=Casablanca -date="2025-06-13 19:00"`), "alice")
+
+	expected := []string{`Invalid id 'casa blanca': ids may only contain letters, digits, '.', '-' and '_'. Try -id="casa-blanca".`}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	if len(bot.store.(*memoryStore).showtimes) != 0 {
+		t.Error("expected nothing to be created")
+	}
+}
+
+func TestIDFromTitle_Collisions(t *testing.T) {
+	bot, _ := newTestBot()
+	for _, id := range []string{"casablanca", "casablanca-2", "casablanca-4"} {
+		bot.store.Create(Showtime{ID: id, Title: "Casablanca"})
+	}
+
+	if id, _ := bot.idFromTitle("Casablanca"); id != "casablanca-3" {
+		t.Errorf("expected the first free suffix, got %q", id)
+	}
+	if id, _ := bot.idFromTitle("Vertigo"); id != "vertigo" {
+		t.Errorf("expected the bare slug when free, got %q", id)
+	}
+	if id, _ := bot.idFromTitle("???"); id != "" {
+		t.Errorf("expected no id for a title without letters or digits, got %q", id)
+	}
+
+	long := strings.Repeat("x", defaultMaxIDLength)
+	bot.store.Create(Showtime{ID: long, Title: long})
+	if id, _ := bot.idFromTitle(long); id != strings.Repeat("x", defaultMaxIDLength-2)+"-2" {
+		t.Errorf("expected the suffix to fit within max_id_length, got %q", id)
+	}
+}
+
+func TestIDFromTitle_ShortMaxLength(t *testing.T) {
+	bot, _ := newTestBot()
+	bot.config.MaxIDLength = 2
+	bot.store.Create(Showtime{ID: "ca", Title: "Casablanca"})
+	if id, _ := bot.idFromTitle("Casablanca"); id != "" {
+		t.Errorf("expected no id when no suffix fits, got %q", id)
+	}
+
+	bot.config.MaxIDLength = 3
+	bot.store.Create(Showtime{ID: "cas", Title: "Casablanca"})
+	for n := 2; n <= 9; n++ {
+		bot.store.Create(Showtime{ID: fmt.Sprintf("c-%d", n), Title: "Casablanca"})
+	}
+	if id, _ := bot.idFromTitle("Casablanca"); id != "" {
+		t.Errorf("expected no id once two-digit suffixes no longer fit, got %q", id)
+	}
+}
+
+func TestCreateShowtime_GeneratedID(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.createShowtime(bot.parseArgs(`.showtime -create -title="North by Northwest" -date="2025-06-13 19:00"`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -title="North by Northwest" -date="2025-06-14 19:00"`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=nbnw -title="North by Northwest" -date="2025-06-15 19:00"`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -title="!!!" -date="2025-06-15 19:00"`), "alice")
+
+	expected := []string{
+		"Created showtime: [north-by-northwest] North by Northwest - 2025-06-13 19:00:00 UTC",
+		"Created showtime: [north-by-northwest-2] North by Northwest - 2025-06-14 19:00:00 UTC",
+		"Created showtime: [nbnw] North by Northwest - 2025-06-15 19:00:00 UTC",
+		`Couldn't make a free id from that title. Add -id="id".`,
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// inactivityCheckInterval is how often the schedule is checked for
+// inactivity, so a nudge is posted at most once a day
+const inactivityCheckInterval = 24 * time.Hour
+
+// runInactivityCheck nudges the channel daily while the schedule is idle. The
+// setting is read on every pass so it can be enabled by a config reload.
+func (bot *CinemaBot) runInactivityCheck() {
+	ticker := time.NewTicker(inactivityCheckInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		bot.mu.RLock()
+		bot.snapshot().checkInactivity(now.UTC())
+		bot.mu.RUnlock()
+	}
+}
+
+// checkInactivity posts a reminder when inactivity_reminder_days is set,
+// nothing is upcoming, and the newest showtime started more than that many
+// days before now. A schedule that has never been used is left alone.
+func (bot *command) checkInactivity(now time.Time) {
+	days := bot.config.InactivityReminderDays
+	if days <= 0 || bot.quiet(now) {
+		return
+	}
+
+	next, err := bot.store.Next(now)
+	if err != nil {
+		log.Printf("Error getting next showtime for inactivity check: %v", err)
+		return
+	}
+	if next != nil {
+		return
+	}
+
+	past, err := bot.store.List(ShowtimeFilter{To: now})
+	if err != nil {
+		log.Printf("Error listing showtimes for inactivity check: %v", err)
+		return
+	}
+	if len(past) == 0 {
+		return
+	}
+
+	newest := past[len(past)-1]
+	if now.Sub(newest.DateTime) <= time.Duration(days)*24*time.Hour {
+		return
+	}
+
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Nothing has been scheduled for a while, the last showtime was %s on %s. Time to pick the next movie?",
+		newest.Title, bot.formatTime(newest.DateTime)))
+}
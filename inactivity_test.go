@@ -0,0 +1,55 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckInactivity(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	lastWeek := Showtime{ID: "recent", Title: "Vertigo", DateTime: now.Add(-7 * 24 * time.Hour)}
+	lastMonth := Showtime{ID: "old", Title: "Casablanca", DateTime: time.Date(2025, 6, 1, 19, 0, 0, 0, time.UTC)}
+	upcoming := Showtime{ID: "next", Title: "Psycho", DateTime: now.Add(24 * time.Hour)}
+
+	tests := []struct {
+		name      string
+		days      int
+		showtimes []Showtime
+		expected  []string
+	}{
+		{"disabled", 0, []Showtime{lastMonth}, nil},
+		{"never used", 14, nil, nil},
+		{"recent activity", 14, []Showtime{lastWeek}, nil},
+		{"upcoming scheduled", 14, []Showtime{lastMonth, upcoming}, nil},
+		{"inactive", 14, []Showtime{lastMonth}, []string{
+			"Nothing has been scheduled for a while, the last showtime was Casablanca on 2025-06-01 19:00:00 UTC. Time to pick the next movie?",
+		}},
+	}
+	for _, tt := range tests {
+		bot, sender := newTestBot()
+		bot.config.InactivityReminderDays = tt.days
+		for _, showtime := range tt.showtimes {
+			bot.store.Create(showtime)
+		}
+
+		bot.checkInactivity(now)
+
+		if !equalStringSlices(sender.messages, tt.expected) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, sender.messages)
+		}
+	}
+}
+
+func TestCheckInactivity_Quiet(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	bot.config.InactivityReminderDays = 1
+	bot.store.Create(Showtime{ID: "old", Title: "Casablanca", DateTime: now.Add(-30 * 24 * time.Hour)})
+	bot.quietUntil = now.Add(time.Hour)
+
+	bot.checkInactivity(now)
+
+	if len(sender.messages) != 0 {
+		t.Errorf("expected no nudge while quiet, got %v", sender.messages)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log"
+	"strings"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+// handleInvite joins a channel the bot was /invited to only when
+// auto_accept_invites is on and the channel is allowlisted. Either way the bot
+// keeps answering commands in its own channel only.
+func (bot *CinemaBot) handleInvite(e *irc.Event) {
+	if len(e.Arguments) < 2 {
+		return
+	}
+	channel := e.Arguments[1]
+
+	bot.mu.RLock()
+	allowed := bot.inviteAllowed(channel)
+	bot.mu.RUnlock()
+
+	if !allowed {
+		log.Printf("Ignoring invite to %s from %s!%s", channel, e.Nick, e.Host)
+		return
+	}
+	log.Printf("Accepting invite to %s from %s!%s", channel, e.Nick, e.Host)
+	bot.conn.Join(channel)
+}
+
+// inviteAllowed reports whether an invite to channel should be accepted: the
+// bot's own channel and invite_channels are, once auto_accept_invites is set
+func (bot *CinemaBot) inviteAllowed(channel string) bool {
+	if !bot.config.AutoAcceptInvites {
+		return false
+	}
+	if strings.EqualFold(channel, bot.config.Channel) {
+		return true
+	}
+	for _, allowed := range bot.config.InviteChannels {
+		if strings.EqualFold(channel, allowed) {
+			return true
+		}
+	}
+	return false
+}
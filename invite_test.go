@@ -0,0 +1,44 @@
+package main
+
+import (
+	"testing"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+func TestInviteAllowed(t *testing.T) {
+	bot, _ := newTestBot()
+	bot.config.InviteChannels = []string{"#Movies"}
+
+	tests := []struct {
+		channel  string
+		expected bool
+	}{
+		{"#testchan", true},
+		{"#movies", true},
+		{"#random", false},
+	}
+	for _, tt := range tests {
+		if bot.inviteAllowed(tt.channel) {
+			t.Errorf("%s: expected invites to be refused while auto_accept_invites is off", tt.channel)
+		}
+	}
+
+	bot.config.AutoAcceptInvites = true
+	for _, tt := range tests {
+		if got := bot.inviteAllowed(tt.channel); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.channel, tt.expected, got)
+		}
+	}
+}
+
+func TestHandleInvite_Ignored(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.AutoAcceptInvites = true
+
+	// Joining would need a connection, so an ignored invite must never try
+	bot.handleInvite(&irc.Event{Nick: "mallory", Arguments: []string{"testbot", "#random"}})
+	if len(sender.messages) != 0 {
+		t.Errorf("expected no replies, got %v", sender.messages)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+	"regexp"
+	"strings"
+)
+
+// ircLogWriter is where the IRC library logs. With .debug irc on that
+// includes every raw line sent, so the passwords in PASS and NickServ
+// messages are masked before anything reaches the log.
+type ircLogWriter struct {
+	// secrets are the configured passwords, masked wherever they appear
+	secrets []string
+}
+
+// ircSecretCommand matches commands whose arguments carry a password, up to
+// the end of the line or of the event being dumped
+var ircSecretCommand = regexp.MustCompile(`(?i)\b(PASS|IDENTIFY|GHOST|REGAIN) [^"\n]*`)
+
+func (w *ircLogWriter) Write(p []byte) (int, error) {
+	log.Print(w.redact(strings.TrimRight(string(p), "\n")))
+	return len(p), nil
+}
+
+// redact masks the secrets and whatever follows a password-carrying command
+func (w *ircLogWriter) redact(line string) string {
+	for _, secret := range w.secrets {
+		if secret != "" {
+			line = strings.ReplaceAll(line, secret, "***")
+		}
+	}
+	return ircSecretCommand.ReplaceAllString(line, "$1 ***")
+}
+
+// configureIRCLog routes the library's logging through an ircLogWriter with
+// its debug output switched off. Call it before connecting.
+func (bot *CinemaBot) configureIRCLog() {
+	writer := &ircLogWriter{secrets: []string{bot.config.ServerPassword, bot.config.NickServ.Password}}
+	bot.conn.Log = log.New(writer, "", 0)
+	bot.conn.Debug = false
+	bot.conn.VerboseCallbackHandler = false
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestIRCLogWriter_Redacts(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	log.SetFlags(0)
+	defer log.SetOutput(os.Stderr)
+	defer log.SetFlags(log.LstdFlags)
+
+	writer := &ircLogWriter{secrets: []string{"hunter2", "", "s3cret"}}
+	logger := log.New(writer, "", 0)
+	lines := []string{
+		"--> PASS hunter2",
+		"--> PRIVMSG NickServ :IDENTIFY s3cret",
+		"--> PRIVMSG NickServ :LOGIN cinemabot s3cret",
+		"--> PRIVMSG NickServ :GHOST cinemabot s3cret",
+		`PRIVMSG (1) >> &irc.Event{Code:"PRIVMSG", Arguments:[]string{"NickServ", "identify s3cret"}}`,
+		"<-- :NickServ NOTICE cinemabot :You are now identified for cinemabot.",
+		"Error, disconnected: EOF",
+	}
+	for _, line := range lines {
+		logger.Println(line)
+	}
+
+	expected := []string{
+		"--> PASS ***",
+		"--> PRIVMSG NickServ :IDENTIFY ***",
+		"--> PRIVMSG NickServ :LOGIN cinemabot ***",
+		"--> PRIVMSG NickServ :GHOST ***",
+		`PRIVMSG (1) >> &irc.Event{Code:"PRIVMSG", Arguments:[]string{"NickServ", "identify ***"}}`,
+		"<-- :NickServ NOTICE cinemabot :You are now identified for cinemabot.",
+		"Error, disconnected: EOF",
+	}
+	if got := strings.Split(strings.TrimSpace(output.String()), "\n"); !equalStringSlices(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// defaultSlowCommandMillis is the slow_command_ms used when it is unset
+const defaultSlowCommandMillis = 500
+
+// timeCommand starts timing a command and returns the function that logs how
+// long it took, with a warning when that exceeded slow_command_ms. It locks
+// the config itself so the returned function can run after the handler has
+// released bot.mu.
+func (bot *CinemaBot) timeCommand(name, nick string) (done func()) {
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+
+		bot.mu.RLock()
+		threshold := time.Duration(bot.config.SlowCommandMillis) * time.Millisecond
+		bot.mu.RUnlock()
+		if threshold <= 0 {
+			threshold = defaultSlowCommandMillis * time.Millisecond
+		}
+
+		if elapsed > threshold {
+			log.Printf("Warning: .%s from %s took %s, over the %s slow_command_ms threshold", name, nick, elapsed.Round(time.Millisecond), threshold)
+			return
+		}
+		log.Printf("Handled .%s from %s in %s", name, nick, elapsed.Round(time.Microsecond))
+	}
+}
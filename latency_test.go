@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTimeCommand(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	bot, _ := newTestBot()
+	bot.timeCommand("nextmovie", "alice")()
+	if !strings.Contains(output.String(), "Handled .nextmovie from alice in ") {
+		t.Errorf("expected the timing to be logged, got %q", output.String())
+	}
+
+	output.Reset()
+	bot.config.SlowCommandMillis = 1
+	done := bot.timeCommand("showtime", "bob")
+	time.Sleep(5 * time.Millisecond)
+	done()
+	if !strings.Contains(output.String(), "Warning: .showtime from bob took ") {
+		t.Errorf("expected a slow command warning, got %q", output.String())
+	}
+}
@@ -1,50 +1,327 @@
 package main
 
 import (
-	"database/sql"
+	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"net/http"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"regexp"
 	"strconv"
 	"strings"
 	"sync"
+	"syscall"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
-	_ "github.com/mattn/go-sqlite3"
 	irc "github.com/thoj/go-ircevent"
+	"gopkg.in/yaml.v3"
 )
 
 type Config struct {
-	Server   string `json:"server"`
-	Nick     string `json:"nick"`
-	Channel  string `json:"channel"`
+	Server   string `json:"server" yaml:"server"`
+	Nick     string `json:"nick" yaml:"nick"`
+	Channel  string `json:"channel" yaml:"channel"`
 	NickServ struct {
-		Password string `json:"password,omitempty"`
-	} `json:"nickserv,omitempty"`
-	AuthorizedNicks map[string]bool `json:"authorized_nicks,omitempty"`
-	DatabasePath    string          `json:"database_path,omitempty"`
+		Password string `json:"password,omitempty" yaml:"password,omitempty"`
+		// Service is who the identify command is sent to, "NickServ" when empty
+		Service string `json:"service,omitempty" yaml:"service,omitempty"`
+		// IdentifyCommand is the message sent to Service with {nick} and
+		// {password} filled in, "IDENTIFY {password}" when empty
+		IdentifyCommand string `json:"identify_command,omitempty" yaml:"identify_command,omitempty"`
+		// GhostCommand is sent to Service, filled in the same way, when the
+		// bot had to register under an alternate nick, "GHOST {nick}
+		// {password}" when empty
+		GhostCommand string `json:"ghost_command,omitempty" yaml:"ghost_command,omitempty"`
+	} `json:"nickserv,omitempty" yaml:"nickserv,omitempty"`
+	// AlternateNicks are tried in order when nick is already in use, before
+	// falling back to appending "_"
+	AlternateNicks  []string        `json:"alternate_nicks,omitempty" yaml:"alternate_nicks,omitempty"`
+	AuthorizedNicks AuthorizedNicks `json:"authorized_nicks,omitempty" yaml:"authorized_nicks,omitempty"`
+	DatabasePath    string          `json:"database_path,omitempty" yaml:"database_path,omitempty"`
+	// StagingDatabasePath is the SQLite file .staging on switches commands
+	// to, so schedule edits can be tried without touching production data
+	StagingDatabasePath string `json:"staging_database_path,omitempty" yaml:"staging_database_path,omitempty"`
+	// ServerPassword is sent with PASS before registration, for servers that
+	// require a connection password
+	ServerPassword string `json:"server_password,omitempty" yaml:"server_password,omitempty"`
+	// PingTimeoutSeconds is how long the server may go without answering our
+	// PINGs before the connection is dropped and re-established
+	PingTimeoutSeconds int `json:"ping_timeout_seconds,omitempty" yaml:"ping_timeout_seconds,omitempty"`
+	// KeepAliveSeconds, when set, makes the IRC library PING the server at this
+	// interval (and whenever it has been this long since anything arrived) so
+	// idle-kicking servers always see traffic
+	KeepAliveSeconds int `json:"keepalive_seconds,omitempty" yaml:"keepalive_seconds,omitempty"`
+	// JustStartedSeconds is how long after its start a movie is still
+	// announced as just started. Unset means defaultJustStartedSeconds and 0
+	// turns the phrasing off, hence the pointer.
+	JustStartedSeconds *int `json:"just_started_seconds,omitempty" yaml:"just_started_seconds,omitempty"`
+	// CurrentWindowHours is how long after its start a movie counts as playing
+	CurrentWindowHours int `json:"current_window_hours,omitempty" yaml:"current_window_hours,omitempty"`
+	// SoonThresholdMinutes makes .nextmovie announce a movie starting within
+	// this many minutes as starting soon; zero disables it
+	SoonThresholdMinutes int `json:"soon_threshold_minutes,omitempty" yaml:"soon_threshold_minutes,omitempty"`
+	// NextMovieMentionCancelled makes .nextmovie name the cancelled
+	// showtimes it skipped over instead of passing them silently
+	NextMovieMentionCancelled bool `json:"nextmovie_mention_cancelled,omitempty" yaml:"nextmovie_mention_cancelled,omitempty"`
+	// PlainIndicators renders list status indicators as text instead of emoji
+	PlainIndicators bool `json:"plain_indicators,omitempty" yaml:"plain_indicators,omitempty"`
+	// Webhooks receive a JSON POST for every showtime Event
+	Webhooks []string `json:"webhooks,omitempty" yaml:"webhooks,omitempty"`
+	// NotifyChannel is a channel, joined on connect, where every showtime
+	// Event is announced, e.g. for organizers
+	NotifyChannel string `json:"notify_channel,omitempty" yaml:"notify_channel,omitempty"`
+	// TMDBAPIKey enables -lookup on create; without it titles are used as typed
+	TMDBAPIKey string `json:"tmdb_api_key,omitempty" yaml:"tmdb_api_key,omitempty"`
+	// OMDbAPIKey adds IMDb ratings and runtimes to .showtime -info
+	OMDbAPIKey string `json:"omdb_api_key,omitempty" yaml:"omdb_api_key,omitempty"`
+	// DisplayTimezone is the IANA zone used to render times, UTC when empty
+	DisplayTimezone string `json:"display_timezone,omitempty" yaml:"display_timezone,omitempty"`
+	// ShowBothTimes renders UTC followed by the display timezone in parentheses
+	ShowBothTimes bool `json:"show_both_times,omitempty" yaml:"show_both_times,omitempty"`
+	// RowsPerMessage merges up to this many -list rows into each message
+	// (separated by " | ") to send fewer lines; 1 or unset sends one per row
+	RowsPerMessage int `json:"rows_per_message,omitempty" yaml:"rows_per_message,omitempty"`
+	// CompactList makes .showtime -list use the compact layout unless
+	// -detailed is given
+	CompactList bool `json:"compact_list,omitempty" yaml:"compact_list,omitempty"`
+	// MaxListEntries caps a channel -list without -from, -to or -tag,
+	// defaultMaxListEntries when unset
+	MaxListEntries int `json:"max_list_entries,omitempty" yaml:"max_list_entries,omitempty"`
+	// TimeFormat is the Go layout used to render times, defaultTimeFormat when
+	// empty, e.g. "2006-01-02 03:04 PM MST" for a 12-hour clock
+	TimeFormat string `json:"time_format,omitempty" yaml:"time_format,omitempty"`
+	// MaintenanceIntervalHours runs PRAGMA optimize this often, with a daily
+	// VACUUM when the schedule is quiet; zero disables maintenance
+	MaintenanceIntervalHours int `json:"maintenance_interval_hours,omitempty" yaml:"maintenance_interval_hours,omitempty"`
+	// InactivityReminderDays posts a nudge once a day when nothing is
+	// upcoming and the last showtime was more than this many days ago; zero
+	// disables it
+	InactivityReminderDays int `json:"inactivity_reminder_days,omitempty" yaml:"inactivity_reminder_days,omitempty"`
+	// DigestTime is the UTC time of day (HH:MM) at which the coming 24 hours
+	// of showtimes are posted; empty disables the digest
+	DigestTime string `json:"digest_time,omitempty" yaml:"digest_time,omitempty"`
+	// DigestWhenEmpty posts the digest even when nothing is scheduled
+	DigestWhenEmpty bool `json:"digest_when_empty,omitempty" yaml:"digest_when_empty,omitempty"`
+	// AuditRetentionDays deletes audit log entries older than this many days,
+	// checked daily; zero keeps them forever
+	AuditRetentionDays int `json:"audit_retention_days,omitempty" yaml:"audit_retention_days,omitempty"`
+	// LogChannelMessages records every message in the channel (nick, host,
+	// text and time) in the channel_log table for moderation context
+	LogChannelMessages bool `json:"log_channel_messages,omitempty" yaml:"log_channel_messages,omitempty"`
+	// ChannelLogRetentionDays is how long channel_log rows are kept,
+	// defaultChannelLogRetentionDays when unset
+	ChannelLogRetentionDays int `json:"channel_log_retention_days,omitempty" yaml:"channel_log_retention_days,omitempty"`
+	// IDPattern is a regular expression new showtime ids must match,
+	// defaultIDPattern when empty
+	IDPattern string `json:"id_pattern,omitempty" yaml:"id_pattern,omitempty"`
+	// MaxIDLength caps new showtime ids, defaultMaxIDLength when unset
+	MaxIDLength int `json:"max_id_length,omitempty" yaml:"max_id_length,omitempty"`
+	// AutoAcceptInvites joins channels the bot is /invited to when they are
+	// the bot's channel or listed in InviteChannels; other invites are ignored
+	AutoAcceptInvites bool `json:"auto_accept_invites,omitempty" yaml:"auto_accept_invites,omitempty"`
+	// InviteChannels allowlists channels whose invites are accepted
+	InviteChannels []string `json:"invite_channels,omitempty" yaml:"invite_channels,omitempty"`
+	// ChannelCommands limits a channel to the listed commands (without the
+	// leading dot); channels without an entry allow every command
+	ChannelCommands map[string][]string `json:"channel_commands,omitempty" yaml:"channel_commands,omitempty"`
+	// QueryTimeoutSeconds bounds each database call so a stuck query fails
+	// with an error instead of hanging the bot, 10 when unset
+	QueryTimeoutSeconds int `json:"query_timeout_seconds,omitempty" yaml:"query_timeout_seconds,omitempty"`
+	// DuplicateTitleCheck refuses to create a showtime whose title is already
+	// scheduled on the same UTC day unless -force is given
+	DuplicateTitleCheck bool `json:"duplicate_title_check,omitempty" yaml:"duplicate_title_check,omitempty"`
+	// PublicBaseURL is where the health check server is reachable from
+	// outside, e.g. "https://cinema.example.com"; when set, create
+	// confirmations link to the showtime on the schedule page
+	PublicBaseURL string `json:"public_base_url,omitempty" yaml:"public_base_url,omitempty"`
+	// JoinMessage is announced in the channel every time the bot joins it
+	JoinMessage string `json:"join_message,omitempty" yaml:"join_message,omitempty"`
+	// AuthHostPattern is a regular expression an authorized nick's host must
+	// match, e.g. "^user/" or "\.staff\.example\.net$"; when empty the host
+	// must be exactly "user/<nick>". It doesn't tie the host to the nick, so
+	// AuthHostPatterns is the safer choice on networks with shared cloaks.
+	AuthHostPattern string `json:"auth_host_pattern,omitempty" yaml:"auth_host_pattern,omitempty"`
+	// AuthHostPatterns overrides AuthHostPattern for the nicks it lists
+	AuthHostPatterns map[string]string `json:"auth_host_patterns,omitempty" yaml:"auth_host_patterns,omitempty"`
+	// SlowCommandMillis is how long a command may take end to end before its
+	// timing is logged as a warning, defaultSlowCommandMillis when unset
+	SlowCommandMillis int `json:"slow_command_ms,omitempty" yaml:"slow_command_ms,omitempty"`
+
+	// location is DisplayTimezone resolved by loadConfig
+	location *time.Location
+	// authHost and authHosts are AuthHostPattern and AuthHostPatterns
+	// compiled by loadConfig
+	authHost  *regexp.Regexp
+	authHosts map[string]*regexp.Regexp
+	// idPattern is IDPattern compiled by loadConfig
+	idPattern *regexp.Regexp
 }
 
+// AuthorizedNicks is the authorized_nicks setting. Plain entries
+// ("alice": true) authorize a nick in every channel; entries keyed by a
+// channel ("#a": {"bob": true}) authorize nicks in that channel only.
+type AuthorizedNicks struct {
+	Global   map[string]bool
+	Channels map[string]map[string]bool
+}
+
+// Allows reports whether nick may manage showtimes in channel
+func (a AuthorizedNicks) Allows(channel, nick string) bool {
+	return a.Global[nick] || a.Channels[strings.ToLower(channel)][nick]
+}
+
+// isChannelName reports whether an authorized_nicks key names a channel
+func isChannelName(key string) bool {
+	return strings.HasPrefix(key, "#") || strings.HasPrefix(key, "&")
+}
+
+func (a *AuthorizedNicks) UnmarshalJSON(data []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	*a = AuthorizedNicks{}
+	for key, value := range raw {
+		if isChannelName(key) {
+			var nicks map[string]bool
+			if err := json.Unmarshal(value, &nicks); err != nil {
+				return fmt.Errorf("authorized_nicks %s: %v", key, err)
+			}
+			a.addChannel(key, nicks)
+			continue
+		}
+		var allowed bool
+		if err := json.Unmarshal(value, &allowed); err != nil {
+			return fmt.Errorf("authorized_nicks %s: %v", key, err)
+		}
+		a.addGlobal(key, allowed)
+	}
+	return nil
+}
+
+func (a *AuthorizedNicks) UnmarshalYAML(node *yaml.Node) error {
+	var raw map[string]yaml.Node
+	if err := node.Decode(&raw); err != nil {
+		return err
+	}
+
+	*a = AuthorizedNicks{}
+	for key, value := range raw {
+		if isChannelName(key) {
+			var nicks map[string]bool
+			if err := value.Decode(&nicks); err != nil {
+				return fmt.Errorf("authorized_nicks %s: %v", key, err)
+			}
+			a.addChannel(key, nicks)
+			continue
+		}
+		var allowed bool
+		if err := value.Decode(&allowed); err != nil {
+			return fmt.Errorf("authorized_nicks %s: %v", key, err)
+		}
+		a.addGlobal(key, allowed)
+	}
+	return nil
+}
+
+func (a *AuthorizedNicks) addGlobal(nick string, allowed bool) {
+	if a.Global == nil {
+		a.Global = make(map[string]bool)
+	}
+	a.Global[nick] = allowed
+}
+
+func (a *AuthorizedNicks) addChannel(channel string, nicks map[string]bool) {
+	if a.Channels == nil {
+		a.Channels = make(map[string]map[string]bool)
+	}
+	a.Channels[strings.ToLower(channel)] = nicks
+}
+
+const (
+	defaultJustStartedSeconds = 60
+	defaultCurrentWindowHours = 3
+	defaultPingTimeoutSeconds = 180
+	defaultTimeFormat         = "2006-01-02 15:04:05 MST"
+	defaultMaxListEntries     = 25
+)
+
 type Showtime struct {
 	ID        string    `json:"id"`
 	Title     string    `json:"title"`
 	DateTime  time.Time `json:"datetime"`
 	CreatedBy string    `json:"created_by"`
 	CreatedAt time.Time `json:"created_at"`
+	TMDBID    int       `json:"tmdb_id,omitempty"`
+	PosterURL string    `json:"poster_url,omitempty"`
+	// AdminNote is an organizer-only remark, never included in public output
+	AdminNote string `json:"-"`
+	// RuntimeMinutes is the movie's length when known, zero otherwise
+	RuntimeMinutes int `json:"runtime_minutes,omitempty"`
+	// Tags categorize the showtime (e.g. "horror"), lowercase and unique
+	Tags []string `json:"tags,omitempty"`
+	// Cancelled screenings stay listed but are never next, current or upcoming
+	Cancelled bool `json:"cancelled,omitempty"`
+}
+
+// Sender delivers outgoing messages; *irc.Connection satisfies it
+type Sender interface {
+	Privmsg(target, message string)
 }
 
 type CinemaBot struct {
-	conn   *irc.Connection
-	config Config
-	db     *sql.DB
-	mu     sync.RWMutex
+	conn       *irc.Connection
+	sender     Sender
+	config     Config
+	configFile string
+	store      ShowtimeStore
+	mu         sync.RWMutex
+
+	// lastPong is when the server last answered (or the connection was
+	// registered), guarded by pongMu rather than mu so the ping monitor never
+	// waits on command handling
+	lastPong time.Time
+	pongMu   sync.Mutex
+
+	// quietUntil silences channel replies until then, guarded by mu
+	quietUntil time.Time
+	// staging is the store commands use after .staging on, nil while on
+	// production, guarded by mu. store itself always stays on production.
+	staging ShowtimeStore
+
+	// startedAt is when the bot was created and connectedAt when the server
+	// last welcomed us (zero until then), guarded by mu
+	startedAt   time.Time
+	connectedAt time.Time
+
+	// outbox holds replies sent while disconnected and is flushed when we
+	// rejoin the channel; nil in tests, which send straight to a capture
+	outbox *outbox
+
+	// pendingClear is the last .showtime -clear awaiting confirmation
+	pendingClear clearRequest
+	// pendingClockCheck is the last .clockcheck awaiting RPL_TIME
+	pendingClockCheck clockCheck
+	// nick is the pending GHOST and the server's NICKLEN
+	nick nickState
+
+	// notifiers receive every showtime Event, built by configureNotifiers and
+	// guarded by mu
+	notifiers []Notifier
 }
 
 func NewCinemaBot(configFile string) (*CinemaBot, error) {
-	bot := &CinemaBot{}
+	bot := &CinemaBot{configFile: configFile, startedAt: time.Now().UTC()}
 
 	// Load config
 	if err := bot.loadConfig(configFile); err != nil {
@@ -52,14 +329,23 @@ func NewCinemaBot(configFile string) (*CinemaBot, error) {
 	}
 
 	// Initialize database
-	if err := bot.initDatabase(); err != nil {
+	store, err := NewSQLiteStore(bot.config.DatabasePath)
+	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %v", err)
 	}
+	if bot.config.QueryTimeoutSeconds > 0 {
+		store.timeout = time.Duration(bot.config.QueryTimeoutSeconds) * time.Second
+	}
+	bot.store = store
 
 	// Setup IRC connection
 	bot.conn = irc.IRC(bot.config.Nick, bot.config.Nick)
-	bot.conn.VerboseCallbackHandler = false
-	bot.conn.Debug = false
+	bot.configureIRCLog()
+	bot.conn.Password = bot.config.ServerPassword
+	bot.configureKeepAlive()
+	bot.outbox = newOutbox(bot.conn, bot.conn.Connected)
+	bot.sender = splitSender{bot.outbox}
+	bot.configureNotifiers()
 
 	// Add event handlers
 	bot.setupHandlers()
@@ -71,10 +357,13 @@ func (bot *CinemaBot) loadConfig(configFile string) error {
 	if configFile == "" {
 		// Default config if no file specified
 		bot.config = Config{
-			Server:       "irc.snoonet.org:6667",
-			Nick:         "marquee",
-			Channel:      "#stopdrinkingcinema",
-			DatabasePath: "cinema_bot.db",
+			Server:             "irc.snoonet.org:6667",
+			Nick:               "marquee",
+			Channel:            "#stopdrinkingcinema",
+			DatabasePath:       "cinema_bot.db",
+			CurrentWindowHours: defaultCurrentWindowHours,
+			PingTimeoutSeconds: defaultPingTimeoutSeconds,
+			location:           time.UTC,
 		}
 		return nil
 	}
@@ -84,8 +373,16 @@ func (bot *CinemaBot) loadConfig(configFile string) error {
 		return err
 	}
 
-	if err := json.Unmarshal(data, &bot.config); err != nil {
-		return err
+	// YAML is picked by extension, everything else is parsed as JSON
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".yaml", ".yml":
+		if err := yaml.Unmarshal(data, &bot.config); err != nil {
+			return err
+		}
+	default:
+		if err := json.Unmarshal(data, &bot.config); err != nil {
+			return err
+		}
 	}
 
 	// Set default database path if not specified
@@ -93,597 +390,2368 @@ func (bot *CinemaBot) loadConfig(configFile string) error {
 		bot.config.DatabasePath = "cinema_bot.db"
 	}
 
+	if bot.config.CurrentWindowHours == 0 {
+		bot.config.CurrentWindowHours = defaultCurrentWindowHours
+	}
+
+	if bot.config.PingTimeoutSeconds == 0 {
+		bot.config.PingTimeoutSeconds = defaultPingTimeoutSeconds
+	}
+
+	if bot.config.DigestTime != "" {
+		if _, err := time.Parse("15:04", bot.config.DigestTime); err != nil {
+			return fmt.Errorf("invalid digest_time %q (use HH:MM)", bot.config.DigestTime)
+		}
+	}
+
+	if bot.config.TimeFormat != "" {
+		if err := validateTimeFormat(bot.config.TimeFormat); err != nil {
+			return fmt.Errorf("invalid time_format: %v", err)
+		}
+	}
+
+	if bot.config.AuthHostPattern != "" {
+		pattern, err := regexp.Compile(bot.config.AuthHostPattern)
+		if err != nil {
+			return fmt.Errorf("invalid auth_host_pattern: %v", err)
+		}
+		bot.config.authHost = pattern
+	}
+	if bot.config.IDPattern != "" {
+		pattern, err := regexp.Compile(bot.config.IDPattern)
+		if err != nil {
+			return fmt.Errorf("invalid id_pattern: %v", err)
+		}
+		bot.config.idPattern = pattern
+	}
+	for nick, expr := range bot.config.AuthHostPatterns {
+		pattern, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Errorf("invalid auth_host_patterns entry for %s: %v", nick, err)
+		}
+		if bot.config.authHosts == nil {
+			bot.config.authHosts = make(map[string]*regexp.Regexp)
+		}
+		bot.config.authHosts[nick] = pattern
+	}
+
+	bot.config.location = time.UTC
+	if bot.config.DisplayTimezone != "" {
+		location, err := time.LoadLocation(bot.config.DisplayTimezone)
+		if err != nil {
+			return fmt.Errorf("invalid display_timezone: %v", err)
+		}
+		bot.config.location = location
+	}
+
 	return nil
 }
 
-func (bot *CinemaBot) initDatabase() error {
-	var err error
-	bot.db, err = sql.Open("sqlite3", bot.config.DatabasePath)
-	if err != nil {
+// validateTimeFormat checks that layout is a Go time layout that renders a
+// time and can be read back
+func validateTimeFormat(layout string) error {
+	reference := time.Date(2025, 6, 13, 20, 30, 0, 0, time.UTC)
+	formatted := reference.Format(layout)
+	if formatted == layout {
+		return fmt.Errorf("%q has no time fields (layouts are written using 2006-01-02 15:04:05)", layout)
+	}
+	if _, err := time.Parse(layout, formatted); err != nil {
 		return err
 	}
+	return nil
+}
 
-	// Test connection
-	if err := bot.db.Ping(); err != nil {
+// reloadConfig re-reads the config file and applies the settings that can
+// change without reconnecting
+func (bot *CinemaBot) reloadConfig() error {
+	fresh := &CinemaBot{}
+	if err := fresh.loadConfig(bot.configFile); err != nil {
 		return err
 	}
 
-	// Create showtimes table if it doesn't exist
-	createTableSQL := `
-	CREATE TABLE IF NOT EXISTS showtimes (
-		id TEXT PRIMARY KEY,
-		title TEXT NOT NULL,
-		datetime DATETIME NOT NULL,
-		created_by TEXT NOT NULL,
-		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
-	);
-	
-	CREATE INDEX IF NOT EXISTS idx_datetime ON showtimes(datetime);
-	CREATE INDEX IF NOT EXISTS idx_created_by ON showtimes(created_by);
-	`
+	bot.mu.Lock()
+	changed, ignored := bot.applyReloadedConfig(fresh.config)
+	bot.mu.Unlock()
 
-	if _, err := bot.db.Exec(createTableSQL); err != nil {
-		return fmt.Errorf("failed to create table: %v", err)
+	if len(changed) == 0 {
+		log.Printf("Config reloaded from %s, nothing changed", bot.configFile)
+	} else {
+		log.Printf("Config reloaded from %s, updated: %s", bot.configFile, strings.Join(changed, ", "))
+	}
+	if len(ignored) > 0 {
+		log.Printf("Warning: changes to %s require a restart and were ignored", strings.Join(ignored, ", "))
 	}
-
-	log.Printf("Database initialized successfully at %s", bot.config.DatabasePath)
 	return nil
 }
 
+// applyReloadedConfig copies runtime-changeable settings from cfg and reports
+// which ones changed, along with changed settings that need a reconnect
+func (bot *CinemaBot) applyReloadedConfig(cfg Config) (changed, ignored []string) {
+	if !reflect.DeepEqual(bot.config.AuthorizedNicks, cfg.AuthorizedNicks) {
+		bot.config.AuthorizedNicks = cfg.AuthorizedNicks
+		changed = append(changed, "authorized_nicks")
+	}
+	if !reflect.DeepEqual(bot.config.JustStartedSeconds, cfg.JustStartedSeconds) {
+		bot.config.JustStartedSeconds = cfg.JustStartedSeconds
+		changed = append(changed, "just_started_seconds")
+	}
+	if bot.config.CurrentWindowHours != cfg.CurrentWindowHours {
+		bot.config.CurrentWindowHours = cfg.CurrentWindowHours
+		changed = append(changed, "current_window_hours")
+	}
+	if bot.config.SoonThresholdMinutes != cfg.SoonThresholdMinutes {
+		bot.config.SoonThresholdMinutes = cfg.SoonThresholdMinutes
+		changed = append(changed, "soon_threshold_minutes")
+	}
+	if bot.config.NextMovieMentionCancelled != cfg.NextMovieMentionCancelled {
+		bot.config.NextMovieMentionCancelled = cfg.NextMovieMentionCancelled
+		changed = append(changed, "nextmovie_mention_cancelled")
+	}
+	if bot.config.PlainIndicators != cfg.PlainIndicators {
+		bot.config.PlainIndicators = cfg.PlainIndicators
+		changed = append(changed, "plain_indicators")
+	}
+	if bot.config.AuthHostPattern != cfg.AuthHostPattern {
+		bot.config.AuthHostPattern = cfg.AuthHostPattern
+		bot.config.authHost = cfg.authHost
+		changed = append(changed, "auth_host_pattern")
+	}
+	if !reflect.DeepEqual(bot.config.AuthHostPatterns, cfg.AuthHostPatterns) {
+		bot.config.AuthHostPatterns = cfg.AuthHostPatterns
+		bot.config.authHosts = cfg.authHosts
+		changed = append(changed, "auth_host_patterns")
+	}
+	if bot.config.IDPattern != cfg.IDPattern {
+		bot.config.IDPattern = cfg.IDPattern
+		bot.config.idPattern = cfg.idPattern
+		changed = append(changed, "id_pattern")
+	}
+	if bot.config.MaxIDLength != cfg.MaxIDLength {
+		bot.config.MaxIDLength = cfg.MaxIDLength
+		changed = append(changed, "max_id_length")
+	}
+	if bot.config.AutoAcceptInvites != cfg.AutoAcceptInvites {
+		bot.config.AutoAcceptInvites = cfg.AutoAcceptInvites
+		changed = append(changed, "auto_accept_invites")
+	}
+	if !reflect.DeepEqual(bot.config.InviteChannels, cfg.InviteChannels) {
+		bot.config.InviteChannels = cfg.InviteChannels
+		changed = append(changed, "invite_channels")
+	}
+	if !reflect.DeepEqual(bot.config.AlternateNicks, cfg.AlternateNicks) {
+		bot.config.AlternateNicks = cfg.AlternateNicks
+		changed = append(changed, "alternate_nicks")
+	}
+	if !reflect.DeepEqual(bot.config.Webhooks, cfg.Webhooks) {
+		bot.config.Webhooks = cfg.Webhooks
+		changed = append(changed, "webhooks")
+	}
+	if bot.config.NotifyChannel != cfg.NotifyChannel {
+		bot.config.NotifyChannel = cfg.NotifyChannel
+		changed = append(changed, "notify_channel")
+	}
+	bot.configureNotifiers()
+	if bot.config.TMDBAPIKey != cfg.TMDBAPIKey {
+		bot.config.TMDBAPIKey = cfg.TMDBAPIKey
+		changed = append(changed, "tmdb_api_key")
+	}
+	if bot.config.OMDbAPIKey != cfg.OMDbAPIKey {
+		bot.config.OMDbAPIKey = cfg.OMDbAPIKey
+		changed = append(changed, "omdb_api_key")
+	}
+	if bot.config.DisplayTimezone != cfg.DisplayTimezone {
+		bot.config.DisplayTimezone = cfg.DisplayTimezone
+		bot.config.location = cfg.location
+		changed = append(changed, "display_timezone")
+	}
+	if bot.config.ShowBothTimes != cfg.ShowBothTimes {
+		bot.config.ShowBothTimes = cfg.ShowBothTimes
+		changed = append(changed, "show_both_times")
+	}
+	if bot.config.RowsPerMessage != cfg.RowsPerMessage {
+		bot.config.RowsPerMessage = cfg.RowsPerMessage
+		changed = append(changed, "rows_per_message")
+	}
+	if bot.config.CompactList != cfg.CompactList {
+		bot.config.CompactList = cfg.CompactList
+		changed = append(changed, "compact_list")
+	}
+	if bot.config.MaxListEntries != cfg.MaxListEntries {
+		bot.config.MaxListEntries = cfg.MaxListEntries
+		changed = append(changed, "max_list_entries")
+	}
+	if bot.config.TimeFormat != cfg.TimeFormat {
+		bot.config.TimeFormat = cfg.TimeFormat
+		changed = append(changed, "time_format")
+	}
+	if bot.config.InactivityReminderDays != cfg.InactivityReminderDays {
+		bot.config.InactivityReminderDays = cfg.InactivityReminderDays
+		changed = append(changed, "inactivity_reminder_days")
+	}
+	if !reflect.DeepEqual(bot.config.ChannelCommands, cfg.ChannelCommands) {
+		bot.config.ChannelCommands = cfg.ChannelCommands
+		changed = append(changed, "channel_commands")
+	}
+	if bot.config.AuditRetentionDays != cfg.AuditRetentionDays {
+		bot.config.AuditRetentionDays = cfg.AuditRetentionDays
+		changed = append(changed, "audit_retention_days")
+	}
+	if bot.config.LogChannelMessages != cfg.LogChannelMessages {
+		bot.config.LogChannelMessages = cfg.LogChannelMessages
+		changed = append(changed, "log_channel_messages")
+	}
+	if bot.config.ChannelLogRetentionDays != cfg.ChannelLogRetentionDays {
+		bot.config.ChannelLogRetentionDays = cfg.ChannelLogRetentionDays
+		changed = append(changed, "channel_log_retention_days")
+	}
+	if bot.config.DigestTime != cfg.DigestTime {
+		bot.config.DigestTime = cfg.DigestTime
+		changed = append(changed, "digest_time")
+	}
+	if bot.config.DigestWhenEmpty != cfg.DigestWhenEmpty {
+		bot.config.DigestWhenEmpty = cfg.DigestWhenEmpty
+		changed = append(changed, "digest_when_empty")
+	}
+	if bot.config.DuplicateTitleCheck != cfg.DuplicateTitleCheck {
+		bot.config.DuplicateTitleCheck = cfg.DuplicateTitleCheck
+		changed = append(changed, "duplicate_title_check")
+	}
+	if bot.config.PublicBaseURL != cfg.PublicBaseURL {
+		bot.config.PublicBaseURL = cfg.PublicBaseURL
+		changed = append(changed, "public_base_url")
+	}
+	if bot.config.JoinMessage != cfg.JoinMessage {
+		bot.config.JoinMessage = cfg.JoinMessage
+		changed = append(changed, "join_message")
+	}
+	if bot.config.StagingDatabasePath != cfg.StagingDatabasePath {
+		bot.config.StagingDatabasePath = cfg.StagingDatabasePath
+		changed = append(changed, "staging_database_path")
+	}
+	if bot.config.SlowCommandMillis != cfg.SlowCommandMillis {
+		bot.config.SlowCommandMillis = cfg.SlowCommandMillis
+		changed = append(changed, "slow_command_ms")
+	}
+
+	if bot.config.Server != cfg.Server {
+		ignored = append(ignored, "server")
+	}
+	if bot.config.ServerPassword != cfg.ServerPassword {
+		ignored = append(ignored, "server_password")
+	}
+	if bot.config.PingTimeoutSeconds != cfg.PingTimeoutSeconds {
+		ignored = append(ignored, "ping_timeout_seconds")
+	}
+	if bot.config.KeepAliveSeconds != cfg.KeepAliveSeconds {
+		ignored = append(ignored, "keepalive_seconds")
+	}
+	if bot.config.Nick != cfg.Nick {
+		ignored = append(ignored, "nick")
+	}
+	if bot.config.Channel != cfg.Channel {
+		ignored = append(ignored, "channel")
+	}
+	if bot.config.NickServ != cfg.NickServ {
+		ignored = append(ignored, "nickserv")
+	}
+	if bot.config.DatabasePath != cfg.DatabasePath {
+		ignored = append(ignored, "database_path")
+	}
+	if bot.config.QueryTimeoutSeconds != cfg.QueryTimeoutSeconds {
+		ignored = append(ignored, "query_timeout_seconds")
+	}
+	if bot.config.MaintenanceIntervalHours != cfg.MaintenanceIntervalHours {
+		ignored = append(ignored, "maintenance_interval_hours")
+	}
+
+	return changed, ignored
+}
+
+// watchReloadSignal reloads the config whenever the process receives SIGHUP
+func (bot *CinemaBot) watchReloadSignal() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go func() {
+		for range signals {
+			if err := bot.reloadConfig(); err != nil {
+				log.Printf("Error reloading config: %v", err)
+			}
+		}
+	}()
+}
+
 func (bot *CinemaBot) Close() error {
-	if bot.db != nil {
-		return bot.db.Close()
+	bot.mu.Lock()
+	bot.closeStaging()
+	bot.mu.Unlock()
+	if bot.store != nil {
+		return bot.store.Close()
 	}
 	return nil
 }
 
 func (bot *CinemaBot) setupHandlers() {
 	bot.conn.AddCallback("001", func(e *irc.Event) {
+		bot.recordPong(time.Now())
+
+		bot.mu.Lock()
+		bot.connectedAt = time.Now().UTC()
+		bot.mu.Unlock()
+
 		// If NickServ password is configured, identify
 		if bot.config.NickServ.Password != "" {
-			bot.conn.Privmsg("NickServ", fmt.Sprintf("IDENTIFY %s", bot.config.NickServ.Password))
+			bot.sender.Privmsg(bot.identifyMessage())
 			time.Sleep(2 * time.Second) // Wait for identification
 		}
+		bot.regainNick(e.Arguments[0], time.Now())
 
 		// Join channel
 		bot.conn.Join(bot.config.Channel)
 		log.Printf("Joined %s", bot.config.Channel)
+		if bot.config.NotifyChannel != "" && !strings.EqualFold(bot.config.NotifyChannel, bot.config.Channel) {
+			bot.conn.Join(bot.config.NotifyChannel)
+			log.Printf("Joined %s for notifications", bot.config.NotifyChannel)
+		}
 	})
 
-	bot.conn.AddCallback("PRIVMSG", func(e *irc.Event) {
-		message := e.Message()
-		nick := e.Nick
-		host := e.Host
-
-		// Only respond to messages in our channel
-		if e.Arguments[0] != bot.config.Channel {
+	bot.conn.AddCallback("JOIN", func(e *irc.Event) {
+		// Only our own joins, not everyone else entering the channel
+		if e.Nick != bot.conn.GetNick() || !strings.EqualFold(e.Arguments[0], bot.config.Channel) {
 			return
 		}
 
-		bot.mu.Lock()
-		defer bot.mu.Unlock()
-
-		// Handle showtime command
-		if strings.HasPrefix(message, ".showtime") {
-			if bot.authorizedShowtimeCommand(nick, host) {
-				bot.handleShowtimeCommand(message, nick)
-			} else {
-				bot.conn.Privmsg(bot.config.Channel, fmt.Sprintf("%s: You are not authorized to use this command.", nick))
-				log.Printf("Unauthorized showtime command attempt by %s!%s", nick, host)
-			}
+		bot.mu.RLock()
+		defer bot.mu.RUnlock()
+		bot.snapshot().announceJoin()
+		if bot.outbox != nil {
+			bot.outbox.flush()
 		}
+	})
 
-		// Handle nextmovie command (available to everyone)
-		if strings.HasPrefix(message, ".nextmovie") {
-			bot.handleNextMovieCommand()
-		}
+	// Replace the library's handler, which only ever appends "_"
+	bot.conn.ClearCallback("433")
+	bot.conn.AddCallback("433", bot.handleNickInUse)
+	bot.conn.AddCallback("005", bot.handleISupport)
+	for _, code := range []string{"NOTICE", "QUIT", "NICK"} {
+		bot.conn.AddCallback(code, bot.handleGhostReply)
+	}
 
-		if strings.HasPrefix(message, ".date") {
-			bot.handleDateCommand()
-		}
+	bot.conn.AddCallback("INVITE", bot.handleInvite)
+	bot.conn.AddCallback("391", bot.handleTimeReply)
+
+	bot.conn.AddCallback("PONG", func(e *irc.Event) {
+		bot.recordPong(time.Now())
 	})
-}
 
-func (bot *CinemaBot) handleDateCommand() {
-	// Write the current date in UTC
-	now := time.Now().UTC()
-	bot.conn.Privmsg(bot.config.Channel, fmt.Sprintf("Current time (UTC): %s", now.Format("2006-01-02 15:04:05 MST")))
+	bot.conn.AddCallback("PRIVMSG", func(e *irc.Event) {
+		bot.handlePrivmsg(e.Arguments[0], e.Nick, e.Host, e.Message())
+	})
 }
 
-func (bot *CinemaBot) authorizedShowtimeCommand(nick, host string) bool {
-	if bot.config.AuthorizedNicks[nick] && host == "user/"+nick {
-		return true
+// handlePrivmsg dispatches a channel message to its command. mu is only held
+// while the command copies what it needs from the bot, so slow queries never
+// keep a config reload, .quiet or .staging waiting, nor the readers queued
+// behind them; the store does its own locking. Only .quiet and .staging, which
+// change state, run holding mu exclusively.
+func (bot *CinemaBot) handlePrivmsg(channel, nick, host, message string) {
+	// Only respond to messages in our channel
+	if channel != bot.config.Channel {
+		return
 	}
-	return false
-}
 
-func (bot *CinemaBot) handleNextMovieCommand() {
-	now := time.Now().UTC()
+	// Registered first so it runs last, timing the handler and its lock waits
+	if name := commandName(message); name != "" {
+		defer bot.timeCommand(name, nick)()
+	}
 
-	// Find the most recently started movie (within last 3 hours)
-	currentShowtime, err := bot.getCurrentShowtime(now)
-	if err != nil {
-		log.Printf("Error getting current showtime: %v", err)
-		bot.conn.Privmsg(bot.config.Channel, "Error retrieving current movie information.")
-		return
+	bot.mu.RLock()
+	logging := bot.config.LogChannelMessages
+	bot.mu.RUnlock()
+	if logging {
+		bot.logChannelMessage(channel, nick, host, message, time.Now().UTC())
 	}
 
-	if currentShowtime != nil {
-		duration := now.Sub(currentShowtime.DateTime)
-		timeMessage := bot.formatTimeSince(duration)
-		message := fmt.Sprintf("%s into %s", timeMessage, currentShowtime.Title)
-		bot.conn.Privmsg(bot.config.Channel, message)
-		log.Printf("Current movie response sent: %s", message)
+	if name := commandName(message); name == "quiet" || name == "staging" {
+		bot.mu.Lock()
+		defer bot.mu.Unlock()
+		cmd := &command{CinemaBot: bot, sender: bot.sender, store: bot.store, config: &bot.config, notifiers: bot.notifiers}
+		if !cmd.commandEnabled(channel, name) {
+			return
+		}
+		if !cmd.authorizedShowtimeCommand(channel, nick, host) {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: You are not authorized to use this command.", nick))
+			log.Printf("Unauthorized %s command attempt by %s!%s", name, nick, host)
+		} else if name == "quiet" {
+			cmd.handleQuietCommand(bot.parseArgs(message), time.Now().UTC())
+		} else {
+			cmd.handleStagingCommand(bot.parseArgs(message), nick)
+		}
 		return
 	}
 
-	// If no current movie, find the next upcoming one
-	nextShowtime, err := bot.getNextShowtime(now)
-	if err != nil {
-		log.Printf("Error getting next showtime: %v", err)
-		bot.conn.Privmsg(bot.config.Channel, "Error retrieving next movie information.")
+	bot.mu.RLock()
+	cmd := bot.newCommand(time.Now())
+	bot.mu.RUnlock()
+	cmd.handleCommand(channel, nick, host, message)
+}
+
+// command is the bot as one chat command sees it. Its sender and store are
+// chosen from the quiet and staging state when the command arrives and it
+// has its own copy of the config, so handlers run without holding mu and
+// without touching what the rest of the bot uses.
+type command struct {
+	*CinemaBot
+	sender Sender
+	store  ShowtimeStore
+	config *Config
+	// notifiers is copied from the bot along with config
+	notifiers []Notifier
+	// onStaging is set when store is the staging database
+	onStaging bool
+}
+
+// snapshot returns the bot as background work sees it: the production store
+// and sender and copies of the config and notifiers. The caller holds mu.
+func (bot *CinemaBot) snapshot() *command {
+	config := bot.config
+	return &command{CinemaBot: bot, sender: bot.sender, store: bot.store, config: &config, notifiers: bot.notifiers}
+}
+
+// newCommand returns the context for a command arriving at now. Commands
+// still run while quiet, they just don't answer. On staging every answer is
+// tagged so nobody mistakes it for the real schedule. The caller holds mu.
+func (bot *CinemaBot) newCommand(now time.Time) *command {
+	cmd := bot.snapshot()
+	if bot.staging != nil {
+		cmd.store = bot.staging
+		cmd.onStaging = true
+	}
+	if bot.quiet(now) {
+		cmd.sender = discardSender{}
+	} else if bot.staging != nil {
+		cmd.sender = taggedSender{bot.sender, stagingTag}
+	}
+	return cmd
+}
+
+// handleCommand runs the command in message
+func (bot *command) handleCommand(channel, nick, host, message string) {
+	// Disabled commands are ignored as if they didn't exist
+	if !bot.commandEnabled(channel, commandName(message)) {
 		return
 	}
 
-	if nextShowtime != nil {
-		duration := nextShowtime.DateTime.Sub(now)
-		timeMessage := bot.formatTimeUntil(duration)
-		message := fmt.Sprintf("%s, %s is playing!", timeMessage, nextShowtime.Title)
-		bot.conn.Privmsg(bot.config.Channel, message)
-		//log.Printf("Next movie response sent: %s", message)
+	if name := commandName(message); name != "" && !knownCommand(name) {
+		bot.suggestCommand(channel, name, nick, host)
 		return
 	}
 
-	// No movies at all
-	bot.conn.Privmsg(bot.config.Channel, "No movies scheduled!")
-}
+	// Handle showtime command
+	if strings.HasPrefix(message, ".showtime") {
+		if bot.authorizedShowtimeCommand(channel, nick, host) {
+			bot.handleShowtimeCommand(message, nick)
+		} else {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: You are not authorized to use this command.", nick))
+			log.Printf("Unauthorized showtime command attempt by %s!%s", nick, host)
+		}
+	}
 
-func (bot *CinemaBot) getCurrentShowtime(now time.Time) (*Showtime, error) {
-	// Look for movies that started within the last 3 hours
-	threeHoursAgo := now.Add(-3 * time.Hour)
+	// Handle nextmovie command (available to everyone)
+	if strings.HasPrefix(message, ".nextmovie") {
+		bot.handleNextMovieCommand(bot.parseArgs(message))
+	}
 
-	query := `
-		SELECT id, title, datetime, created_by, created_at 
-		FROM showtimes 
-		WHERE datetime BETWEEN ? AND ? 
-		ORDER BY datetime DESC 
-		LIMIT 1
-	`
+	if strings.HasPrefix(message, ".date") {
+		bot.handleDateCommand()
+	}
 
-	row := bot.db.QueryRow(query, threeHoursAgo.Format(time.RFC3339), now.Format(time.RFC3339))
+	if strings.HasPrefix(message, ".whatplayed") {
+		bot.handleWhatPlayedCommand(bot.parseArgs(message))
+	}
 
-	var showtime Showtime
-	var datetimeStr, createdAtStr string
+	if strings.HasPrefix(message, ".leaderboard") {
+		bot.handleLeaderboardCommand()
+	}
 
-	err := row.Scan(&showtime.ID, &showtime.Title, &datetimeStr, &showtime.CreatedBy, &createdAtStr)
-	if err == sql.ErrNoRows {
-		return nil, nil
+	if strings.HasPrefix(message, ".titles") {
+		bot.handleTitlesCommand(bot.parseArgs(message))
 	}
-	if err != nil {
-		return nil, err
+
+	if strings.HasPrefix(message, ".remind") {
+		bot.handleRemindCommand(bot.parseArgs(message), nick)
 	}
 
-	showtime.DateTime, err = time.Parse(time.RFC3339, datetimeStr)
-	if err != nil {
-		return nil, err
+	if strings.HasPrefix(message, ".uptime") {
+		if bot.authorizedShowtimeCommand(channel, nick, host) {
+			bot.handleUptimeCommand(time.Now().UTC())
+		} else {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: You are not authorized to use this command.", nick))
+			log.Printf("Unauthorized uptime command attempt by %s!%s", nick, host)
+		}
 	}
 
-	showtime.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
-	if err != nil {
-		return nil, err
+	if strings.HasPrefix(message, ".selftest") {
+		if bot.authorizedShowtimeCommand(channel, nick, host) {
+			bot.handleSelftestCommand()
+		} else {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: You are not authorized to use this command.", nick))
+			log.Printf("Unauthorized selftest command attempt by %s!%s", nick, host)
+		}
 	}
 
-	return &showtime, nil
-}
+	if strings.HasPrefix(message, ".clockcheck") {
+		if bot.authorizedShowtimeCommand(channel, nick, host) {
+			bot.handleClockCheckCommand(time.Now().UTC())
+		} else {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: You are not authorized to use this command.", nick))
+			log.Printf("Unauthorized clockcheck command attempt by %s!%s", nick, host)
+		}
+	}
 
-func (bot *CinemaBot) getNextShowtime(now time.Time) (*Showtime, error) {
-	query := `
-		SELECT id, title, datetime, created_by, created_at 
-		FROM showtimes 
-		WHERE datetime > ? 
-		ORDER BY datetime ASC 
-		LIMIT 1
-	`
+	if strings.HasPrefix(message, ".debug") {
+		if bot.authorizedShowtimeCommand(channel, nick, host) {
+			bot.handleDebugCommand(message)
+		} else {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: You are not authorized to use this command.", nick))
+			log.Printf("Unauthorized debug command attempt by %s!%s", nick, host)
+		}
+	}
+}
 
-	row := bot.db.QueryRow(query, now.Format(time.RFC3339))
+// replyError answers a command whose store call failed with message, unless
+// the failure is one users can act on: a timeout is worth retrying, while a
+// full disk or read-only database needs the operator and is logged as such
+func (bot *command) replyError(err error, message string) {
+	if errors.Is(err, context.DeadlineExceeded) {
+		message = "The database is temporarily unavailable, please try again shortly."
+	} else if problem := writeFailure(err); problem != "" {
+		log.Printf("ALERT: database writes are failing because %s: %v", problem, err)
+		message = fmt.Sprintf("Can't save changes: %s. Please let the bot's operator know.", problem)
+	}
+	bot.sender.Privmsg(bot.config.Channel, message)
+}
 
-	var showtime Showtime
-	var datetimeStr, createdAtStr string
+// commandName returns the command a message invokes without its dot, e.g.
+// "showtime" for ".showtime -list", or "" when it isn't a command
+func commandName(message string) string {
+	if !strings.HasPrefix(message, ".") {
+		return ""
+	}
+	fields := strings.Fields(message)
+	return strings.ToLower(strings.TrimPrefix(fields[0], "."))
+}
 
-	err := row.Scan(&showtime.ID, &showtime.Title, &datetimeStr, &showtime.CreatedBy, &createdAtStr)
-	if err == sql.ErrNoRows {
-		return nil, nil
+// commandEnabled reports whether command may be used in channel according to
+// channel_commands
+func (bot *command) commandEnabled(channel, command string) bool {
+	if command == "" {
+		return true
 	}
-	if err != nil {
-		return nil, err
+	for configured, allowed := range bot.config.ChannelCommands {
+		if !strings.EqualFold(configured, channel) {
+			continue
+		}
+		for _, name := range allowed {
+			if strings.EqualFold(strings.TrimPrefix(name, "."), command) {
+				return true
+			}
+		}
+		return false
 	}
+	return true
+}
 
-	showtime.DateTime, err = time.Parse(time.RFC3339, datetimeStr)
-	if err != nil {
-		return nil, err
+// commands are the names the PRIVMSG handler dispatches on. Like the
+// handler, a name followed by anything (".showtimes") still counts.
+var commands = []string{"showtime", "nextmovie", "date", "whatplayed", "leaderboard", "titles", "remind", "uptime", "selftest", "debug", "quiet", "staging", "clockcheck"}
+
+// authorizedCommands are the commands only authorized nicks may use
+var authorizedCommands = map[string]bool{"showtime": true, "uptime": true, "selftest": true, "debug": true, "quiet": true, "staging": true, "clockcheck": true}
+
+// maxSuggestionDistance is how many edits away a typo may be and still get a
+// suggestion, and minSuggestionLength how long a word must be to get one at
+// all, so chatter like ".ok" is left alone
+const (
+	maxSuggestionDistance = 2
+	minSuggestionLength   = 3
+)
+
+func knownCommand(name string) bool {
+	for _, command := range commands {
+		if strings.HasPrefix(name, command) {
+			return true
+		}
 	}
+	return false
+}
 
-	showtime.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
-	if err != nil {
-		return nil, err
+// suggestCommand answers an unknown command with the closest one nick may
+// use, if any is a completion of it or within maxSuggestionDistance edits.
+// Anything that doesn't look like a word (e.g. "...") or is shorter than
+// minSuggestionLength is ignored.
+func (bot *command) suggestCommand(channel, name, nick, host string) {
+	if r, _ := utf8.DecodeRuneInString(name); !unicode.IsLetter(r) || utf8.RuneCountInString(name) < minSuggestionLength {
+		return
 	}
 
-	return &showtime, nil
+	best, bestDistance := "", maxSuggestionDistance+1
+	for _, command := range commands {
+		if !bot.commandEnabled(channel, command) {
+			continue
+		}
+		if authorizedCommands[command] && !bot.authorizedShowtimeCommand(channel, nick, host) {
+			continue
+		}
+		distance := levenshtein(name, command)
+		if strings.HasPrefix(command, name) {
+			distance = 0
+		}
+		if distance < bestDistance {
+			best, bestDistance = command, distance
+		}
+	}
+	if best == "" {
+		return
+	}
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: Did you mean .%s?", nick, best))
 }
 
-func (bot *CinemaBot) createShowtime(args []string, nick string) {
-	var id, title, date string
-	var hours, minutes, seconds, month, day, year int
-	var err error
-
-	// Parse arguments
-	for _, part := range args[2:] { // Skip ";showtime" and "-create"
-		if strings.HasPrefix(part, "-id=") {
-			id = strings.Trim(strings.TrimPrefix(part, "-id="), "\"")
-		} else if strings.HasPrefix(part, "-title=") {
-			title = strings.Trim(strings.TrimPrefix(part, "-title="), "\"")
-		} else if strings.HasPrefix(part, "-hour=") || strings.HasPrefix(part, "-hours=") {
-			var hourStr string
-			if strings.HasPrefix(part, "-hour=") {
-				hourStr = strings.Trim(strings.TrimPrefix(part, "-hour="), "\"")
-			} else {
-				hourStr = strings.Trim(strings.TrimPrefix(part, "-hours="), "\"")
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	previous := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current := make([]int, len(rb)+1)
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
 			}
-			hours, err = strconv.Atoi(hourStr)
-			if err != nil || hours < 0 || hours > 23 {
-				bot.conn.Privmsg(bot.config.Channel, "Invalid hour value (must be 0-23).")
-				return
-			}
-		} else if strings.HasPrefix(part, "-minute=") || strings.HasPrefix(part, "-minutes=") {
-			var minStr string
-			if strings.HasPrefix(part, "-minute=") {
-				minStr = strings.Trim(strings.TrimPrefix(part, "-minute="), "\"")
-			} else {
-				minStr = strings.Trim(strings.TrimPrefix(part, "-minutes="), "\"")
+			current[j] = previous[j-1] + cost
+			if previous[j]+1 < current[j] {
+				current[j] = previous[j] + 1
 			}
-			minutes, err = strconv.Atoi(minStr)
-			if err != nil || minutes < 0 || minutes > 59 {
-				bot.conn.Privmsg(bot.config.Channel, "Invalid minute value (must be 0-59).")
-				return
+			if current[j-1]+1 < current[j] {
+				current[j] = current[j-1] + 1
 			}
-		} else if strings.HasPrefix(part, "-second=") || strings.HasPrefix(part, "-seconds=") || strings.HasPrefix(part, "-sec=") {
-			var secStr string
-			if strings.HasPrefix(part, "-second=") {
-				secStr = strings.Trim(strings.TrimPrefix(part, "-second="), "\"")
-			} else if strings.HasPrefix(part, "-seconds=") {
+		}
+		previous = current
+	}
+	return previous[len(rb)]
+}
+
+// handleQuietCommand silences the bot's channel replies for a Go duration
+// such as "30m", or lifts the silence early with "off"
+func (bot *command) handleQuietCommand(args []string, now time.Time) {
+	if len(args) != 2 {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .quiet 30m | .quiet off")
+		return
+	}
+
+	if args[1] == "off" {
+		bot.quietUntil = time.Time{}
+		bot.sender.Privmsg(bot.config.Channel, "Quiet mode off.")
+		return
+	}
+
+	duration, err := time.ParseDuration(args[1])
+	if err != nil || duration <= 0 {
+		bot.sender.Privmsg(bot.config.Channel, "Invalid duration (e.g. 30m, 1h30m).")
+		return
+	}
+
+	bot.quietUntil = now.Add(duration)
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Going quiet until %s. Use .quiet off to resume early.", bot.formatTime(bot.quietUntil)))
+}
+
+// quiet reports whether channel replies are currently silenced
+func (bot *CinemaBot) quiet(now time.Time) bool {
+	return now.Before(bot.quietUntil)
+}
+
+// discardSender drops every message
+type discardSender struct{}
+
+func (discardSender) Privmsg(target, message string) {}
+
+// identifyMessage returns the services nick and the message that identifies
+// the bot to it, following the nickserv settings
+func (bot *CinemaBot) identifyMessage() (target, message string) {
+	target = bot.config.NickServ.Service
+	if target == "" {
+		target = "NickServ"
+	}
+	command := bot.config.NickServ.IdentifyCommand
+	if command == "" {
+		command = "IDENTIFY {password}"
+	}
+	message = strings.NewReplacer("{nick}", bot.config.Nick, "{password}", bot.config.NickServ.Password).Replace(command)
+	return target, message
+}
+
+// announceJoin sends the configured join_message, if any
+func (bot *command) announceJoin() {
+	if bot.config.JoinMessage == "" {
+		return
+	}
+	bot.sender.Privmsg(bot.config.Channel, bot.config.JoinMessage)
+}
+
+func (bot *command) handleDateCommand() {
+	// Write the current date in the display timezone
+	now := time.Now().UTC()
+	if bot.showingBothTimes() {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Current time: %s", bot.formatTime(now)))
+		return
+	}
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Current time (%s): %s", bot.displayLocation(), bot.formatTime(now)))
+}
+
+// displayLocation is the zone times are rendered in, UTC unless configured
+func (bot *command) displayLocation() *time.Location {
+	if bot.config.location == nil {
+		return time.UTC
+	}
+	return bot.config.location
+}
+
+// showingBothTimes reports whether times are rendered in UTC and the display
+// timezone; this only applies when a non-UTC display timezone is configured
+func (bot *command) showingBothTimes() bool {
+	return bot.config.ShowBothTimes && bot.displayLocation() != time.UTC
+}
+
+// formatTime renders t for channel output in the display timezone, or as
+// "2025-06-13 20:00:00 UTC (16:00 EDT)" when showing both times. The local
+// part only repeats the date when it differs from the UTC date.
+func (bot *command) formatTime(t time.Time) string {
+	layout := bot.timeFormat()
+	if !bot.showingBothTimes() {
+		return t.In(bot.displayLocation()).Format(layout)
+	}
+
+	utc := t.UTC()
+	local := t.In(bot.displayLocation())
+	localLayout := "15:04 MST"
+	if strings.Contains(layout, "PM") || strings.Contains(layout, "pm") {
+		localLayout = "3:04 PM MST"
+	}
+	if local.Format("2006-01-02") != utc.Format("2006-01-02") {
+		localLayout = "2006-01-02 " + localLayout
+	}
+	return fmt.Sprintf("%s (%s)", utc.Format(layout), local.Format(localLayout))
+}
+
+// timeFormat is the configured time_format, or the default 24-hour layout
+func (bot *command) timeFormat() string {
+	if bot.config.TimeFormat == "" {
+		return defaultTimeFormat
+	}
+	return bot.config.TimeFormat
+}
+
+func (bot *command) authorizedShowtimeCommand(channel, nick, host string) bool {
+	if bot.config.AuthorizedNicks.Allows(channel, nick) && bot.trustedHost(nick, host) {
+		return true
+	}
+	return false
+}
+
+// trustedHost reports whether host passes the host check for nick: its
+// auth_host_patterns entry, else auth_host_pattern, else being "user/<nick>"
+func (bot *command) trustedHost(nick, host string) bool {
+	if pattern, ok := bot.config.authHosts[nick]; ok {
+		return pattern.MatchString(host)
+	}
+	if bot.config.authHost != nil {
+		return bot.config.authHost.MatchString(host)
+	}
+	return host == "user/"+nick
+}
+
+func (bot *command) handleNextMovieCommand(args []string) {
+	bot.nextMovieAt(args, time.Now().UTC())
+}
+
+// nextMovieAt answers .nextmovie as of now
+func (bot *command) nextMovieAt(args []string, now time.Time) {
+	// -precise keeps seconds in the countdown even when hours away
+	granularity := coarseGranularity
+	var id string
+	for _, arg := range args[1:] {
+		if arg == "-precise" {
+			granularity = preciseGranularity
+		} else if !strings.HasPrefix(arg, "-") {
+			id = arg
+		}
+	}
+
+	if id != "" {
+		bot.announceShowtimeByID(id, now, granularity)
+		return
+	}
+
+	// Find the most recently started movie, which is only current until its
+	// runtime (or the current window, when that isn't known) is over
+	currentShowtime, err := bot.store.Current(now, whatPlayedLookback)
+	if err != nil {
+		log.Printf("Error getting current showtime: %v", err)
+		bot.replyError(err, "Error retrieving current movie information.")
+		return
+	}
+	if currentShowtime != nil && now.Sub(currentShowtime.DateTime) >= bot.playingDuration(*currentShowtime) {
+		currentShowtime = nil
+	}
+
+	// The one or two showtimes after the current one, for double features
+	upcoming, err := bot.store.Upcoming(now, 2)
+	if err != nil {
+		log.Printf("Error getting next showtime: %v", err)
+		bot.replyError(err, "Error retrieving next movie information.")
+		return
+	}
+
+	// Cancelled showtimes before this were passed over to find the next one
+	var nextStart time.Time
+	if len(upcoming) > 0 {
+		nextStart = upcoming[0].DateTime
+	}
+
+	var message string
+	switch {
+	case currentShowtime != nil:
+		duration := now.Sub(currentShowtime.DateTime)
+		message = fmt.Sprintf("%s into %s", bot.formatTimeSince(duration, granularity), currentShowtime.Title)
+		if bot.justStarted(duration) {
+			message = fmt.Sprintf("%s just started!", currentShowtime.Title)
+		}
+		upcoming = append([]Showtime{*currentShowtime}, upcoming...)
+	case len(upcoming) > 0:
+		message = bot.nextShowtimeMessage(upcoming[0], now, granularity)
+	default:
+		message = "No movies scheduled!"
+	}
+
+	if len(upcoming) > 1 {
+		message = bot.appendFollowing(message, upcoming[1], now, granularity)
+	}
+	if bot.config.NextMovieMentionCancelled {
+		message += bot.cancelledNote(now, nextStart)
+	}
+	bot.sender.Privmsg(bot.config.Channel, message)
+	if currentShowtime != nil {
+		log.Printf("Current movie response sent: %s", message)
+	}
+}
+
+// cancelledNote names the cancelled showtimes starting after now and, unless
+// until is zero, no later than until: " (Casablanca was cancelled.)", or ""
+// when there are none
+func (bot *command) cancelledNote(now, until time.Time) string {
+	showtimes, err := bot.store.List(ShowtimeFilter{From: now, To: until})
+	if err != nil {
+		log.Printf("Error listing cancelled showtimes: %v", err)
+		return ""
+	}
+
+	var titles []string
+	for _, showtime := range showtimes {
+		if showtime.Cancelled && showtime.DateTime.After(now) {
+			titles = append(titles, showtime.Title)
+		}
+	}
+	switch len(titles) {
+	case 0:
+		return ""
+	case 1:
+		return fmt.Sprintf(" (%s was cancelled.)", titles[0])
+	default:
+		return fmt.Sprintf(" (%s were cancelled.)", strings.Join(titles, ", "))
+	}
+}
+
+// appendFollowing adds the showtime after the one message is about, with its
+// countdown: "..., then Vertigo in 2 hours" or "...! Then Vertigo in 2 hours."
+func (bot *command) appendFollowing(message string, following Showtime, now time.Time, granularity Granularity) string {
+	countdown := bot.formatTimeUntil(following.DateTime.Sub(now), granularity)
+	countdown = strings.ToLower(countdown[:1]) + countdown[1:]
+	if strings.HasSuffix(message, "!") || strings.HasSuffix(message, ".") {
+		return fmt.Sprintf("%s Then %s %s.", message, following.Title, countdown)
+	}
+	return fmt.Sprintf("%s, then %s %s", message, following.Title, countdown)
+}
+
+// announceNextShowtime replies with the countdown to the next showtime starting
+// after now, ignoring anything currently playing
+func (bot *command) announceNextShowtime(now time.Time, granularity Granularity) {
+	nextShowtime, err := bot.store.Next(now)
+	if err != nil {
+		log.Printf("Error getting next showtime: %v", err)
+		bot.replyError(err, "Error retrieving next movie information.")
+		return
+	}
+
+	if nextShowtime != nil {
+		bot.sender.Privmsg(bot.config.Channel, bot.nextShowtimeMessage(*nextShowtime, now, granularity))
+		return
+	}
+
+	// No movies at all
+	bot.sender.Privmsg(bot.config.Channel, "No movies scheduled!")
+}
+
+// nextShowtimeMessage is the countdown announcement for a showtime that hasn't
+// started yet
+func (bot *command) nextShowtimeMessage(showtime Showtime, now time.Time, granularity Granularity) string {
+	duration := showtime.DateTime.Sub(now)
+	if bot.startingNow(duration) {
+		return fmt.Sprintf("%s is starting now!", showtime.Title)
+	}
+	message := fmt.Sprintf("%s, %s is playing!", bot.formatTimeUntil(duration, granularity), showtime.Title)
+	if bot.startingSoon(duration) {
+		message = "\x02Starting soon!\x02 " + message
+	}
+	return message
+}
+
+// announceShowtimeByID replies with the countdown to one specific showtime,
+// or how far into it we are when it's playing
+func (bot *command) announceShowtimeByID(id string, now time.Time, granularity Granularity) {
+	showtime, err := bot.store.GetByID(id)
+	if err != nil {
+		log.Printf("Error getting showtime: %v", err)
+		bot.replyError(err, "Error retrieving showtime.")
+		return
+	}
+	if showtime == nil {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Showtime with ID '%s' not found.", id))
+		return
+	}
+
+	var message string
+	switch since := now.Sub(showtime.DateTime); {
+	case showtime.Cancelled:
+		message = fmt.Sprintf("%s (%s) was cancelled.", showtime.Title, bot.formatTime(showtime.DateTime))
+	case since < 0 && bot.startingNow(-since):
+		message = fmt.Sprintf("%s is starting now!", showtime.Title)
+	case since < 0:
+		message = fmt.Sprintf("%s, %s is playing!", bot.formatTimeUntil(-since, granularity), showtime.Title)
+	case bot.justStarted(since):
+		message = fmt.Sprintf("%s just started!", showtime.Title)
+	case since < bot.playingDuration(*showtime):
+		message = fmt.Sprintf("%s into %s", bot.formatTimeSince(since, granularity), showtime.Title)
+	default:
+		message = fmt.Sprintf("%s already played (%s).", showtime.Title, bot.formatRelativeTime(showtime.DateTime, now))
+	}
+	bot.sender.Privmsg(bot.config.Channel, message)
+}
+
+// whatPlayedLookback is how far before the asked-about time .whatplayed and
+// .nextmovie look for a start, long enough for any runtime
+const whatPlayedLookback = 12 * time.Hour
+
+// handleWhatPlayedCommand reports which showtime was playing at a past time.
+// A showtime counts from its start until its cached OMDb runtime has elapsed,
+// or for the current window when the runtime isn't known.
+func (bot *command) handleWhatPlayedCommand(args []string) {
+	if len(args) < 2 {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .whatplayed 2025-06-07 20:00")
+		return
+	}
+
+	at, err := parseDate(strings.Join(args[1:], " "))
+	if err != nil {
+		bot.sender.Privmsg(bot.config.Channel, err.Error())
+		return
+	}
+
+	showtime, err := bot.store.Current(at, whatPlayedLookback)
+	if err != nil {
+		log.Printf("Error getting showtime playing at %v: %v", at, err)
+		bot.replyError(err, "Error retrieving showtime.")
+		return
+	}
+
+	if showtime == nil || at.Sub(showtime.DateTime) > bot.playingDuration(*showtime) {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Nothing was playing at %s.", bot.formatTime(at)))
+		return
+	}
+
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("At %s: [%s] %s (started %s)",
+		bot.formatTime(at), showtime.ID, showtime.Title, bot.formatTime(showtime.DateTime)))
+}
+
+// playingDuration is how long after its start a showtime was on screen: its
+// cached runtime when known, the current window otherwise. It never fetches
+// from OMDb so it's cheap to call.
+func (bot *command) playingDuration(showtime Showtime) time.Duration {
+	if runtime := bot.knownRuntime(showtime); runtime > 0 {
+		return runtime
+	}
+	return bot.currentWindow()
+}
+
+// knownRuntime is the showtime's stored runtime, else the one cached from
+// OMDb for its title, else zero
+func (bot *command) knownRuntime(showtime Showtime) time.Duration {
+	if showtime.RuntimeMinutes > 0 {
+		return time.Duration(showtime.RuntimeMinutes) * time.Minute
+	}
+	info, err := bot.store.CachedMovieInfo(showtime.Title)
+	if err != nil {
+		log.Printf("Error reading cached movie info for %q: %v", showtime.Title, err)
+	}
+	if info != nil {
+		return info.RuntimeDuration()
+	}
+	return 0
+}
+
+// leaderboardSize is how many schedulers .leaderboard lists
+const leaderboardSize = 5
+
+// handleLeaderboardCommand lists who has scheduled the most showtimes
+func (bot *command) handleLeaderboardCommand() {
+	counts, err := bot.store.TopCreators(leaderboardSize)
+	if err != nil {
+		log.Printf("Error getting leaderboard: %v", err)
+		bot.replyError(err, "Error retrieving leaderboard.")
+		return
+	}
+	if len(counts) == 0 {
+		bot.sender.Privmsg(bot.config.Channel, "No showtimes scheduled yet.")
+		return
+	}
+
+	entries := make([]string, len(counts))
+	for i, count := range counts {
+		entries[i] = fmt.Sprintf("%d. %s (%d)", i+1, count.Nick, count.Count)
+	}
+	bot.sender.Privmsg(bot.config.Channel, "Top schedulers: "+strings.Join(entries, ", "))
+}
+
+// handleTitlesCommand lists every title ever scheduled, optionally only those
+// containing -search, split over as many messages as needed
+func (bot *command) handleTitlesCommand(args []string) {
+	search := flagValue(args, "-search")
+	titles, err := bot.store.Titles(search)
+	if err != nil {
+		log.Printf("Error getting titles: %v", err)
+		bot.replyError(err, "Error retrieving titles.")
+		return
+	}
+
+	header := fmt.Sprintf("%s scheduled: ", pluralize(len(titles), "title"))
+	if search != "" {
+		header = fmt.Sprintf("%s matching '%s': ", pluralize(len(titles), "title"), search)
+	}
+	if len(titles) == 0 {
+		bot.sender.Privmsg(bot.config.Channel, strings.TrimSuffix(header, ": ")+".")
+		return
+	}
+
+	titles[0] = header + titles[0]
+	for _, chunk := range chunkItems(titles, ", ", bot.channelBytes()) {
+		bot.sender.Privmsg(bot.config.Channel, chunk)
+	}
+}
+
+// currentWindow is how long after its start a showtime counts as playing
+func (bot *command) currentWindow() time.Duration {
+	if bot.config.CurrentWindowHours <= 0 {
+		return defaultCurrentWindowHours * time.Hour
+	}
+	return time.Duration(bot.config.CurrentWindowHours) * time.Hour
+}
+
+// startingSoon reports whether a movie duration away is within
+// soon_threshold_minutes
+func (bot *command) startingSoon(duration time.Duration) bool {
+	threshold := time.Duration(bot.config.SoonThresholdMinutes) * time.Minute
+	return threshold > 0 && duration <= threshold
+}
+
+// startingNow reports whether a movie starting in duration is within the
+// just_started_seconds window before its start, the mirror of justStarted, so
+// .nextmovie says it is starting now rather than counting down the seconds
+func (bot *command) startingNow(duration time.Duration) bool {
+	window := bot.justStartedWindow()
+	return window > 0 && duration.Round(time.Second) < window
+}
+
+// justStarted reports whether a movie that has been playing for duration is
+// still within the configured "just started" grace window
+func (bot *command) justStarted(duration time.Duration) bool {
+	return duration.Round(time.Second) < bot.justStartedWindow()
+}
+
+// justStartedWindow returns just_started_seconds, or its default when unset
+func (bot *command) justStartedWindow() time.Duration {
+	if bot.config.JustStartedSeconds == nil {
+		return defaultJustStartedSeconds * time.Second
+	}
+	return time.Duration(*bot.config.JustStartedSeconds) * time.Second
+}
+
+func (bot *command) createShowtime(args []string, nick string) {
+	var id, title, note, after, gap string
+	var tags []string
+	var lookup, force bool
+
+	// Parse arguments
+	for _, part := range args[2:] { // Skip ";showtime" and "-create"
+		if strings.HasPrefix(part, "-id=") {
+			id = strings.Trim(strings.TrimPrefix(part, "-id="), "\"")
+		} else if strings.HasPrefix(part, "-title=") {
+			title = strings.Trim(strings.TrimPrefix(part, "-title="), "\"")
+		} else if strings.HasPrefix(part, "-note=") {
+			note = strings.TrimSpace(bot.stripControlCodes(strings.Trim(strings.TrimPrefix(part, "-note="), "\"")))
+		} else if strings.HasPrefix(part, "-tags=") {
+			tags = normalizeTags(strings.Trim(strings.TrimPrefix(part, "-tags="), "\""))
+		} else if strings.HasPrefix(part, "-after=") {
+			after = strings.Trim(strings.TrimPrefix(part, "-after="), "\"")
+		} else if strings.HasPrefix(part, "-gap=") {
+			gap = strings.Trim(strings.TrimPrefix(part, "-gap="), "\"")
+		} else if part == "-lookup" {
+			lookup = true
+		} else if part == "-force" {
+			force = true
+		}
+	}
+
+	title = bot.sanitizeTitle(title)
+
+	// Validate required fields
+	if title == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Required: -title=\"title\" (and -id=\"id\" when the title has no letters or digits)")
+		return
+	}
+	if id == "" {
+		generated, err := bot.idFromTitle(title)
+		if err != nil {
+			log.Printf("Error checking showtime existence: %v", err)
+			bot.replyError(err, "Error checking showtime existence.")
+			return
+		}
+		if generated == "" {
+			bot.sender.Privmsg(bot.config.Channel, "Couldn't make a free id from that title. Add -id=\"id\".")
+			return
+		}
+		id = generated
+	}
+	if err := bot.validateID(id, title); err != nil {
+		bot.sender.Privmsg(bot.config.Channel, err.Error())
+		return
+	}
+
+	// Check if ID already exists
+	existing, err := bot.store.GetByID(id)
+	if err != nil {
+		log.Printf("Error checking showtime existence: %v", err)
+		bot.replyError(err, "Error checking showtime existence.")
+		return
+	}
+	if existing != nil {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Showtime with ID '%s' already exists.", id))
+		return
+	}
+
+	// Create datetime
+	now := time.Now().UTC()
+	var datetime time.Time
+	if after != "" {
+		previous, lookupErr := bot.store.GetByID(after)
+		if lookupErr != nil {
+			log.Printf("Error getting showtime: %v", lookupErr)
+			bot.replyError(lookupErr, "Error retrieving showtime.")
+			return
+		}
+		if previous == nil {
+			bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(after))
+			return
+		}
+		datetime, err = bot.startAfter(*previous, gap)
+	} else {
+		datetime, err = buildDatetime(args[2:], now)
+	}
+	if err != nil {
+		bot.sender.Privmsg(bot.config.Channel, err.Error())
+		return
+	}
+
+	// Create and store the showtime in database
+	showtime := Showtime{
+		ID:        id,
+		Title:     title,
+		DateTime:  datetime,
+		CreatedBy: nick,
+		CreatedAt: now,
+		AdminNote: note,
+		Tags:      tags,
+	}
+
+	if lookup {
+		bot.applyTMDBLookup(&showtime)
+		title = showtime.Title
+	}
+
+	if bot.config.DuplicateTitleCheck && !force {
+		duplicate, err := bot.sameTitleOnDay(title, datetime)
+		if err != nil {
+			log.Printf("Error checking for duplicate titles: %v", err)
+			bot.replyError(err, "Error checking for duplicate titles.")
+			return
+		}
+		if duplicate != nil {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("[%s] %s is already scheduled on %s. Add -force to create it anyway.",
+				duplicate.ID, duplicate.Title, duplicate.DateTime.Format("2006-01-02")))
+			return
+		}
+	}
+
+	if err := bot.store.Create(showtime); err != nil {
+		log.Printf("Error inserting showtime: %v", err)
+		bot.replyError(err, "Error creating showtime.")
+		return
+	}
+
+	timeStr := bot.formatTime(datetime)
+	confirmation := fmt.Sprintf("Created showtime: [%s] %s - %s", id, title, timeStr)
+	if link := bot.showtimeLink(id); link != "" {
+		confirmation += " - " + link
+	}
+	bot.sender.Privmsg(bot.config.Channel, confirmation)
+	bot.notify(Event{Kind: "created", Showtime: showtime})
+
+	// Debug logging
+	//log.Printf("Created showtime [%s]: %s at %s (created by %s)", id, title, timeStr, nick)
+}
+
+// startAfter computes the start of a showtime following previous, gap after
+// it ends, for double features. The runtime of previous must be known. The
+// error message is suitable for replying to the user.
+func (bot *command) startAfter(previous Showtime, gap string) (time.Time, error) {
+	var pause time.Duration
+	if gap != "" {
+		var err error
+		if pause, err = time.ParseDuration(gap); err != nil || pause < 0 {
+			return time.Time{}, fmt.Errorf("Invalid -gap '%s' (e.g. 15m, 1h).", gap)
+		}
+	}
+
+	runtime := bot.knownRuntime(previous)
+	if runtime <= 0 {
+		return time.Time{}, fmt.Errorf("[%s] %s has no runtime to schedule after. Set one with .showtime -set-runtime=\"%s\" -runtime=minutes.",
+			previous.ID, previous.Title, previous.ID)
+	}
+	return chainedStart(previous.DateTime, runtime, pause), nil
+}
+
+// chainedStart is when a showtime gap after one starting at start and running
+// for runtime begins
+func chainedStart(start time.Time, runtime, gap time.Duration) time.Time {
+	return start.Add(runtime).Add(gap).UTC()
+}
+
+// normalizeTags splits a comma-separated -tags value into trimmed, lowercase
+// tags, dropping empties and repeats
+func normalizeTags(value string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(value, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+// sameTitleOnDay returns a showtime titled title (ignoring case) on the same
+// UTC day as datetime, or nil when there is none
+func (bot *command) sameTitleOnDay(title string, datetime time.Time) (*Showtime, error) {
+	day := datetime.UTC().Truncate(24 * time.Hour)
+	showtimes, err := bot.store.List(ShowtimeFilter{From: day, To: day.Add(24*time.Hour - time.Second)})
+	if err != nil {
+		return nil, err
+	}
+	for _, showtime := range showtimes {
+		if strings.EqualFold(showtime.Title, title) {
+			return &showtime, nil
+		}
+	}
+	return nil, nil
+}
+
+// longestGap reports the longest stretch between the starts of consecutive
+// upcoming showtimes, to show organizers where the schedule runs dry
+func (bot *command) longestGap(now time.Time) {
+	upcoming, err := bot.store.Upcoming(now, 0)
+	if err != nil {
+		log.Printf("Error getting upcoming showtimes: %v", err)
+		bot.replyError(err, "Error retrieving showtimes.")
+		return
+	}
+	if len(upcoming) < 2 {
+		bot.sender.Privmsg(bot.config.Channel, "Need at least two upcoming showtimes to find a gap.")
+		return
+	}
+
+	widest := 1
+	for i := 2; i < len(upcoming); i++ {
+		if upcoming[i].DateTime.Sub(upcoming[i-1].DateTime) > upcoming[widest].DateTime.Sub(upcoming[widest-1].DateTime) {
+			widest = i
+		}
+	}
+	before, after := upcoming[widest-1], upcoming[widest]
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Longest gap: %s between [%s] %s (%s) and [%s] %s (%s).",
+		bot.formatTimeSince(after.DateTime.Sub(before.DateTime), coarseGranularity),
+		before.ID, before.Title, bot.formatTime(before.DateTime),
+		after.ID, after.Title, bot.formatTime(after.DateTime)))
+}
+
+// compareShowtimes answers -between="id" "other id" with the time between the
+// two starts and, when the earlier one's runtime is known, whether it runs
+// into the later one
+func (bot *command) compareShowtimes(args []string) {
+	var ids []string
+	for i, arg := range args {
+		if strings.HasPrefix(arg, "-between=") {
+			ids = append(ids, flagValue(args, "-between"))
+			if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+				ids = append(ids, args[i+1])
+			}
+			break
+		}
+	}
+	if len(ids) != 2 || ids[0] == "" || ids[1] == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -between=\"id\" \"other id\"")
+		return
+	}
+
+	showtimes := make([]Showtime, 0, len(ids))
+	for _, id := range ids {
+		showtime, err := bot.store.GetByID(id)
+		if err != nil {
+			log.Printf("Error getting showtime: %v", err)
+			bot.replyError(err, "Error retrieving showtime.")
+			return
+		}
+		if showtime == nil {
+			bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(id))
+			return
+		}
+		showtimes = append(showtimes, *showtime)
+	}
+
+	first, second := showtimes[0], showtimes[1]
+	if second.DateTime.Before(first.DateTime) {
+		first, second = second, first
+	}
+	gap := second.DateTime.Sub(first.DateTime)
+
+	message := fmt.Sprintf("[%s] %s and [%s] %s start at the same time.", first.ID, first.Title, second.ID, second.Title)
+	if gap > 0 {
+		message = fmt.Sprintf("[%s] %s starts %s before [%s] %s.", first.ID, first.Title,
+			bot.formatTimeSince(gap, normalGranularity), second.ID, second.Title)
+	}
+
+	runtime := bot.knownRuntime(first)
+	switch end := first.DateTime.Add(runtime); {
+	case runtime == 0:
+		message += fmt.Sprintf(" %s has no known runtime, so overlap can't be checked.", first.Title)
+	case end.After(second.DateTime):
+		message += fmt.Sprintf(" They overlap by %s.", bot.formatTimeSince(end.Sub(second.DateTime), normalGranularity))
+	case end.Equal(second.DateTime):
+		message += fmt.Sprintf(" %s ends just as %s starts.", first.Title, second.Title)
+	default:
+		message += fmt.Sprintf(" %s ends %s before %s starts.", first.Title, bot.formatTimeSince(second.DateTime.Sub(end), normalGranularity), second.Title)
+	}
+	bot.sender.Privmsg(bot.config.Channel, message)
+}
+
+// showtimeLink returns the showtime's row on the public schedule page, or ""
+// without public_base_url
+func (bot *command) showtimeLink(id string) string {
+	if bot.config.PublicBaseURL == "" {
+		return ""
+	}
+	return strings.TrimRight(bot.config.PublicBaseURL, "/") + "/showtimes.html#" + url.PathEscape(id)
+}
+
+// cloneWeek copies every showtime in the seven days from now to the same time
+// a week later. Copies get the original id suffixed with their new date, and
+// any copy whose id is already taken is skipped.
+func (bot *command) cloneWeek(now time.Time, nick string) {
+	const week = 7 * 24 * time.Hour
+
+	showtimes, err := bot.store.List(ShowtimeFilter{From: now, To: now.Add(week - time.Second)})
+	if err != nil {
+		log.Printf("Error listing showtimes: %v", err)
+		bot.replyError(err, "Error retrieving showtimes.")
+		return
+	}
+	if len(showtimes) == 0 {
+		bot.sender.Privmsg(bot.config.Channel, "No showtimes in the next 7 days to clone.")
+		return
+	}
+
+	var clones []Showtime
+	invalid := 0
+	for _, showtime := range showtimes {
+		clone := showtime
+		clone.DateTime = showtime.DateTime.Add(week)
+		// A clone of a clone replaces the date suffix rather than adding another
+		base := strings.TrimSuffix(showtime.ID, "-"+showtime.DateTime.Format("0102"))
+		clone.ID = bot.storableID(bot.withSuffix(base, "-"+clone.DateTime.Format("0102")), clone.Title)
+		if clone.ID == "" {
+			invalid++
+			continue
+		}
+		clone.CreatedBy = nick
+		clone.CreatedAt = now
+		// A one-off cancellation isn't part of the weekly pattern
+		clone.Cancelled = false
+		clones = append(clones, clone)
+	}
+
+	created, err := bot.store.CreateAll(clones)
+	if err != nil {
+		log.Printf("Error cloning showtimes: %v", err)
+		bot.replyError(err, "Error cloning showtimes.")
+		return
+	}
+
+	message := fmt.Sprintf("Cloned %s to next week.", pluralize(len(created), "showtime"))
+	if skipped := len(clones) - len(created); skipped > 0 {
+		message += fmt.Sprintf(" Skipped %d with existing ids.", skipped)
+	}
+	if invalid > 0 {
+		message += fmt.Sprintf(" Skipped %d whose id couldn't be made valid.", invalid)
+	}
+	bot.sender.Privmsg(bot.config.Channel, message)
+
+	for _, showtime := range created {
+		bot.notify(Event{Kind: "created", Showtime: showtime})
+	}
+}
+
+// applyTMDBLookup replaces the showtime's title with TMDB's canonical one and
+// records its TMDB id and poster. When no API key is configured or the lookup
+// fails the showtime is left as typed.
+func (bot *command) applyTMDBLookup(showtime *Showtime) {
+	if bot.config.TMDBAPIKey == "" {
+		log.Printf("Skipping TMDB lookup for %q: no tmdb_api_key configured", showtime.Title)
+		return
+	}
+
+	movie, err := lookupTMDB(bot.config.TMDBAPIKey, showtime.Title)
+	if err != nil {
+		log.Printf("TMDB lookup for %q failed: %v", showtime.Title, err)
+		return
+	}
+	if movie == nil {
+		log.Printf("TMDB lookup for %q found no match", showtime.Title)
+		return
+	}
+
+	if movieTitle := bot.sanitizeTitle(movie.Title); movieTitle != "" {
+		showtime.Title = movieTitle
+	}
+	showtime.TMDBID = movie.ID
+	showtime.PosterURL = movie.PosterURL()
+}
+
+// dateFormats are the layouts accepted by -date, always interpreted as UTC
+var dateFormats = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"01-02-2006 15:04:05",
+	"01-02-2006 15:04",
+	"2006/01/02 15:04:05",
+	"2006/01/02 15:04",
+}
+
+// buildDatetime computes a showtime's start from a -date string, an -at time
+// of day, or the individual -year/-month/-day/-hours/-minutes/-seconds flags.
+// Missing date components default to now's date and missing time components
+// to zero. The returned error message is suitable for replying to the user.
+func buildDatetime(args []string, now time.Time) (time.Time, error) {
+	var date, at string
+	var hours, minutes, seconds, month, day, year int
+	var err error
+
+	for _, part := range args {
+		if strings.HasPrefix(part, "-hour=") || strings.HasPrefix(part, "-hours=") {
+			var hourStr string
+			if strings.HasPrefix(part, "-hour=") {
+				hourStr = strings.Trim(strings.TrimPrefix(part, "-hour="), "\"")
+			} else {
+				hourStr = strings.Trim(strings.TrimPrefix(part, "-hours="), "\"")
+			}
+			hours, err = strconv.Atoi(hourStr)
+			if err != nil || hours < 0 || hours > 23 {
+				return time.Time{}, errors.New("Invalid hour value (must be 0-23).")
+			}
+		} else if strings.HasPrefix(part, "-minute=") || strings.HasPrefix(part, "-minutes=") {
+			var minStr string
+			if strings.HasPrefix(part, "-minute=") {
+				minStr = strings.Trim(strings.TrimPrefix(part, "-minute="), "\"")
+			} else {
+				minStr = strings.Trim(strings.TrimPrefix(part, "-minutes="), "\"")
+			}
+			minutes, err = strconv.Atoi(minStr)
+			if err != nil || minutes < 0 || minutes > 59 {
+				return time.Time{}, errors.New("Invalid minute value (must be 0-59).")
+			}
+		} else if strings.HasPrefix(part, "-second=") || strings.HasPrefix(part, "-seconds=") || strings.HasPrefix(part, "-sec=") {
+			var secStr string
+			if strings.HasPrefix(part, "-second=") {
+				secStr = strings.Trim(strings.TrimPrefix(part, "-second="), "\"")
+			} else if strings.HasPrefix(part, "-seconds=") {
 				secStr = strings.Trim(strings.TrimPrefix(part, "-seconds="), "\"")
 			} else {
 				secStr = strings.Trim(strings.TrimPrefix(part, "-sec="), "\"")
 			}
 			seconds, err = strconv.Atoi(secStr)
 			if err != nil || seconds < 0 || seconds > 59 {
-				bot.conn.Privmsg(bot.config.Channel, "Invalid second value (must be 0-59).")
-				return
+				return time.Time{}, errors.New("Invalid second value (must be 0-59).")
 			}
 		} else if strings.HasPrefix(part, "-month=") {
 			month, err = strconv.Atoi(strings.Trim(strings.TrimPrefix(part, "-month="), "\""))
 			if err != nil || month < 1 || month > 12 {
-				bot.conn.Privmsg(bot.config.Channel, "Invalid month value (must be 1-12).")
-				return
+				return time.Time{}, errors.New("Invalid month value (must be 1-12).")
 			}
 		} else if strings.HasPrefix(part, "-day=") {
 			day, err = strconv.Atoi(strings.Trim(strings.TrimPrefix(part, "-day="), "\""))
 			if err != nil || day < 1 || day > 31 {
-				bot.conn.Privmsg(bot.config.Channel, "Invalid day value (must be 1-31).")
-				return
+				return time.Time{}, errors.New("Invalid day value (must be 1-31).")
 			}
 		} else if strings.HasPrefix(part, "-year=") {
 			year, err = strconv.Atoi(strings.Trim(strings.TrimPrefix(part, "-year="), "\""))
 			if err != nil || year < 1900 || year > 2100 {
-				bot.conn.Privmsg(bot.config.Channel, "Invalid year value (must be 1900-2100).")
-				return
+				return time.Time{}, errors.New("Invalid year value (must be 1900-2100).")
 			}
 		} else if strings.HasPrefix(part, "-date=") {
 			date = strings.Trim(strings.TrimPrefix(part, "-date="), "\"")
+		} else if strings.HasPrefix(part, "-at=") {
+			at = strings.Trim(strings.TrimPrefix(part, "-at="), "\"")
 		}
 	}
 
-	// Validate required fields
-	if id == "" || title == "" {
-		bot.conn.Privmsg(bot.config.Channel, "Required: -id=\"id\" -title=\"title\"")
-		return
+	if date != "" {
+		return parseDate(date)
+	}
+	if at != "" {
+		return parseTimeOfDay(at, now)
 	}
 
-	// Check if ID already exists
-	exists, err := bot.showtimeExists(id)
+	// Use current time as base if not all fields specified
+	if year == 0 {
+		year = now.Year()
+	}
+	if month == 0 {
+		month = int(now.Month())
+	}
+	if day == 0 {
+		day = now.Day()
+	}
+
+	// Create date in UTC and validate it's valid (handles leap years, month boundaries, etc.)
+	datetime := time.Date(year, time.Month(month), day, hours, minutes, seconds, 0, time.UTC)
+
+	// Check if the date is valid by comparing with what we intended
+	if datetime.Year() != year || int(datetime.Month()) != month || datetime.Day() != day {
+		return time.Time{}, errors.New("Invalid date (check month/day combination and leap year).")
+	}
+
+	return datetime, nil
+}
+
+// parseTimeOfDay turns an HH:MM time into the next occurrence of it: today if
+// that's still ahead of now, otherwise tomorrow
+func parseTimeOfDay(value string, now time.Time) (time.Time, error) {
+	clock, err := time.Parse("15:04", value)
 	if err != nil {
-		log.Printf("Error checking showtime existence: %v", err)
-		bot.conn.Privmsg(bot.config.Channel, "Error checking showtime existence.")
-		return
+		return time.Time{}, errors.New("Invalid -at time (use HH:MM, e.g. 20:30).")
 	}
-	if exists {
-		bot.conn.Privmsg(bot.config.Channel, fmt.Sprintf("Showtime with ID '%s' already exists.", id))
-		return
+
+	now = now.UTC()
+	datetime := time.Date(now.Year(), now.Month(), now.Day(), clock.Hour(), clock.Minute(), 0, 0, time.UTC)
+	if !datetime.After(now) {
+		datetime = datetime.AddDate(0, 0, 1)
 	}
+	return datetime, nil
+}
 
-	// Create datetime
-	now := time.Now().UTC()
-	var datetime time.Time
+// parseDate parses a full date string in any of dateFormats as UTC
+func parseDate(date string) (time.Time, error) {
+	for _, format := range dateFormats {
+		if datetime, err := time.Parse(format, date); err == nil {
+			return datetime.UTC(), nil
+		}
+	}
+	return time.Time{}, errors.New("Invalid date format. Supported formats: 2006-01-02 15:04:05, 01-02-2006 15:04:05, 2006/01/02 15:04:05")
+}
 
-	if date != "" {
-		// Parse full date string - support multiple formats, always in UTC
-		formats := []string{
-			"2006-01-02 15:04:05",
-			"2006-01-02 15:04",
-			"01-02-2006 15:04:05",
-			"01-02-2006 15:04",
-			"2006/01/02 15:04:05",
-			"2006/01/02 15:04",
+func (bot *command) formatTimeUntil(duration time.Duration, granularity Granularity) string {
+	// Round to nearest second to avoid showing negative durations due to microsecond differences
+	totalSeconds := int(duration.Round(time.Second).Seconds())
+
+	if totalSeconds <= 0 {
+		return "Now"
+	}
+
+	return "In " + strings.Join(bot.durationParts(totalSeconds, granularity), ", ")
+}
+
+func (bot *command) formatTimeSince(duration time.Duration, granularity Granularity) string {
+	// Round to nearest second
+	totalSeconds := int(duration.Round(time.Second).Seconds())
+
+	if totalSeconds <= 0 {
+		return "Just started"
+	}
+
+	return strings.Join(bot.durationParts(totalSeconds, granularity), ", ")
+}
+
+// Granularity controls which units the duration formatters use
+type Granularity int
+
+const (
+	// normalGranularity drops seconds past an hour and minutes past a day
+	normalGranularity Granularity = iota
+	// preciseGranularity always includes every unit down to seconds
+	preciseGranularity
+	// coarseGranularity behaves like normalGranularity but rounds long ranges
+	// to weeks and months
+	coarseGranularity
+)
+
+const (
+	// dayThreshold is the duration from which countdowns are expressed in days and hours
+	dayThreshold = 24 * time.Hour
+	// weekThreshold and monthThreshold start the coarse buckets; a month is
+	// approximated as 30 days
+	weekThreshold  = 7 * dayThreshold
+	monthThreshold = 30 * dayThreshold
+)
+
+// durationParts splits a positive number of seconds into human readable units.
+// Seconds are dropped once the duration reaches an hour, and minutes once it
+// reaches dayThreshold, unless the granularity is precise.
+func (bot *command) durationParts(totalSeconds int, granularity Granularity) []string {
+	precise := granularity == preciseGranularity
+	hours := totalSeconds / 3600
+	minutes := (totalSeconds % 3600) / 60
+	seconds := totalSeconds % 60
+	days := totalSeconds / 86400
+
+	var parts []string
+
+	if granularity == coarseGranularity {
+		switch {
+		case totalSeconds >= int(monthThreshold.Seconds()):
+			parts = append(parts, pluralize(days/30, "month"))
+			if weeks := (days % 30) / 7; weeks > 0 {
+				parts = append(parts, pluralize(weeks, "week"))
+			}
+			return parts
+		case totalSeconds >= int(weekThreshold.Seconds()):
+			parts = append(parts, pluralize(days/7, "week"))
+			if days%7 > 0 {
+				parts = append(parts, pluralize(days%7, "day"))
+			}
+			return parts
 		}
+	}
 
-		var parseErr error
-		for _, format := range formats {
-			datetime, parseErr = time.Parse(format, date)
-			if parseErr == nil {
-				// Convert to UTC if not already
-				datetime = datetime.UTC()
-				break
+	if totalSeconds >= int(dayThreshold.Seconds()) {
+		hours = (totalSeconds % 86400) / 3600
+		parts = append(parts, pluralize(days, "day"))
+		if !precise {
+			if hours > 0 {
+				parts = append(parts, pluralize(hours, "hour"))
 			}
+			return parts
 		}
+	}
+
+	if hours > 0 {
+		parts = append(parts, pluralize(hours, "hour"))
+	}
+
+	if minutes > 0 {
+		parts = append(parts, pluralize(minutes, "minute"))
+	}
+
+	if seconds > 0 && (hours == 0 || precise) { // Under an hour, or always when precise
+		parts = append(parts, pluralize(seconds, "second"))
+	}
+
+	return parts
+}
+
+// pluralize renders a count with its unit, e.g. "1 hour" or "3 hours"
+func pluralize(count int, unit string) string {
+	if count == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", count, unit)
+}
+
+// formatRelativeTime describes t relative to now, e.g. "In 2 hours" or "2 hours ago"
+func (bot *command) formatRelativeTime(t, now time.Time) string {
+	if t.After(now) {
+		return bot.formatTimeUntil(t.Sub(now), coarseGranularity)
+	}
+	if now.Sub(t).Round(time.Second) <= 0 {
+		return "Now"
+	}
+	return bot.formatTimeSince(now.Sub(t), coarseGranularity) + " ago"
+}
+
+// showtimeUsage is the reply for a malformed .showtime command
+const showtimeUsage = "Usage: .showtime -list [-active] [-from=date] [-to=date] [-tag=tag] [-relative | -full | -compact | -detailed] [-grouped] [-format=json] [-dm] | -soonest | -brief | -gaps | -clone-week | -import-ics=\"url or path\" | -create [options] | -info=\"id\" | -delete=\"id\" | -cancel=\"id\" | -uncancel=\"id\" | -reassign=\"id\" -to=\"nick\" | -retz=\"id\" -from=zone -to=zone | -set-runtime=\"id or title\" -runtime=minutes | -shift-by=duration -creator=\"nick\" [-force] | -raw=\"id\" | -whoadded=\"title\" [-like] | -between=\"id\" \"other id\" | -clear"
 
-		if parseErr != nil {
-			bot.conn.Privmsg(bot.config.Channel, "Invalid date format. Supported formats: 2006-01-02 15:04:05, 01-02-2006 15:04:05, 2006/01/02 15:04:05")
+func (bot *command) handleShowtimeCommand(message, nick string) {
+	// Parse the command more carefully to handle quoted arguments
+	args := bot.parseArgs(message)
+	if len(args) < 2 {
+		bot.sender.Privmsg(bot.config.Channel, showtimeUsage)
+		return
+	}
+
+	switch {
+	case args[1] == "-list":
+		opts, err := bot.parseListOptions(args)
+		if err != nil {
+			bot.sender.Privmsg(bot.config.Channel, err.Error())
 			return
 		}
-	} else {
-		// Use current time as base if not all fields specified
-		if year == 0 {
-			year = now.Year()
+		bot.listShowtimes(opts, nick)
+	case args[1] == "-soonest":
+		bot.announceNextShowtime(time.Now().UTC(), coarseGranularity)
+	case args[1] == "-brief":
+		bot.briefShowtimes(time.Now().UTC())
+	case args[1] == "-gaps":
+		bot.longestGap(time.Now().UTC())
+	case args[1] == "-clone-week":
+		bot.cloneWeek(time.Now().UTC(), nick)
+	case hasFlag(args[1:], "-import-ics"):
+		bot.importICS(args, nick)
+	case hasFlag(args[1:], "-reassign"):
+		bot.reassignShowtime(args, nick)
+	case hasFlag(args[1:], "-set-runtime"):
+		bot.setRuntime(args, nick)
+	case hasFlag(args[1:], "-retz"):
+		bot.retzShowtime(args, nick)
+	case hasFlag(args[1:], "-clear"):
+		bot.clearShowtimes(args, nick, time.Now().UTC())
+	case hasFlag(args[1:], "-shift-by"):
+		bot.shiftShowtimes(args, nick, time.Now().UTC())
+	case hasFlag(args[1:], "-cancel"):
+		bot.cancelShowtime(args, nick, true)
+	case hasFlag(args[1:], "-uncancel"):
+		bot.cancelShowtime(args, nick, false)
+	case hasFlag(args[1:], "-delete"):
+		bot.deleteShowtime(args, nick)
+	case hasFlag(args[1:], "-between"):
+		bot.compareShowtimes(args)
+	case hasFlag(args[1:], "-whoadded"):
+		bot.whoAdded(args)
+	case hasFlag(args[1:], "-raw"):
+		bot.rawShowtime(args)
+	case hasFlag(args[1:], "-info"):
+		bot.showtimeInfo(args, bot.config.AuthorizedNicks.Allows(bot.config.Channel, nick))
+	case args[1] == "-create":
+		bot.createShowtime(args, nick)
+	default:
+		bot.sender.Privmsg(bot.config.Channel, showtimeUsage)
+	}
+}
+
+// hasFlag reports whether any argument starts with name
+func hasFlag(args []string, name string) bool {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// flagValue returns the unquoted value of the first name="value" argument
+func flagValue(args []string, name string) string {
+	for _, arg := range args {
+		if strings.HasPrefix(arg, name+"=") {
+			return strings.Trim(strings.TrimPrefix(arg, name+"="), "\"")
 		}
-		if month == 0 {
-			month = int(now.Month())
+	}
+	return ""
+}
+
+// listOptions controls how .showtime -list renders its output
+type listOptions struct {
+	relative bool
+	// full shows the absolute time followed by the relative one
+	full    bool
+	grouped bool
+	format  string
+	filter  ShowtimeFilter
+	// active drops showtimes that have already finished
+	active bool
+	// compact packs "id:Title@HH:MM" entries as many per line as fit
+	compact bool
+	// dm sends the list privately to whoever asked instead of the channel
+	dm bool
+}
+
+// parseListOptions reads the -list flags; the error message is suitable for
+// replying to the user
+func (bot *command) parseListOptions(args []string) (listOptions, error) {
+	opts := listOptions{compact: bot.config.CompactList}
+	var err error
+	for _, part := range args[2:] { // Skip ".showtime" and "-list"
+		if part == "-relative" {
+			opts.relative = true
+		} else if part == "-full" {
+			opts.full = true
+		} else if part == "-grouped" {
+			opts.grouped = true
+		} else if part == "-active" {
+			opts.active = true
+		} else if part == "-compact" || part == "--compact" {
+			opts.compact = true
+		} else if part == "-detailed" {
+			opts.compact = false
+		} else if part == "-dm" {
+			opts.dm = true
+		} else if strings.HasPrefix(part, "-tag=") {
+			opts.filter.Tag = strings.ToLower(strings.TrimSpace(strings.Trim(strings.TrimPrefix(part, "-tag="), "\"")))
+		} else if strings.HasPrefix(part, "-format=") {
+			opts.format = strings.ToLower(strings.Trim(strings.TrimPrefix(part, "-format="), "\""))
+		} else if strings.HasPrefix(part, "-from=") {
+			opts.filter.From, err = parseDateBound(strings.Trim(strings.TrimPrefix(part, "-from="), "\""), false)
+			if err != nil {
+				return opts, err
+			}
+		} else if strings.HasPrefix(part, "-to=") {
+			opts.filter.To, err = parseDateBound(strings.Trim(strings.TrimPrefix(part, "-to="), "\""), true)
+			if err != nil {
+				return opts, err
+			}
 		}
-		if day == 0 {
-			day = now.Day()
+	}
+
+	if !opts.filter.From.IsZero() && !opts.filter.To.IsZero() && opts.filter.From.After(opts.filter.To) {
+		return opts, errors.New("Invalid range: -from must not be after -to.")
+	}
+	return opts, nil
+}
+
+// dateOnlyFormats are the -date layouts without a time of day, accepted for
+// list range bounds
+var dateOnlyFormats = []string{
+	"2006-01-02",
+	"01-02-2006",
+	"2006/01/02",
+}
+
+// parseDateBound parses a -from/-to value using the create command's date
+// formats, also accepting a bare date. A bare date means the start of that
+// day, or its last second when endOfDay is set so -to includes the whole day.
+func parseDateBound(value string, endOfDay bool) (time.Time, error) {
+	for _, format := range dateOnlyFormats {
+		if day, err := time.Parse(format, value); err == nil {
+			if endOfDay {
+				return day.Add(24*time.Hour - time.Second), nil
+			}
+			return day, nil
 		}
+	}
+	return parseDate(value)
+}
 
-		// Create date in UTC and validate it's valid (handles leap years, month boundaries, etc.)
-		datetime = time.Date(year, time.Month(month), day, hours, minutes, seconds, 0, time.UTC)
+// listShowtimes replies with the schedule. nick is who asked; when they created
+// any of the listed showtimes a hint on deleting them follows the list. With
+// opts.dm the list goes to nick privately and the channel sees nothing.
+// Otherwise a list without a date or tag filter stops after max_list_entries
+// with a trailer saying how many were left out.
+func (bot *command) listShowtimes(opts listOptions, nick string) {
+	showtimes, err := bot.store.List(opts.filter)
+	if err != nil {
+		log.Printf("Error getting showtimes: %v", err)
+		bot.replyError(err, "Error retrieving showtimes.")
+		return
+	}
 
-		// Check if the date is valid by comparing with what we intended
-		if datetime.Year() != year || int(datetime.Month()) != month || datetime.Day() != day {
-			bot.conn.Privmsg(bot.config.Channel, "Invalid date (check month/day combination and leap year).")
-			return
+	target := bot.config.Channel
+	if opts.dm && nick != "" {
+		target = nick
+	}
+
+	now := time.Now().UTC()
+	if opts.active {
+		showtimes = bot.activeShowtimes(showtimes, now)
+	}
+
+	limit := bot.config.MaxListEntries
+	if limit <= 0 {
+		limit = defaultMaxListEntries
+	}
+	if !opts.dm && opts.filter == (ShowtimeFilter{}) && len(showtimes) > limit {
+		more := len(showtimes) - limit
+		showtimes = showtimes[:limit]
+		// Deferred so it follows whichever layout sends the list. JSON lists
+		// get it as an object so every message still parses.
+		trailer := fmt.Sprintf("…and %d more. Narrow the list with -from, -to or -tag, or add -dm to get all of it privately.", more)
+		if opts.format == "json" {
+			trailer = fmt.Sprintf(`{"more":%d}`, more)
 		}
+		defer bot.sender.Privmsg(target, trailer)
 	}
 
-	// Create and store the showtime in database
-	showtime := Showtime{
-		ID:        id,
-		Title:     title,
-		DateTime:  datetime,
-		CreatedBy: nick,
-		CreatedAt: now,
+	if opts.format == "json" {
+		bot.listShowtimesJSON(target, showtimes)
+		return
 	}
 
-	if err := bot.insertShowtime(showtime); err != nil {
-		log.Printf("Error inserting showtime: %v", err)
-		bot.conn.Privmsg(bot.config.Channel, "Error creating showtime.")
+	if len(showtimes) == 0 {
+		bot.sender.Privmsg(target, "No showtimes scheduled.")
 		return
 	}
 
-	timeStr := datetime.Format("2006-01-02 15:04:05 MST")
-	bot.conn.Privmsg(bot.config.Channel,
-		fmt.Sprintf("Created showtime: [%s] %s - %s", id, title, timeStr))
+	if opts.compact {
+		bot.listShowtimesCompact(target, showtimes, now)
+		return
+	}
 
-	// Debug logging
-	//log.Printf("Created showtime [%s]: %s at %s (created by %s)", id, title, timeStr, nick)
+	bot.sender.Privmsg(target, "Scheduled showtimes:")
+	var lastDay string
+	var rows []string
+	ownsAny := false
+	for _, showtime := range showtimes {
+		ownsAny = ownsAny || showtime.CreatedBy == nick
+		// Showtimes arrive ordered by datetime, so a new day starts a new group
+		if day := showtime.DateTime.In(bot.displayLocation()).Format("2006-01-02"); opts.grouped && day != lastDay {
+			bot.sendRowsTo(target, rows)
+			rows = nil
+			bot.sender.Privmsg(target, fmt.Sprintf("— %s —", day))
+			lastDay = day
+		}
+		timeStr := bot.formatTime(showtime.DateTime)
+		if opts.full {
+			relative := bot.formatRelativeTime(showtime.DateTime, now)
+			timeStr += " (" + strings.ToLower(relative[:1]) + relative[1:] + ")"
+		} else if opts.relative {
+			timeStr = bot.formatRelativeTime(showtime.DateTime, now)
+		}
+		rows = append(rows, fmt.Sprintf("%s [%s] %s - %s (by %s)",
+			bot.statusIndicator(showtime, now), showtime.ID, markedTitle(showtime), timeStr, showtime.CreatedBy))
+	}
+	bot.sendRowsTo(target, rows)
+
+	if ownsAny && nick != "" {
+		bot.sender.Privmsg(target, "To delete: .showtime -delete=\"<id>\" using the id in [brackets]")
+	}
 }
 
-func (bot *CinemaBot) showtimeExists(id string) (bool, error) {
-	query := "SELECT COUNT(*) FROM showtimes WHERE id = ?"
-	var count int
-	err := bot.db.QueryRow(query, id).Scan(&count)
-	if err != nil {
-		return false, err
+// listShowtimesCompact sends showtimes to target as "id:Title@HH:MM" entries
+// packed as many to a line as fit. The date is added ("id:Title@06-14 20:00")
+// to the first entry of each day other than today, in the display timezone.
+func (bot *command) listShowtimesCompact(target string, showtimes []Showtime, now time.Time) {
+	location := bot.displayLocation()
+	lastDay := now.In(location).Format("2006-01-02")
+	items := make([]string, 0, len(showtimes))
+	for _, showtime := range showtimes {
+		local := showtime.DateTime.In(location)
+		layout := "15:04"
+		if day := local.Format("2006-01-02"); day != lastDay {
+			layout = "01-02 15:04"
+			lastDay = day
+		}
+		items = append(items, fmt.Sprintf("%s:%s@%s", showtime.ID, markedTitle(showtime), local.Format(layout)))
+	}
+
+	for _, chunk := range chunkItems(items, " | ", messageBytes(target)) {
+		bot.sender.Privmsg(target, chunk)
 	}
-	return count > 0, nil
 }
 
-func (bot *CinemaBot) insertShowtime(showtime Showtime) error {
-	query := `
-		INSERT INTO showtimes (id, title, datetime, created_by, created_at) 
-		VALUES (?, ?, ?, ?, ?)
-	`
-	_, err := bot.db.Exec(query,
-		showtime.ID,
-		showtime.Title,
-		showtime.DateTime.Format(time.RFC3339),
-		showtime.CreatedBy,
-		showtime.CreatedAt.Format(time.RFC3339))
-	return err
+// activeShowtimes keeps the showtimes that are playing or yet to start, using
+// each one's runtime when known and the current window otherwise
+func (bot *command) activeShowtimes(showtimes []Showtime, now time.Time) []Showtime {
+	var active []Showtime
+	for _, showtime := range showtimes {
+		if showtime.DateTime.Add(bot.playingDuration(showtime)).After(now) {
+			active = append(active, showtime)
+		}
+	}
+	return active
 }
 
-func (bot *CinemaBot) formatTimeUntil(duration time.Duration) string {
-	// Round to nearest second to avoid showing negative durations due to microsecond differences
-	totalSeconds := int(duration.Round(time.Second).Seconds())
+// sendRows sends list rows to the channel, merging up to rows_per_message of
+// them into each message without exceeding the channel's message budget
+func (bot *command) sendRows(rows []string) {
+	bot.sendRowsTo(bot.config.Channel, rows)
+}
 
-	if totalSeconds <= 0 {
-		return "Now"
+// sendRowsTo is sendRows for any target, such as a nick for a private list
+func (bot *command) sendRowsTo(target string, rows []string) {
+	perMessage := bot.config.RowsPerMessage
+	if perMessage < 1 {
+		perMessage = 1
 	}
 
-	if totalSeconds < 60 {
-		if totalSeconds == 1 {
-			return "In 1 second"
+	for len(rows) > 0 {
+		n := perMessage
+		if n > len(rows) {
+			n = len(rows)
+		}
+		for _, chunk := range chunkItems(rows[:n], " | ", messageBytes(target)) {
+			bot.sender.Privmsg(target, chunk)
 		}
-		return fmt.Sprintf("In %d seconds", totalSeconds)
+		rows = rows[n:]
 	}
+}
 
-	hours := totalSeconds / 3600
-	minutes := (totalSeconds % 3600) / 60
-	seconds := totalSeconds % 60
+// markedTitle is the showtime's title for lists and -info, flagged when the
+// screening has been cancelled
+func markedTitle(showtime Showtime) string {
+	if showtime.Cancelled {
+		return "[CANCELLED] " + showtime.Title
+	}
+	return showtime.Title
+}
 
-	var parts []string
+// statusIndicator marks a showtime as past, live or upcoming. A movie counts
+// as live for its playingDuration after its start.
+func (bot *command) statusIndicator(showtime Showtime, now time.Time) string {
+	switch {
+	case showtime.DateTime.After(now):
+		if bot.config.PlainIndicators {
+			return "[soon]"
+		}
+		return "⏭"
+	case now.Sub(showtime.DateTime) < bot.playingDuration(showtime):
+		if bot.config.PlainIndicators {
+			return "[live]"
+		}
+		return "▶"
+	default:
+		if bot.config.PlainIndicators {
+			return "[past]"
+		}
+		return "⏮"
+	}
+}
 
-	if hours > 0 {
-		if hours == 1 {
-			parts = append(parts, "1 hour")
-		} else {
-			parts = append(parts, fmt.Sprintf("%d hours", hours))
+// briefShowtimes replies with upcoming titles on as few lines as possible, e.g.
+// "Soon: A (2h), B (tomorrow), C (Fri)"
+func (bot *command) briefShowtimes(now time.Time) {
+	showtimes, err := bot.store.Upcoming(now, 0)
+	if err != nil {
+		log.Printf("Error getting upcoming showtimes: %v", err)
+		bot.replyError(err, "Error retrieving showtimes.")
+		return
+	}
+
+	if len(showtimes) == 0 {
+		bot.sender.Privmsg(bot.config.Channel, "No upcoming showtimes.")
+		return
+	}
+
+	items := make([]string, 0, len(showtimes))
+	for _, showtime := range showtimes {
+		items = append(items, fmt.Sprintf("%s (%s)", showtime.Title, bot.briefWhen(showtime.DateTime, now)))
+	}
+
+	const prefix = "Soon: "
+	for i, chunk := range chunkItems(items, ", ", bot.channelBytes()-len(prefix)) {
+		if i == 0 {
+			chunk = prefix + chunk
 		}
+		bot.sender.Privmsg(bot.config.Channel, chunk)
 	}
+}
 
-	if minutes > 0 {
-		if minutes == 1 {
-			parts = append(parts, "1 minute")
-		} else {
-			parts = append(parts, fmt.Sprintf("%d minutes", minutes))
+// briefWhen is a short label for when t happens: "45m" or "2h" when close,
+// otherwise "tomorrow", a weekday within the next week, or the date
+func (bot *command) briefWhen(t, now time.Time) string {
+	until := t.Sub(now)
+	local := t.In(bot.displayLocation())
+	localNow := now.In(bot.displayLocation())
+	today := time.Date(localNow.Year(), localNow.Month(), localNow.Day(), 0, 0, 0, 0, bot.displayLocation())
+	days := int(time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, bot.displayLocation()).Sub(today).Hours() / 24)
+
+	switch {
+	case until < time.Hour:
+		return fmt.Sprintf("%dm", int(until.Round(time.Minute).Minutes()))
+	case days == 0 || until < 12*time.Hour:
+		return fmt.Sprintf("%dh", int(until.Round(time.Hour).Hours()))
+	case days == 1:
+		return "tomorrow"
+	case days < 7:
+		return local.Format("Mon")
+	default:
+		return local.Format("Jan 2")
+	}
+}
+
+// listShowtimesJSON sends target compact JSON arrays of showtimes, split so
+// every message stays within its message budget and is valid JSON on its own
+func (bot *command) listShowtimesJSON(target string, showtimes []Showtime) {
+	items := make([]string, 0, len(showtimes))
+	for _, showtime := range showtimes {
+		data, err := json.Marshal(struct {
+			ID        string    `json:"id"`
+			Title     string    `json:"title"`
+			DateTime  time.Time `json:"datetime"`
+			Cancelled bool      `json:"cancelled,omitempty"`
+		}{showtime.ID, showtime.Title, showtime.DateTime, showtime.Cancelled})
+		if err != nil {
+			log.Printf("Error encoding showtime %s: %v", showtime.ID, err)
+			continue
 		}
+		items = append(items, string(data))
 	}
 
-	if seconds > 0 && hours == 0 { // Only show seconds if less than an hour
-		if seconds == 1 {
-			parts = append(parts, "1 second")
-		} else {
-			parts = append(parts, fmt.Sprintf("%d seconds", seconds))
-		}
+	if len(items) == 0 {
+		bot.sender.Privmsg(target, "[]")
+		return
+	}
+
+	for _, chunk := range chunkItems(items, ",", messageBytes(target)-2) {
+		bot.sender.Privmsg(target, "["+chunk+"]")
+	}
+}
+
+// maxMessageBytes is the payload budget for a single PRIVMSG, leaving room in
+// the 512 byte IRC line for the command, target and relayed source prefix
+const maxMessageBytes = 400
+
+// maxSourcePrefixBytes is room left for the ":nick!user@host " prefix the
+// server adds when relaying our messages
+const maxSourcePrefixBytes = 80
+
+// messageBytes is the payload budget for a PRIVMSG to target: maxMessageBytes,
+// or less when target is long enough that the full relayed line would pass
+// 512 bytes
+func messageBytes(target string) int {
+	budget := 512 - maxSourcePrefixBytes - len("PRIVMSG "+target+" :\r\n")
+	if budget > maxMessageBytes {
+		return maxMessageBytes
 	}
+	return budget
+}
 
-	return "In " + strings.Join(parts, ", ")
+// channelBytes is the payload budget for messages to the bot's channel
+func (bot *command) channelBytes() int {
+	return messageBytes(bot.config.Channel)
 }
 
-func (bot *CinemaBot) formatTimeSince(duration time.Duration) string {
-	// Round to nearest second
-	totalSeconds := int(duration.Round(time.Second).Seconds())
+// splitSender is the last line of defence against replies the server would
+// truncate: messages over messageBytes are split between words, so nothing
+// depends on every reply path having chunked correctly
+type splitSender struct {
+	sender Sender
+}
 
-	if totalSeconds <= 0 {
-		return "Just started"
+func (s splitSender) Privmsg(target, message string) {
+	limit := messageBytes(target)
+	if len(message) <= limit {
+		s.sender.Privmsg(target, message)
+		return
 	}
-
-	if totalSeconds < 60 {
-		if totalSeconds == 1 {
-			return "1 second"
-		}
-		return fmt.Sprintf("%d seconds", totalSeconds)
+	for _, chunk := range chunkItems(strings.Split(message, " "), " ", limit) {
+		s.sender.Privmsg(target, chunk)
 	}
+}
 
-	hours := totalSeconds / 3600
-	minutes := (totalSeconds % 3600) / 60
-	seconds := totalSeconds % 60
-
-	var parts []string
+// chunkItems joins items with sep into chunks of at most limit bytes. Items are
+// never split unless a single item exceeds limit, in which case it is cut on
+// rune boundaries.
+func chunkItems(items []string, sep string, limit int) []string {
+	var chunks []string
+	var current strings.Builder
 
-	if hours > 0 {
-		if hours == 1 {
-			parts = append(parts, "1 hour")
-		} else {
-			parts = append(parts, fmt.Sprintf("%d hours", hours))
+	for _, item := range items {
+		for len(item) > limit {
+			if current.Len() > 0 {
+				chunks = append(chunks, current.String())
+				current.Reset()
+			}
+			cut := limit
+			for cut > 0 && !utf8.RuneStart(item[cut]) {
+				cut--
+			}
+			chunks = append(chunks, item[:cut])
+			item = item[cut:]
 		}
-	}
 
-	if minutes > 0 {
-		if minutes == 1 {
-			parts = append(parts, "1 minute")
-		} else {
-			parts = append(parts, fmt.Sprintf("%d minutes", minutes))
+		if current.Len() > 0 && current.Len()+len(sep)+len(item) > limit {
+			chunks = append(chunks, current.String())
+			current.Reset()
+		}
+		if current.Len() > 0 {
+			current.WriteString(sep)
 		}
+		current.WriteString(item)
 	}
 
-	if seconds > 0 && hours == 0 { // Only show seconds if less than an hour
-		if seconds == 1 {
-			parts = append(parts, "1 second")
-		} else {
-			parts = append(parts, fmt.Sprintf("%d seconds", seconds))
-		}
+	if current.Len() > 0 {
+		chunks = append(chunks, current.String())
 	}
 
-	return strings.Join(parts, ", ")
+	return chunks
 }
 
-func (bot *CinemaBot) handleShowtimeCommand(message, nick string) {
-	// Parse the command more carefully to handle quoted arguments
-	args := bot.parseArgs(message)
-	if len(args) < 2 {
-		bot.conn.Privmsg(bot.config.Channel, "Usage: .showtime -list | -create [options] | -delete=\"id\"")
-		return
-	}
+// wrapIndent starts every line of wrapped text after the first
+const wrapIndent = "  ↳ "
 
-	// Check if any argument starts with -delete
-	var hasDelete bool
-	for _, arg := range args[1:] {
-		if strings.HasPrefix(arg, "-delete") {
-			hasDelete = true
-			break
+// sendWrapped sends text to the channel word-wrapped into as many messages as
+// it takes to stay within the channel's message budget, marking continuation lines with
+// wrapIndent. Runs of whitespace collapse to single spaces.
+func (bot *command) sendWrapped(text string) {
+	for i, line := range chunkItems(strings.Fields(text), " ", bot.channelBytes()-len(wrapIndent)) {
+		if i > 0 {
+			line = wrapIndent + line
 		}
+		bot.sender.Privmsg(bot.config.Channel, line)
 	}
+}
 
-	switch {
-	case args[1] == "-list":
-		bot.listShowtimes()
-	case hasDelete:
-		bot.deleteShowtime(args, nick)
-	case args[1] == "-create":
-		bot.createShowtime(args, nick)
-	default:
-		bot.conn.Privmsg(bot.config.Channel, "Usage: .showtime -list | -create [options] | -delete=\"id\"")
-	}
+// sanitizeTitle strips IRC formatting, collapses runs of whitespace into single
+// spaces and trims the ends
+func (bot *CinemaBot) sanitizeTitle(title string) string {
+	return strings.Join(strings.Fields(bot.stripControlCodes(title)), " ")
 }
 
-func (bot *CinemaBot) listShowtimes() {
-	showtimes, err := bot.getAllShowtimes()
-	if err != nil {
-		log.Printf("Error getting showtimes: %v", err)
-		bot.conn.Privmsg(bot.config.Channel, "Error retrieving showtimes.")
-		return
+// stripControlCodes removes mIRC color sequences and other control characters,
+// leaving whitespace and printable Unicode intact
+func (bot *CinemaBot) stripControlCodes(text string) string {
+	var result strings.Builder
+	runes := []rune(text)
+
+	for i := 0; i < len(runes); i++ {
+		char := runes[i]
+		switch {
+		case char == '\x03':
+			// Color code: \x03 followed by up to two foreground digits and an
+			// optional comma with up to two background digits
+			i += skipDigits(runes[i+1:], 2)
+			if i+2 < len(runes) && runes[i+1] == ',' && unicode.IsDigit(runes[i+2]) {
+				i++
+				i += skipDigits(runes[i+1:], 2)
+			}
+		case unicode.IsSpace(char):
+			result.WriteRune(char)
+		case unicode.IsControl(char):
+			// Drop bold, italic, underline, reset and any other control bytes
+		default:
+			result.WriteRune(char)
+		}
 	}
 
-	if len(showtimes) == 0 {
-		bot.conn.Privmsg(bot.config.Channel, "No showtimes scheduled.")
-		return
-	}
+	return result.String()
+}
 
-	bot.conn.Privmsg(bot.config.Channel, "Scheduled showtimes:")
-	for _, showtime := range showtimes {
-		// Display time in UTC
-		timeStr := showtime.DateTime.Format("2006-01-02 15:04:05 MST")
-		msg := fmt.Sprintf("[%s] %s - %s (by %s)",
-			showtime.ID, showtime.Title, timeStr, showtime.CreatedBy)
-		bot.conn.Privmsg(bot.config.Channel, msg)
+// skipDigits returns how many leading ASCII digits (at most max) runes begins with
+func skipDigits(runes []rune, max int) int {
+	n := 0
+	for n < max && n < len(runes) && runes[n] >= '0' && runes[n] <= '9' {
+		n++
 	}
+	return n
 }
 
-func (bot *CinemaBot) getAllShowtimes() ([]Showtime, error) {
-	query := `
-		SELECT id, title, datetime, created_by, created_at 
-		FROM showtimes 
-		ORDER BY datetime ASC
-	`
+// handleUptimeCommand reports how long the process and the current
+// connection have been up, and where the bot is connected
+func (bot *command) handleUptimeCommand(now time.Time) {
+	bot.mu.RLock()
+	connectedAt := bot.connectedAt
+	bot.mu.RUnlock()
 
-	rows, err := bot.db.Query(query)
-	if err != nil {
-		return nil, err
+	message := fmt.Sprintf("Up %s", bot.formatUptime(now.Sub(bot.startedAt)))
+	if connectedAt.IsZero() {
+		message += " | not connected yet"
+	} else {
+		message += fmt.Sprintf(" | connected for %s to %s in %s",
+			bot.formatUptime(now.Sub(connectedAt)), bot.config.Server, bot.config.Channel)
 	}
-	defer rows.Close()
+	bot.sender.Privmsg(bot.config.Channel, message)
+}
 
-	var showtimes []Showtime
-	for rows.Next() {
-		var showtime Showtime
-		var datetimeStr, createdAtStr string
+// formatUptime renders an elapsed duration such as "2 days, 3 hours"
+func (bot *command) formatUptime(duration time.Duration) string {
+	totalSeconds := int(duration.Round(time.Second).Seconds())
+	if totalSeconds <= 0 {
+		return pluralize(0, "second")
+	}
+	return strings.Join(bot.durationParts(totalSeconds, coarseGranularity), ", ")
+}
 
-		err := rows.Scan(&showtime.ID, &showtime.Title, &datetimeStr, &showtime.CreatedBy, &createdAtStr)
-		if err != nil {
-			return nil, err
-		}
+// handleDebugCommand runs troubleshooting helpers for admins. "parse" echoes
+// how parseArgs tokenizes the rest of the line, to untangle quoting problems;
+// "irc on|off" toggles the IRC library's raw traffic and callback logging.
+func (bot *command) handleDebugCommand(message string) {
+	rest := strings.TrimSpace(strings.TrimPrefix(message, ".debug"))
+	if strings.HasPrefix(rest, "irc") {
+		bot.setIRCDebug(strings.TrimSpace(strings.TrimPrefix(rest, "irc")))
+		return
+	}
+	if rest != "parse" && !strings.HasPrefix(rest, "parse ") {
+		bot.sender.Privmsg(bot.config.Channel, debugUsage)
+		return
+	}
 
-		showtime.DateTime, err = time.Parse(time.RFC3339, datetimeStr)
-		if err != nil {
-			return nil, err
-		}
+	args := bot.parseArgs(strings.TrimPrefix(rest, "parse"))
+	tokens := make([]string, len(args))
+	for i, arg := range args {
+		tokens[i] = strconv.Quote(arg)
+	}
 
-		showtime.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
-		if err != nil {
-			return nil, err
-		}
+	if len(tokens) == 0 {
+		bot.sender.Privmsg(bot.config.Channel, "0 tokens")
+		return
+	}
+	prefix := fmt.Sprintf("%s: ", pluralize(len(tokens), "token"))
+	for _, chunk := range chunkItems(tokens, " ", bot.channelBytes()-len(prefix)) {
+		bot.sender.Privmsg(bot.config.Channel, prefix+chunk)
+	}
+}
 
-		showtimes = append(showtimes, showtime)
+const debugUsage = "Usage: .debug parse <text> | .debug irc on|off"
+
+// setIRCDebug switches the library's raw traffic and callback logging to
+// state ("on" or "off") and reports the result
+func (bot *command) setIRCDebug(state string) {
+	var enabled bool
+	switch state {
+	case "on":
+		enabled = true
+	case "off":
+		enabled = false
+	default:
+		bot.sender.Privmsg(bot.config.Channel, debugUsage)
+		return
 	}
 
-	return showtimes, rows.Err()
+	// Under the connection's own lock rather than mu
+	bot.conn.Lock()
+	bot.conn.Debug = enabled
+	bot.conn.VerboseCallbackHandler = enabled
+	bot.conn.Unlock()
+	log.Printf("IRC debug logging turned %s", state)
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("IRC debug logging is now %s.", state))
 }
 
 // parseArgs parses command arguments, handling quoted strings properly
@@ -771,86 +2839,392 @@ func (bot *CinemaBot) parseArgs(message string) []string {
 	return args
 }
 
-func (bot *CinemaBot) deleteShowtime(args []string, nick string) {
-	var id string
+// showtimeInfo replies with a showtime's details; the admin note is included
+// only when showNote is set
+func (bot *command) showtimeInfo(args []string, showNote bool) {
+	id := flagValue(args, "-info")
+	if id == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -info=\"id\"")
+		return
+	}
 
-	// Parse -delete="id" format
-	for _, part := range args {
-		if strings.HasPrefix(part, "-delete=") {
-			id = strings.Trim(strings.TrimPrefix(part, "-delete="), "\"")
-			break
+	showtime, err := bot.store.GetByID(id)
+	if err != nil {
+		log.Printf("Error getting showtime: %v", err)
+		bot.replyError(err, "Error retrieving showtime.")
+		return
+	}
+
+	if showtime == nil {
+		bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(id))
+		return
+	}
+
+	timeStr := bot.formatTime(showtime.DateTime)
+	details := []string{fmt.Sprintf("[%s] %s - %s (by %s)", showtime.ID, markedTitle(*showtime), timeStr, showtime.CreatedBy)}
+
+	if info := bot.movieInfo(showtime.Title); info != nil {
+		if info.IMDbRating != "" {
+			details = append(details, fmt.Sprintf("IMDb %s/10", info.IMDbRating))
+		}
+		if info.Runtime != "" {
+			details = append(details, info.Runtime)
+		}
+	}
+	if len(showtime.Tags) > 0 {
+		details = append(details, "Tags: "+strings.Join(showtime.Tags, ", "))
+	}
+	if showtime.PosterURL != "" {
+		details = append(details, "Poster: "+showtime.PosterURL)
+	}
+	if showNote && showtime.AdminNote != "" {
+		details = append(details, "Note: "+showtime.AdminNote)
+	}
+
+	bot.sendWrapped(strings.Join(details, " | "))
+}
+
+// whoAdded lists who created each showtime with a title and when, for
+// sorting out duplicates; -like matches titles containing the text instead
+func (bot *command) whoAdded(args []string) {
+	title := flagValue(args, "-whoadded")
+	if title == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -whoadded=\"title\" [-like]")
+		return
+	}
+
+	showtimes, err := bot.store.ByTitle(title, hasFlag(args[1:], "-like"))
+	if err != nil {
+		log.Printf("Error getting showtimes by title: %v", err)
+		bot.replyError(err, "Error retrieving showtimes.")
+		return
+	}
+	if len(showtimes) == 0 {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("No showtimes titled '%s'.", title))
+		return
+	}
+
+	rows := make([]string, 0, len(showtimes))
+	for _, showtime := range showtimes {
+		rows = append(rows, fmt.Sprintf("[%s] %s added by %s on %s", showtime.ID, showtime.Title,
+			showtime.CreatedBy, bot.formatTime(showtime.CreatedAt)))
+	}
+	bot.sendRows(rows)
+}
+
+// rawShowtime prints a showtime's datetime exactly as stored next to how it
+// renders in UTC and the display timezone, to tell a wrongly entered time from
+// a display problem
+func (bot *command) rawShowtime(args []string) {
+	id := flagValue(args, "-raw")
+	if id == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -raw=\"id\"")
+		return
+	}
+
+	raw, err := bot.store.RawDateTime(id)
+	if err != nil {
+		log.Printf("Error getting stored datetime: %v", err)
+		bot.replyError(err, "Error retrieving showtime.")
+		return
+	}
+	if raw == "" {
+		bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(id))
+		return
+	}
+
+	details := []string{fmt.Sprintf("[%s] stored %q", id, raw)}
+	if parsed, err := time.Parse(time.RFC3339, raw); err != nil {
+		details = append(details, "not valid RFC3339")
+	} else {
+		layout := bot.timeFormat()
+		details = append(details, "UTC: "+parsed.UTC().Format(layout))
+		if location := bot.displayLocation(); location != time.UTC {
+			details = append(details, fmt.Sprintf("%s: %s", location, parsed.In(location).Format(layout)))
 		}
 	}
+	bot.sender.Privmsg(bot.config.Channel, strings.Join(details, " | "))
+}
+
+// maxIDSuggestions caps how many similar ids a not-found reply lists
+const maxIDSuggestions = 5
+
+// notFoundMessage reports that id doesn't exist, suggesting similar ids when
+// there are any
+func (bot *command) notFoundMessage(id string) string {
+	message := fmt.Sprintf("Showtime with ID '%s' not found.", id)
+
+	similar, err := bot.store.SimilarIDs(id, maxIDSuggestions)
+	if err != nil {
+		log.Printf("Error finding ids similar to %q: %v", id, err)
+		return message
+	}
+	switch len(similar) {
+	case 0:
+		return message
+	case 1:
+		return fmt.Sprintf("%s Did you mean '%s'?", message, similar[0])
+	default:
+		return fmt.Sprintf("%s Did you mean one of: %s?", message, strings.Join(similar, ", "))
+	}
+}
+
+func (bot *command) deleteShowtime(args []string, nick string) {
+	// Parse -delete="id" format
+	id := flagValue(args, "-delete")
 
 	if id == "" {
-		bot.conn.Privmsg(bot.config.Channel, "Usage: .showtime -delete=\"id\"")
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -delete=\"id\"")
 		return
 	}
 
-	showtime, err := bot.getShowtimeByID(id)
+	showtime, err := bot.store.GetByID(id)
 	if err != nil {
 		log.Printf("Error getting showtime: %v", err)
-		bot.conn.Privmsg(bot.config.Channel, "Error retrieving showtime.")
+		bot.replyError(err, "Error retrieving showtime.")
 		return
 	}
 
 	if showtime == nil {
-		bot.conn.Privmsg(bot.config.Channel, fmt.Sprintf("Showtime with ID '%s' not found.", id))
+		bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(id))
 		return
 	}
 
 	// Only allow deletion by creator
 	if showtime.CreatedBy != nick {
-		bot.conn.Privmsg(bot.config.Channel, "You can only delete showtimes you created.")
+		bot.sender.Privmsg(bot.config.Channel, "You can only delete showtimes you created.")
 		return
 	}
 
-	if err := bot.deleteShowtimeByID(id); err != nil {
+	if err := bot.store.Delete(id); err != nil {
 		log.Printf("Error deleting showtime: %v", err)
-		bot.conn.Privmsg(bot.config.Channel, "Error deleting showtime.")
+		bot.replyError(err, "Error deleting showtime.")
 		return
 	}
 
-	bot.conn.Privmsg(bot.config.Channel, fmt.Sprintf("Deleted showtime: %s", id))
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Deleted showtime: %s", id))
+	bot.notify(Event{Kind: "deleted", Showtime: *showtime})
 }
 
-func (bot *CinemaBot) getShowtimeByID(id string) (*Showtime, error) {
-	query := `
-		SELECT id, title, datetime, created_by, created_at 
-		FROM showtimes 
-		WHERE id = ?
-	`
+// cancelShowtime marks the showtime named by -cancel="id" as cancelled, or
+// restores the one named by -uncancel="id" when cancel is false. Cancelled
+// showtimes keep their history and stay in lists, but nothing announces them.
+func (bot *command) cancelShowtime(args []string, nick string, cancel bool) {
+	flag, action := "-uncancel", "uncancel"
+	if cancel {
+		flag, action = "-cancel", "cancel"
+	}
+	id := flagValue(args, flag)
+	if id == "" {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Usage: .showtime %s=\"id\"", flag))
+		return
+	}
+
+	showtime, err := bot.store.GetByID(id)
+	if err != nil {
+		log.Printf("Error getting showtime: %v", err)
+		bot.replyError(err, "Error retrieving showtime.")
+		return
+	}
+	if showtime == nil {
+		bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(id))
+		return
+	}
+
+	if showtime.CreatedBy != nick && !bot.config.AuthorizedNicks.Allows(bot.config.Channel, nick) {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("You can only %s showtimes you created.", action))
+		return
+	}
+	if showtime.Cancelled == cancel {
+		state := "isn't cancelled"
+		if cancel {
+			state = "is already cancelled"
+		}
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("[%s] %s %s.", id, showtime.Title, state))
+		return
+	}
 
-	row := bot.db.QueryRow(query, id)
+	showtime.Cancelled = cancel
+	if err := bot.store.Update(*showtime); err != nil {
+		log.Printf("Error updating showtime: %v", err)
+		bot.replyError(err, "Error updating showtime.")
+		return
+	}
 
-	var showtime Showtime
-	var datetimeStr, createdAtStr string
+	bot.audit(nick, action, id, "")
+	if cancel {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Cancelled [%s] %s - %s. It stays in the list; .showtime -uncancel=\"%s\" brings it back.",
+			id, showtime.Title, bot.formatTime(showtime.DateTime), id))
+		bot.notify(Event{Kind: "cancelled", Showtime: *showtime})
+	} else {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Restored [%s] %s - %s.", id, showtime.Title, bot.formatTime(showtime.DateTime)))
+		bot.notify(Event{Kind: "uncancelled", Showtime: *showtime})
+	}
+}
 
-	err := row.Scan(&showtime.ID, &showtime.Title, &datetimeStr, &showtime.CreatedBy, &createdAtStr)
-	if err == sql.ErrNoRows {
-		return nil, nil
+// reassignShowtime hands a showtime over to another nick so they can manage
+// it, e.g. when its organizer has left
+func (bot *command) reassignShowtime(args []string, nick string) {
+	id := flagValue(args, "-reassign")
+	newOwner := flagValue(args, "-to")
+	if id == "" || newOwner == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -reassign=\"id\" -to=\"nick\"")
+		return
 	}
+
+	showtime, err := bot.store.GetByID(id)
 	if err != nil {
-		return nil, err
+		log.Printf("Error getting showtime: %v", err)
+		bot.replyError(err, "Error retrieving showtime.")
+		return
+	}
+	if showtime == nil {
+		bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(id))
+		return
+	}
+
+	previousOwner := showtime.CreatedBy
+	showtime.CreatedBy = newOwner
+	if err := bot.store.Update(*showtime); err != nil {
+		log.Printf("Error reassigning showtime: %v", err)
+		bot.replyError(err, "Error reassigning showtime.")
+		return
+	}
+
+	bot.audit(nick, "reassign", id, fmt.Sprintf("created_by %s -> %s", previousOwner, newOwner))
+	bot.sender.Privmsg(bot.config.Channel,
+		fmt.Sprintf("Reassigned [%s] %s from %s to %s.", id, showtime.Title, previousOwner, newOwner))
+}
+
+// setRuntime records a runtime in minutes on the showtime with the given id or,
+// failing that, on every showtime with that title
+func (bot *command) setRuntime(args []string, nick string) {
+	target := flagValue(args, "-set-runtime")
+	minutes, err := strconv.Atoi(flagValue(args, "-runtime"))
+	if target == "" || err != nil || minutes < 0 {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -set-runtime=\"id or title\" -runtime=minutes")
+		return
 	}
 
-	showtime.DateTime, err = time.Parse(time.RFC3339, datetimeStr)
+	changed, err := bot.store.SetRuntime(target, minutes)
 	if err != nil {
-		return nil, err
+		log.Printf("Error setting runtime: %v", err)
+		bot.replyError(err, "Error setting runtime.")
+		return
+	}
+	if changed == 0 {
+		bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(target))
+		return
 	}
 
-	showtime.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
+	bot.audit(nick, "set-runtime", target, fmt.Sprintf("runtime_minutes = %d on %d row(s)", minutes, changed))
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Set the runtime of %s to %s.",
+		pluralize(changed, "showtime"), pluralize(minutes, "minute")))
+}
+
+// retzShowtime fixes a showtime entered in the wrong timezone: the wall-clock
+// time it has in the -from zone is kept and re-read in the -to zone
+func (bot *command) retzShowtime(args []string, nick string) {
+	id := flagValue(args, "-retz")
+	fromName := flagValue(args, "-from")
+	toName := flagValue(args, "-to")
+	if id == "" || fromName == "" || toName == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -retz=\"id\" -from=UTC -to=\"America/New_York\"")
+		return
+	}
+
+	from, err := time.LoadLocation(fromName)
 	if err != nil {
-		return nil, err
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Unknown timezone '%s'.", fromName))
+		return
+	}
+	to, err := time.LoadLocation(toName)
+	if err != nil {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Unknown timezone '%s'.", toName))
+		return
+	}
+
+	showtime, err := bot.store.GetByID(id)
+	if err != nil {
+		log.Printf("Error getting showtime: %v", err)
+		bot.replyError(err, "Error retrieving showtime.")
+		return
+	}
+	if showtime == nil {
+		bot.sender.Privmsg(bot.config.Channel, bot.notFoundMessage(id))
+		return
+	}
+
+	previous := showtime.DateTime
+	wall := previous.In(from)
+	showtime.DateTime = time.Date(wall.Year(), wall.Month(), wall.Day(),
+		wall.Hour(), wall.Minute(), wall.Second(), 0, to).UTC()
+	if err := bot.store.Update(*showtime); err != nil {
+		log.Printf("Error updating showtime timezone: %v", err)
+		bot.replyError(err, "Error updating showtime.")
+		return
 	}
 
-	return &showtime, nil
+	bot.audit(nick, "retz", id, fmt.Sprintf("%s as %s -> %s: %s -> %s", wall.Format("2006-01-02 15:04"),
+		from, to, storedTime(previous), storedTime(showtime.DateTime)))
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("[%s] %s: read %s as %s instead of %s. Now %s (was %s).",
+		id, showtime.Title, wall.Format("2006-01-02 15:04"), to, from,
+		bot.formatTime(showtime.DateTime), bot.formatTime(previous)))
 }
 
-func (bot *CinemaBot) deleteShowtimeByID(id string) error {
-	query := "DELETE FROM showtimes WHERE id = ?"
-	_, err := bot.db.Exec(query, id)
-	return err
+// shiftShowtimes moves every upcoming showtime created by -creator by the
+// -shift-by duration, e.g. when an organizer's whole block starts later.
+// Moves that would land a showtime in the past are refused without -force.
+func (bot *command) shiftShowtimes(args []string, nick string, now time.Time) {
+	offset, err := time.ParseDuration(flagValue(args, "-shift-by"))
+	creator := flagValue(args, "-creator")
+	if err != nil || offset == 0 || creator == "" {
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .showtime -shift-by=1h -creator=\"nick\" [-force]")
+		return
+	}
+
+	upcoming, err := bot.store.Upcoming(now, 0)
+	if err != nil {
+		log.Printf("Error getting upcoming showtimes: %v", err)
+		bot.replyError(err, "Error retrieving showtimes.")
+		return
+	}
+
+	var shifted []Showtime
+	intoPast := 0
+	for _, showtime := range upcoming {
+		if showtime.CreatedBy != creator {
+			continue
+		}
+		showtime.DateTime = showtime.DateTime.Add(offset)
+		if !showtime.DateTime.After(now) {
+			intoPast++
+		}
+		shifted = append(shifted, showtime)
+	}
+
+	if len(shifted) == 0 {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s has no upcoming showtimes.", creator))
+		return
+	}
+	if intoPast > 0 && !hasFlag(args[1:], "-force") {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Shifting by %s would move %s into the past. Add -force to shift anyway.",
+			offset, pluralize(intoPast, "showtime")))
+		return
+	}
+
+	if err := bot.store.UpdateAll(shifted); err != nil {
+		log.Printf("Error shifting showtimes: %v", err)
+		bot.replyError(err, "Error shifting showtimes.")
+		return
+	}
+
+	for _, showtime := range shifted {
+		bot.audit(nick, "shift", showtime.ID, fmt.Sprintf("%s: %s -> %s", offset,
+			storedTime(showtime.DateTime.Add(-offset)), storedTime(showtime.DateTime)))
+	}
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Shifted %s's %s by %s.",
+		creator, pluralize(len(shifted), "upcoming showtime"), offset))
 }
 
 func (bot *CinemaBot) Connect() error {
@@ -859,12 +3233,73 @@ func (bot *CinemaBot) Connect() error {
 		return fmt.Errorf("failed to connect: %v", err)
 	}
 
+	bot.recordPong(time.Now())
+	go bot.monitorPings(time.Duration(bot.config.PingTimeoutSeconds) * time.Second)
+	go bot.runReminders()
+	go bot.runInactivityCheck()
+	go bot.runDigest()
+	go bot.runAuditPruning()
+	go bot.runChannelLogPruning()
+	if bot.config.MaintenanceIntervalHours > 0 {
+		go bot.runMaintenance(time.Duration(bot.config.MaintenanceIntervalHours) * time.Hour)
+	}
+
 	bot.conn.Loop()
 	return nil
 }
 
-// startHealthCheckServer starts a simple HTTP server for health checks
-func startHealthCheckServer() {
+// configureKeepAlive applies keepalive_seconds to the library's own ping
+// schedule, leaving its defaults alone when unset
+func (bot *CinemaBot) configureKeepAlive() {
+	if bot.config.KeepAliveSeconds <= 0 {
+		return
+	}
+	interval := time.Duration(bot.config.KeepAliveSeconds) * time.Second
+	bot.conn.KeepAlive = interval
+	bot.conn.PingFreq = interval
+}
+
+func (bot *CinemaBot) recordPong(t time.Time) {
+	bot.pongMu.Lock()
+	bot.lastPong = t
+	bot.pongMu.Unlock()
+}
+
+// pingTimedOut reports whether nothing has answered our PINGs for timeout
+func (bot *CinemaBot) pingTimedOut(now time.Time, timeout time.Duration) bool {
+	bot.pongMu.Lock()
+	defer bot.pongMu.Unlock()
+	return now.Sub(bot.lastPong) > timeout
+}
+
+// monitorPings PINGs the server a few times per timeout period and drops the
+// connection when no PONG arrives in time, so the library's Loop reconnects
+// instead of hanging on a dead socket
+func (bot *CinemaBot) monitorPings(timeout time.Duration) {
+	ticker := time.NewTicker(timeout / 3)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		if !bot.conn.Connected() {
+			// Loop is already reconnecting; give the new connection a full window
+			bot.recordPong(now)
+			continue
+		}
+
+		if bot.pingTimedOut(now, timeout) {
+			log.Printf("No PONG from server in %v, forcing reconnect", timeout)
+			bot.recordPong(now)
+			bot.conn.Disconnect()
+			continue
+		}
+
+		bot.conn.SendRawf("PING cinemabot-%d", now.Unix())
+	}
+}
+
+// startHealthCheckServer starts a simple HTTP server for health checks and the
+// public schedule page
+func startHealthCheckServer(bot *CinemaBot) {
 	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
@@ -873,6 +3308,7 @@ func startHealthCheckServer() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	http.HandleFunc("/showtimes.html", bot.handleSchedulePage)
 
 	log.Printf("Starting health check server on :8000")
 	go func() {
@@ -882,10 +3318,46 @@ func startHealthCheckServer() {
 	}()
 }
 
+// configEnvVar names the config file when -config isn't given
+const configEnvVar = "CINEMABOT_CONFIG"
+
+// resolveConfigPath returns the -config value when it was passed explicitly,
+// otherwise $CINEMABOT_CONFIG if set, otherwise the flag's default
+func resolveConfigPath(flagValue string, explicit bool) string {
+	if explicit {
+		return flagValue
+	}
+	if path := os.Getenv(configEnvVar); path != "" {
+		return path
+	}
+	return flagValue
+}
+
 func main() {
 	configFile := flag.String("config", "bot_config.json", "Path to config file (optional)")
+	checkOnly := flag.Bool("check-config", false, "Validate the config file and exit without connecting")
 	flag.Parse()
 
+	explicit := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			explicit = true
+		}
+	})
+	*configFile = resolveConfigPath(*configFile, explicit)
+
+	if *checkOnly {
+		problems := checkConfig(*configFile)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", *configFile, problem)
+		}
+		if len(problems) > 0 {
+			os.Exit(1)
+		}
+		fmt.Printf("%s: OK\n", *configFile)
+		return
+	}
+
 	bot, err := NewCinemaBot(*configFile)
 	if err != nil {
 		log.Fatalf("Failed to create bot: %v", err)
@@ -898,15 +3370,18 @@ func main() {
 		}
 	}()
 
+	// Reload runtime settings on SIGHUP
+	bot.watchReloadSignal()
+
 	// Start health check server
-	startHealthCheckServer()
+	startHealthCheckServer(bot)
 
 	log.Printf("Starting CinemaBot...")
 	log.Printf("Server: %s", bot.config.Server)
 	log.Printf("Nick: %s", bot.config.Nick)
 	log.Printf("Channel: %s", bot.config.Channel)
 	log.Printf("Database: %s", bot.config.DatabasePath)
-	log.Printf("Timezone: UTC")
+	log.Printf("Timezone: %s", bot.snapshot().displayLocation())
 
 	if err := bot.Connect(); err != nil {
 		log.Fatalf("Connection failed: %v", err)
@@ -1,8 +1,19 @@
 package main
 
 import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
 	"os"
+	"regexp"
+	"sort"
+	"strings"
 	"testing"
+	"time"
+	"unicode/utf8"
+
+	irc "github.com/thoj/go-ircevent"
 )
 
 func TestLoadConfig_ValidFile(t *testing.T) {
@@ -41,6 +52,82 @@ func TestLoadConfig_ValidFile(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_ValidYAMLFile(t *testing.T) {
+	content := `
+server: irc.example.com:6667
+nick: testbot
+channel: "#testchan"
+nickserv:
+  password: secret
+authorized_nicks:
+  alice: true
+`
+	for _, pattern := range []string{"config*.yaml", "config*.yml"} {
+		tmpfile, err := os.CreateTemp("", pattern)
+		if err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+		defer os.Remove(tmpfile.Name())
+		if _, err := tmpfile.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write temp file: %v", err)
+		}
+		tmpfile.Close()
+
+		bot := &CinemaBot{}
+		err = bot.loadConfig(tmpfile.Name())
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if bot.config.Server != "irc.example.com:6667" {
+			t.Errorf("expected server, got %s", bot.config.Server)
+		}
+		if bot.config.Nick != "testbot" {
+			t.Errorf("expected nick, got %s", bot.config.Nick)
+		}
+		if bot.config.Channel != "#testchan" {
+			t.Errorf("expected channel, got %s", bot.config.Channel)
+		}
+		if bot.config.NickServ.Password != "secret" {
+			t.Errorf("expected password, got %s", bot.config.NickServ.Password)
+		}
+		if !bot.config.AuthorizedNicks.Global["alice"] {
+			t.Errorf("expected alice to be authorized, got %v", bot.config.AuthorizedNicks)
+		}
+	}
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	t.Setenv(configEnvVar, "")
+	if path := resolveConfigPath("bot_config.json", false); path != "bot_config.json" {
+		t.Errorf("expected default path without env var, got %s", path)
+	}
+
+	t.Setenv(configEnvVar, "/etc/cinemabot/config.yaml")
+	if path := resolveConfigPath("bot_config.json", false); path != "/etc/cinemabot/config.yaml" {
+		t.Errorf("expected env var path, got %s", path)
+	}
+	if path := resolveConfigPath("custom.json", true); path != "custom.json" {
+		t.Errorf("expected explicit flag to win, got %s", path)
+	}
+}
+
+func TestLoadConfig_InvalidYAML(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config*.yaml")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte("server: [unclosed")); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	bot := &CinemaBot{}
+	if err := bot.loadConfig(tmpfile.Name()); err == nil {
+		t.Fatal("expected error for invalid YAML, got nil")
+	}
+}
+
 func TestLoadConfig_MissingFile(t *testing.T) {
 	bot := &CinemaBot{}
 	err := bot.loadConfig("nonexistent_file.json")
@@ -60,6 +147,463 @@ func TestLoadConfig_EmptyPath(t *testing.T) {
 	}
 }
 
+func TestLoadConfig_JustStartedDefault(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	if err := bot.loadConfig(""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if window := bot.justStartedWindow(); window != defaultJustStartedSeconds*time.Second {
+		t.Errorf("expected default grace %ds, got %v", defaultJustStartedSeconds, window)
+	}
+}
+
+func TestLoadConfig_JustStartedOff(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	if err := bot.loadConfig(writeTestConfig(t, "config.json", `{"just_started_seconds": 0}`)); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if bot.justStarted(0) {
+		t.Error("expected just_started_seconds: 0 to turn the phrasing off")
+	}
+}
+
+func TestJustStarted(t *testing.T) {
+	seconds := 60
+	bot := newConfigCommand(Config{JustStartedSeconds: &seconds})
+	tests := []struct {
+		duration time.Duration
+		expected bool
+	}{
+		{0, true},
+		{30 * time.Second, true},
+		{59*time.Second + 400*time.Millisecond, true},
+		{60 * time.Second, false},
+		{5 * time.Minute, false},
+	}
+	for _, tt := range tests {
+		if got := bot.justStarted(tt.duration); got != tt.expected {
+			t.Errorf("justStarted(%v): expected %v, got %v", tt.duration, tt.expected, got)
+		}
+	}
+}
+
+func TestStartingNow(t *testing.T) {
+	seconds := 5
+	bot := newConfigCommand(Config{JustStartedSeconds: &seconds})
+	tests := []struct {
+		duration time.Duration
+		expected bool
+	}{
+		{0, true},
+		{3 * time.Second, true},
+		{4*time.Second + 400*time.Millisecond, true},
+		{5 * time.Second, false},
+		{time.Minute, false},
+	}
+	for _, tt := range tests {
+		if got := bot.startingNow(tt.duration); got != tt.expected {
+			t.Errorf("startingNow(%v): expected %v, got %v", tt.duration, tt.expected, got)
+		}
+	}
+
+	seconds = 0
+	if bot.startingNow(0) {
+		t.Error("expected just_started_seconds: 0 to turn starting now off too")
+	}
+}
+
+func TestNextMovie_StartWindow(t *testing.T) {
+	now := time.Date(2025, 6, 14, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		window   int
+		start    time.Time
+		expected string
+	}{
+		{"exact start", 5, now, "Vertigo just started!"},
+		{"just after", 5, now.Add(-4 * time.Second), "Vertigo just started!"},
+		{"just before", 5, now.Add(3 * time.Second), "Vertigo is starting now!"},
+		{"window boundary", 5, now.Add(5 * time.Second), "In 5 seconds, Vertigo is playing!"},
+		{"turned off", 0, now.Add(3 * time.Second), "In 3 seconds, Vertigo is playing!"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bot, sender := newTestBot()
+			window := tt.window
+			bot.config.JustStartedSeconds = &window
+			bot.store.Create(Showtime{ID: "vertigo", Title: "Vertigo", DateTime: tt.start})
+
+			bot.nextMovieAt([]string{".nextmovie"}, now)
+			if len(sender.messages) != 1 || !strings.Contains(sender.messages[0], tt.expected) {
+				t.Errorf("expected %q, got %v", tt.expected, sender.messages)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_PerChannelAuthorizedNicks(t *testing.T) {
+	files := map[string]string{
+		"config*.json": `{"authorized_nicks": {"alice": true, "#A": {"bob": true}, "#b": {"carol": true}}}`,
+		"config*.yaml": "authorized_nicks:\n  alice: true\n  \"#A\":\n    bob: true\n  \"#b\":\n    carol: true\n",
+	}
+	for pattern, content := range files {
+		tmpfile, err := os.CreateTemp("", pattern)
+		if err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+		defer os.Remove(tmpfile.Name())
+		if _, err := tmpfile.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write temp file: %v", err)
+		}
+		tmpfile.Close()
+
+		bot := &CinemaBot{}
+		if err := bot.loadConfig(tmpfile.Name()); err != nil {
+			t.Fatalf("%s: expected no error, got %v", pattern, err)
+		}
+
+		tests := []struct {
+			channel, nick string
+			expected      bool
+		}{
+			{"#a", "alice", true},
+			{"#b", "alice", true},
+			{"#a", "bob", true},
+			{"#b", "bob", false},
+			{"#b", "carol", true},
+			{"#a", "carol", false},
+			{"#c", "dave", false},
+		}
+		for _, tt := range tests {
+			if got := bot.config.AuthorizedNicks.Allows(tt.channel, tt.nick); got != tt.expected {
+				t.Errorf("%s: Allows(%s, %s): expected %v, got %v", pattern, tt.channel, tt.nick, tt.expected, got)
+			}
+		}
+	}
+}
+
+func TestLoadConfig_InvalidAuthorizedNicks(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte(`{"authorized_nicks": {"#a": true}}`)); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	bot := &CinemaBot{}
+	if err := bot.loadConfig(tmpfile.Name()); err == nil {
+		t.Error("expected error for a channel entry that isn't a nick map")
+	}
+}
+
+func TestAuthorizedShowtimeCommand(t *testing.T) {
+	bot := newConfigCommand(Config{AuthorizedNicks: AuthorizedNicks{
+		Global:   map[string]bool{"alice": true},
+		Channels: map[string]map[string]bool{"#a": {"bob": true}},
+	}})
+
+	if !bot.authorizedShowtimeCommand("#b", "alice", "user/alice") {
+		t.Error("expected global nick to be authorized everywhere")
+	}
+	if !bot.authorizedShowtimeCommand("#A", "bob", "user/bob") {
+		t.Error("expected bob to be authorized in #a")
+	}
+	if bot.authorizedShowtimeCommand("#b", "bob", "user/bob") {
+		t.Error("expected bob not to be authorized in #b")
+	}
+	if bot.authorizedShowtimeCommand("#a", "bob", "example.com") {
+		t.Error("expected unverified host to be rejected")
+	}
+}
+
+func TestAuthorizedShowtimeCommand_HostPatterns(t *testing.T) {
+	path := writeTestConfig(t, "config.json", `{
+		"authorized_nicks": {"alice": true, "bob": true},
+		"auth_host_pattern": "\\.staff\\.example\\.net$",
+		"auth_host_patterns": {"bob": "^bob\\.home\\.example\\.org$"}
+	}`)
+	bot := newConfigCommand(Config{})
+	if err := bot.loadConfig(path); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		nick     string
+		host     string
+		expected bool
+	}{
+		{"alice", "alice.staff.example.net", true},
+		{"alice", "user/alice", false},
+		{"bob", "bob.home.example.org", true},
+		{"bob", "bob.staff.example.net", false},
+		{"carol", "carol.staff.example.net", false},
+	}
+	for _, tt := range tests {
+		if got := bot.authorizedShowtimeCommand("#a", tt.nick, tt.host); got != tt.expected {
+			t.Errorf("%s at %s: expected %v, got %v", tt.nick, tt.host, tt.expected, got)
+		}
+	}
+}
+
+func TestLoadConfig_InvalidAuthHostPattern(t *testing.T) {
+	for _, content := range []string{
+		`{"auth_host_pattern": "user/("}`,
+		`{"auth_host_patterns": {"alice": "[a-"}}`,
+	} {
+		bot := &CinemaBot{}
+		if err := bot.loadConfig(writeTestConfig(t, "config.json", content)); err == nil {
+			t.Errorf("expected an error for %s", content)
+		}
+	}
+}
+
+func TestApplyReloadedConfig(t *testing.T) {
+	seconds := 60
+	bot := &CinemaBot{config: Config{
+		Server:             "irc.example.com:6667",
+		Nick:               "testbot",
+		Channel:            "#testchan",
+		AuthorizedNicks:    AuthorizedNicks{Global: map[string]bool{"alice": true}},
+		JustStartedSeconds: &seconds,
+	}}
+
+	cfg := bot.config
+	cfg.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"alice": true, "bob": true}}
+	cfg.Channel = "#otherchan"
+
+	changed, ignored := bot.applyReloadedConfig(cfg)
+	if !equalStringSlices(changed, []string{"authorized_nicks"}) {
+		t.Errorf("expected authorized_nicks to change, got %v", changed)
+	}
+	if !equalStringSlices(ignored, []string{"channel"}) {
+		t.Errorf("expected channel to be ignored, got %v", ignored)
+	}
+	if !bot.config.AuthorizedNicks.Global["bob"] {
+		t.Error("expected bob to be authorized after reload")
+	}
+	if bot.config.Channel != "#testchan" {
+		t.Errorf("expected channel to stay #testchan, got %s", bot.config.Channel)
+	}
+}
+
+func TestReloadConfig(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte(`{"authorized_nicks": {"carol": true}, "just_started_seconds": 30}`)); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	bot := newConfigCommand(Config{})
+	bot.configFile = tmpfile.Name()
+	if err := bot.reloadConfig(); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !bot.config.AuthorizedNicks.Global["carol"] || bot.justStartedWindow() != 30*time.Second {
+		t.Errorf("expected reloaded settings, got %+v", bot.config)
+	}
+}
+
+func TestLoadConfig_DisplayTimezone(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte(`{"display_timezone": "America/New_York"}`)); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	bot := newConfigCommand(Config{})
+	if err := bot.loadConfig(tmpfile.Name()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got := bot.formatTime(time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC))
+	if got != "2025-06-13 16:00:00 EDT" {
+		t.Errorf("expected time in New York, got %s", got)
+	}
+}
+
+func TestLoadConfig_InvalidDisplayTimezone(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte(`{"display_timezone": "Mars/Olympus_Mons"}`)); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	bot := &CinemaBot{}
+	if err := bot.loadConfig(tmpfile.Name()); err == nil {
+		t.Fatal("expected error for invalid timezone, got nil")
+	}
+}
+
+func TestFormatTime_DefaultsToUTC(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	got := bot.formatTime(time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC))
+	if got != "2025-06-13 20:00:00 UTC" {
+		t.Errorf("expected UTC time, got %s", got)
+	}
+}
+
+func TestFormatTime_BothTimes(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	bot := newConfigCommand(Config{ShowBothTimes: true, location: location})
+
+	got := bot.formatTime(time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC))
+	if got != "2025-06-13 20:00:00 UTC (16:00 EDT)" {
+		t.Errorf("unexpected rendering %s", got)
+	}
+
+	got = bot.formatTime(time.Date(2025, 6, 14, 2, 30, 0, 0, time.UTC))
+	if got != "2025-06-14 02:30:00 UTC (2025-06-13 22:30 EDT)" {
+		t.Errorf("expected local date when it differs, got %s", got)
+	}
+}
+
+func TestFormatTime_BothTimesWithoutTimezone(t *testing.T) {
+	bot := newConfigCommand(Config{ShowBothTimes: true, location: time.UTC})
+	got := bot.formatTime(time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC))
+	if got != "2025-06-13 20:00:00 UTC" {
+		t.Errorf("expected single UTC time, got %s", got)
+	}
+}
+
+func TestFormatTime_TwelveHour(t *testing.T) {
+	location, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	bot := newConfigCommand(Config{TimeFormat: "2006-01-02 03:04 PM MST"})
+
+	got := bot.formatTime(time.Date(2025, 6, 13, 20, 30, 0, 0, time.UTC))
+	if got != "2025-06-13 08:30 PM UTC" {
+		t.Errorf("expected 12-hour time, got %s", got)
+	}
+
+	bot.config.ShowBothTimes = true
+	bot.config.location = location
+	got = bot.formatTime(time.Date(2025, 6, 13, 20, 30, 0, 0, time.UTC))
+	if got != "2025-06-13 08:30 PM UTC (4:30 PM EDT)" {
+		t.Errorf("expected 12-hour local time, got %s", got)
+	}
+}
+
+func TestFormatTime_FractionalOffsets(t *testing.T) {
+	tests := []struct {
+		zone     string
+		single   string
+		bothTime string
+	}{
+		{"Asia/Kolkata", "2025-06-14 01:30:00 IST", "2025-06-13 20:00:00 UTC (2025-06-14 01:30 IST)"},
+		{"Australia/Eucla", "2025-06-14 04:45:00 +0845", "2025-06-13 20:00:00 UTC (2025-06-14 04:45 +0845)"},
+		{"Asia/Kathmandu", "2025-06-14 01:45:00 +0545", "2025-06-13 20:00:00 UTC (2025-06-14 01:45 +0545)"},
+	}
+	for _, tt := range tests {
+		location, err := time.LoadLocation(tt.zone)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", tt.zone, err)
+		}
+		bot := newConfigCommand(Config{DisplayTimezone: tt.zone, location: location})
+		start := time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC)
+
+		if got := bot.formatTime(start); got != tt.single {
+			t.Errorf("%s: expected %s, got %s", tt.zone, tt.single, got)
+		}
+		bot.config.ShowBothTimes = true
+		if got := bot.formatTime(start); got != tt.bothTime {
+			t.Errorf("%s: expected %s, got %s", tt.zone, tt.bothTime, got)
+		}
+	}
+}
+
+func TestLoadConfig_TimeFormat(t *testing.T) {
+	tests := map[string]bool{
+		`{"time_format": "2006-01-02 03:04 PM MST"}`: true,
+		`{"time_format": "Jan 2 15:04"}`:             true,
+		`{"time_format": "tomorrow-ish"}`:            false,
+	}
+	for content, valid := range tests {
+		tmpfile, err := os.CreateTemp("", "config*.json")
+		if err != nil {
+			t.Fatalf("failed to create temp file: %v", err)
+		}
+		defer os.Remove(tmpfile.Name())
+		if _, err := tmpfile.Write([]byte(content)); err != nil {
+			t.Fatalf("failed to write temp file: %v", err)
+		}
+		tmpfile.Close()
+
+		bot := &CinemaBot{}
+		err = bot.loadConfig(tmpfile.Name())
+		if valid && err != nil {
+			t.Errorf("%s: expected no error, got %v", content, err)
+		}
+		if !valid && err == nil {
+			t.Errorf("%s: expected error, got nil", content)
+		}
+	}
+}
+
+func TestLoadConfig_CurrentWindowHours(t *testing.T) {
+	tmpfile, err := os.CreateTemp("", "config*.json")
+	if err != nil {
+		t.Fatalf("failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+	if _, err := tmpfile.Write([]byte(`{"current_window_hours": 2}`)); err != nil {
+		t.Fatalf("failed to write temp file: %v", err)
+	}
+	tmpfile.Close()
+
+	bot := newConfigCommand(Config{})
+	if err := bot.loadConfig(tmpfile.Name()); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if bot.currentWindow() != 2*time.Hour {
+		t.Errorf("expected 2h window, got %v", bot.currentWindow())
+	}
+
+	bot = newConfigCommand(Config{})
+	if err := bot.loadConfig(""); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if bot.currentWindow() != 3*time.Hour {
+		t.Errorf("expected default 3h window, got %v", bot.currentWindow())
+	}
+}
+
+func TestPingTimedOut(t *testing.T) {
+	bot := &CinemaBot{}
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	bot.recordPong(now.Add(-2 * time.Minute))
+
+	if bot.pingTimedOut(now, 3*time.Minute) {
+		t.Error("expected a PONG two minutes ago to be within a three minute timeout")
+	}
+	if !bot.pingTimedOut(now, time.Minute) {
+		t.Error("expected a PONG two minutes ago to exceed a one minute timeout")
+	}
+
+	bot.recordPong(now)
+	if bot.pingTimedOut(now, time.Minute) {
+		t.Error("expected a fresh PONG to reset the timeout")
+	}
+}
+
 func TestLoadConfig_InvalidJSON(t *testing.T) {
 	content := `{invalid json}`
 	tmpfile, err := os.CreateTemp("", "config*.json")
@@ -223,15 +767,2400 @@ func TestParseArgs_QuoteWithEscapedBackslash(t *testing.T) {
 	}
 }
 
-// Helper for comparing slices
-func equalStringSlices(a, b []string) bool {
-	if len(a) != len(b) {
-		return false
+func TestSanitizeTitle_MultipleSpaces(t *testing.T) {
+	bot := &CinemaBot{}
+	got := bot.sanitizeTitle("  A   Streetcar  Named Desire ")
+	expected := "A Streetcar Named Desire"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
 	}
-	for i := range a {
-		if a[i] != b[i] {
-			return false
+}
+
+func TestSanitizeTitle_Tabs(t *testing.T) {
+	bot := &CinemaBot{}
+	got := bot.sanitizeTitle("\tThe\t\tThird Man\t")
+	expected := "The Third Man"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestSanitizeTitle_OnlyWhitespace(t *testing.T) {
+	bot := &CinemaBot{}
+	got := bot.sanitizeTitle(" \t  ")
+	if got != "" {
+		t.Errorf("expected empty title, got %q", got)
+	}
+}
+
+func TestStripControlCodes_Colors(t *testing.T) {
+	bot := &CinemaBot{}
+	got := bot.stripControlCodes("\x0304Red\x03 \x0312,01Blue on black\x03 plain")
+	expected := "Red Blue on black plain"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestStripControlCodes_ColorFollowedByDigits(t *testing.T) {
+	bot := &CinemaBot{}
+	got := bot.stripControlCodes("\x03042001: A Space Odyssey")
+	expected := "2001: A Space Odyssey"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestStripControlCodes_FormattingBytes(t *testing.T) {
+	bot := &CinemaBot{}
+	got := bot.stripControlCodes("\x02Bold\x02 \x1dItalic\x1d \x1fUnder\x1f\x0f\x07")
+	expected := "Bold Italic Under"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestStripControlCodes_KeepsUnicode(t *testing.T) {
+	bot := &CinemaBot{}
+	got := bot.stripControlCodes("Amélie \x03" + "3,4🎬 千と千尋の神隠し")
+	expected := "Amélie 🎬 千と千尋の神隠し"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestSanitizeTitle_StripsColorCodes(t *testing.T) {
+	bot := &CinemaBot{}
+	got := bot.sanitizeTitle("\x0304,01  Jaws\x03\t\x02 2\x02 ")
+	expected := "Jaws 2"
+	if got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestFormatRelativeTime(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	now := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{now.Add(2 * time.Hour), "In 2 hours"},
+		{now.Add(90 * time.Second), "In 1 minute, 30 seconds"},
+		{now, "Now"},
+		{now.Add(-45 * time.Minute), "45 minutes ago"},
+		{now.Add(-26 * time.Hour), "1 day, 2 hours ago"},
+	}
+	for _, tt := range tests {
+		if got := bot.formatRelativeTime(tt.t, now); got != tt.expected {
+			t.Errorf("formatRelativeTime(%v): expected %q, got %q", tt.t.Sub(now), tt.expected, got)
 		}
 	}
-	return true
+}
+
+func TestParseListOptions_Relative(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	if opts, _ := bot.parseListOptions([]string{".showtime", "-list"}); opts.relative {
+		t.Error("expected absolute times by default")
+	}
+	if opts, _ := bot.parseListOptions([]string{".showtime", "-list", "-relative"}); !opts.relative {
+		t.Error("expected -relative to enable relative times")
+	}
+}
+
+func TestFormatTimeUntil_Default(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "Now"},
+		{time.Second, "In 1 second"},
+		{45 * time.Second, "In 45 seconds"},
+		{5*time.Minute + 3*time.Second, "In 5 minutes, 3 seconds"},
+		{2*time.Hour + 5*time.Minute + 30*time.Second, "In 2 hours, 5 minutes"},
+	}
+	for _, tt := range tests {
+		if got := bot.formatTimeUntil(tt.duration, normalGranularity); got != tt.expected {
+			t.Errorf("formatTimeUntil(%v): expected %q, got %q", tt.duration, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatTimeUntil_Precise(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{0, "Now"},
+		{45 * time.Second, "In 45 seconds"},
+		{2*time.Hour + 5*time.Minute + 30*time.Second, "In 2 hours, 5 minutes, 30 seconds"},
+		{time.Hour + time.Second, "In 1 hour, 1 second"},
+		{3 * time.Hour, "In 3 hours"},
+	}
+	for _, tt := range tests {
+		if got := bot.formatTimeUntil(tt.duration, preciseGranularity); got != tt.expected {
+			t.Errorf("formatTimeUntil(%v, precise): expected %q, got %q", tt.duration, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatTimeSince_Precise(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	duration := time.Hour + 2*time.Minute + 9*time.Second
+	if got := bot.formatTimeSince(duration, normalGranularity); got != "1 hour, 2 minutes" {
+		t.Errorf("expected %q, got %q", "1 hour, 2 minutes", got)
+	}
+	if got := bot.formatTimeSince(duration, preciseGranularity); got != "1 hour, 2 minutes, 9 seconds" {
+		t.Errorf("expected %q, got %q", "1 hour, 2 minutes, 9 seconds", got)
+	}
+}
+
+func TestFormatTimeUntil_Days(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{24*time.Hour - time.Second, "In 23 hours, 59 minutes"},
+		{24 * time.Hour, "In 1 day"},
+		{25 * time.Hour, "In 1 day, 1 hour"},
+		{25*time.Hour + 30*time.Minute, "In 1 day, 1 hour"},
+		{50 * time.Hour, "In 2 days, 2 hours"},
+		{51*time.Hour + 12*time.Minute, "In 2 days, 3 hours"},
+		{7 * 24 * time.Hour, "In 7 days"},
+	}
+	for _, tt := range tests {
+		if got := bot.formatTimeUntil(tt.duration, normalGranularity); got != tt.expected {
+			t.Errorf("formatTimeUntil(%v): expected %q, got %q", tt.duration, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatTimeUntil_DaysPrecise(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	duration := 2*24*time.Hour + 3*time.Hour + 4*time.Minute + 5*time.Second
+	expected := "In 2 days, 3 hours, 4 minutes, 5 seconds"
+	if got := bot.formatTimeUntil(duration, preciseGranularity); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestFormatTimeSince_Days(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{24 * time.Hour, "1 day"},
+		{25 * time.Hour, "1 day, 1 hour"},
+		{74 * time.Hour, "3 days, 2 hours"},
+	}
+	for _, tt := range tests {
+		if got := bot.formatTimeSince(tt.duration, normalGranularity); got != tt.expected {
+			t.Errorf("formatTimeSince(%v): expected %q, got %q", tt.duration, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatTimeUntil_Coarse(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	day := 24 * time.Hour
+	tests := []struct {
+		duration time.Duration
+		expected string
+	}{
+		{90 * time.Minute, "In 1 hour, 30 minutes"},
+		{7*day - time.Hour, "In 6 days, 23 hours"},
+		{7 * day, "In 1 week"},
+		{8*day + 5*time.Hour, "In 1 week, 1 day"},
+		{21 * day, "In 3 weeks"},
+		{30*day - time.Second, "In 4 weeks, 1 day"},
+		{30 * day, "In 1 month"},
+		{45 * day, "In 1 month, 2 weeks"},
+		{65 * day, "In 2 months"},
+	}
+	for _, tt := range tests {
+		if got := bot.formatTimeUntil(tt.duration, coarseGranularity); got != tt.expected {
+			t.Errorf("formatTimeUntil(%v, coarse): expected %q, got %q", tt.duration, tt.expected, got)
+		}
+	}
+}
+
+func TestFormatTimeUntil_NormalIgnoresWeeks(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	expected := "In 21 days"
+	if got := bot.formatTimeUntil(21*24*time.Hour, normalGranularity); got != expected {
+		t.Errorf("expected %q, got %q", expected, got)
+	}
+}
+
+func TestParseListOptions_Format(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	opts, _ := bot.parseListOptions([]string{".showtime", "-list", "-format=JSON", "-relative"})
+	if opts.format != "json" || !opts.relative {
+		t.Errorf("expected json format with relative times, got %+v", opts)
+	}
+}
+
+func TestParseListOptions_DateRange(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	opts, err := bot.parseListOptions([]string{".showtime", "-list", "-from=2025-06-01", `-to="2025-06-07"`})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !opts.filter.From.Equal(time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("unexpected from %v", opts.filter.From)
+	}
+	if !opts.filter.To.Equal(time.Date(2025, 6, 7, 23, 59, 59, 0, time.UTC)) {
+		t.Errorf("expected -to to include the whole day, got %v", opts.filter.To)
+	}
+
+	opts, err = bot.parseListOptions([]string{".showtime", "-list", "-from=2025-06-01 18:30"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !opts.filter.From.Equal(time.Date(2025, 6, 1, 18, 30, 0, 0, time.UTC)) || !opts.filter.To.IsZero() {
+		t.Errorf("unexpected filter %+v", opts.filter)
+	}
+}
+
+func TestParseListOptions_InvalidDateRange(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	if _, err := bot.parseListOptions([]string{".showtime", "-list", "-from=2025-06-07", "-to=2025-06-01"}); err == nil {
+		t.Error("expected error when -from is after -to")
+	}
+	if _, err := bot.parseListOptions([]string{".showtime", "-list", "-from=June"}); err == nil {
+		t.Error("expected error for an unparseable date")
+	}
+}
+
+func TestChunkItems_FitsOneChunk(t *testing.T) {
+	chunks := chunkItems([]string{"a", "b", "c"}, ",", 10)
+	expected := []string{"a,b,c"}
+	if !equalStringSlices(chunks, expected) {
+		t.Errorf("expected %v, got %v", expected, chunks)
+	}
+}
+
+func TestChunkItems_SplitsOnItemBoundaries(t *testing.T) {
+	chunks := chunkItems([]string{"aaaa", "bbbb", "cccc"}, ", ", 10)
+	expected := []string{"aaaa, bbbb", "cccc"}
+	if !equalStringSlices(chunks, expected) {
+		t.Errorf("expected %v, got %v", expected, chunks)
+	}
+}
+
+func TestChunkItems_OversizedItem(t *testing.T) {
+	chunks := chunkItems([]string{"ab", "ééééé", "c"}, ",", 4)
+	expected := []string{"ab", "éé", "éé", "é,c"}
+	if !equalStringSlices(chunks, expected) {
+		t.Errorf("expected %v, got %v", expected, chunks)
+	}
+	for _, chunk := range chunks {
+		if !utf8.ValidString(chunk) {
+			t.Errorf("chunk %q is not valid UTF-8", chunk)
+		}
+	}
+}
+
+func TestChunkItems_Empty(t *testing.T) {
+	if chunks := chunkItems(nil, ",", 10); len(chunks) != 0 {
+		t.Errorf("expected no chunks, got %v", chunks)
+	}
+}
+
+func TestMessageBytes(t *testing.T) {
+	if got := messageBytes("#testchan"); got != maxMessageBytes {
+		t.Errorf("expected the full budget for a short channel, got %d", got)
+	}
+	channel := "#" + strings.Repeat("c", 199)
+	if got := messageBytes(channel); got+len("PRIVMSG "+channel+" :\r\n")+maxSourcePrefixBytes != 512 {
+		t.Errorf("expected a long channel to shrink the budget to fit 512 bytes, got %d", got)
+	}
+}
+
+func TestLongChannelName(t *testing.T) {
+	bot, capture := newTestBot()
+	bot.sender = splitSender{capture}
+	bot.config.Channel = "#" + strings.Repeat("c", 199)
+	title := strings.TrimSpace(strings.Repeat("The Cabinet of Dr. Caligari ", 9))
+
+	bot.createShowtime(bot.parseArgs(fmt.Sprintf(`.showtime -create -id=movie -title="%s" -date="2025-06-13 19:00"`, title)), "alice")
+	bot.store.Create(Showtime{ID: "other", Title: title, DateTime: time.Date(2025, 6, 14, 19, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+	bot.config.RowsPerMessage = 5
+	bot.handleShowtimeCommand(".showtime -list", "carol")
+	bot.handleShowtimeCommand(".showtime -list -compact", "carol")
+	bot.handleShowtimeCommand(`.showtime -info="movie"`, "carol")
+
+	if len(capture.messages) < 6 {
+		t.Fatalf("expected the replies to be split, got %v", capture.messages)
+	}
+	for _, message := range capture.messages {
+		if line := ":" + strings.Repeat("n", maxSourcePrefixBytes-2) + " PRIVMSG " + bot.config.Channel + " :" + message + "\r\n"; len(line) > 512 {
+			t.Errorf("relayed line of %d bytes exceeds 512: %q", len(line), message)
+		}
+	}
+	if first := strings.Join(capture.messages[:2], " "); first != "Created showtime: [movie] "+title+" - 2025-06-13 19:00:00 UTC" {
+		t.Errorf("expected the confirmation split between words, got %q", first)
+	}
+}
+
+func TestSendWrapped(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.sendWrapped("short   and  sweet")
+	if !equalStringSlices(sender.messages, []string{"short and sweet"}) {
+		t.Errorf("expected one collapsed line, got %v", sender.messages)
+	}
+
+	sender.messages = nil
+	note := strings.Repeat("naïve déjà-vu ", 60)
+	bot.sendWrapped(note)
+	if len(sender.messages) < 2 {
+		t.Fatalf("expected the note to wrap, got %v", sender.messages)
+	}
+	var words []string
+	for i, line := range sender.messages {
+		if len(line) > maxMessageBytes {
+			t.Errorf("line %d is %d bytes", i, len(line))
+		}
+		if !utf8.ValidString(line) {
+			t.Errorf("line %d is not valid UTF-8", i)
+		}
+		if i > 0 {
+			if !strings.HasPrefix(line, wrapIndent) {
+				t.Errorf("expected continuation line %d to be indented, got %q", i, line)
+			}
+			line = strings.TrimPrefix(line, wrapIndent)
+		}
+		words = append(words, strings.Fields(line)...)
+	}
+	if joined := strings.Join(words, " "); joined != strings.TrimSpace(note) {
+		t.Errorf("expected every word intact and in order, got %q", joined)
+	}
+}
+
+func TestBuildDatetime(t *testing.T) {
+	now := time.Date(2025, 6, 13, 12, 30, 45, 0, time.UTC)
+	tests := []struct {
+		name     string
+		args     []string
+		expected time.Time
+		wantErr  bool
+	}{
+		{"iso seconds", []string{`-date=2025-07-02 15:04:05`}, time.Date(2025, 7, 2, 15, 4, 5, 0, time.UTC), false},
+		{"iso minutes", []string{`-date=2025-07-02 15:04`}, time.Date(2025, 7, 2, 15, 4, 0, 0, time.UTC), false},
+		{"us seconds", []string{`-date=07-02-2025 15:04:05`}, time.Date(2025, 7, 2, 15, 4, 5, 0, time.UTC), false},
+		{"us minutes", []string{`-date=07-02-2025 15:04`}, time.Date(2025, 7, 2, 15, 4, 0, 0, time.UTC), false},
+		{"slash seconds", []string{`-date=2025/07/02 15:04:05`}, time.Date(2025, 7, 2, 15, 4, 5, 0, time.UTC), false},
+		{"slash minutes", []string{`-date=2025/07/02 15:04`}, time.Date(2025, 7, 2, 15, 4, 0, 0, time.UTC), false},
+		{"quoted date", []string{`-date="2025-07-02 15:04"`}, time.Date(2025, 7, 2, 15, 4, 0, 0, time.UTC), false},
+		{"unknown date format", []string{`-date=July 2nd`}, time.Time{}, true},
+		{"all components", []string{"-year=2025", "-month=6", "-day=20", "-hours=19", "-minutes=15", "-seconds=30"}, time.Date(2025, 6, 20, 19, 15, 30, 0, time.UTC), false},
+		{"singular component flags", []string{"-day=20", "-hour=19", "-minute=15", "-second=30"}, time.Date(2025, 6, 20, 19, 15, 30, 0, time.UTC), false},
+		{"sec alias", []string{"-sec=9"}, time.Date(2025, 6, 13, 0, 0, 9, 0, time.UTC), false},
+		{"defaults to today at midnight", nil, time.Date(2025, 6, 13, 0, 0, 0, 0, time.UTC), false},
+		{"time only uses today", []string{"-hours=21"}, time.Date(2025, 6, 13, 21, 0, 0, 0, time.UTC), false},
+		{"ignores unrelated flags", []string{"-id=x", "-title=y", "-hours=20"}, time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC), false},
+		{"leap day", []string{"-year=2024", "-month=2", "-day=29"}, time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC), false},
+		{"non leap day", []string{"-year=2025", "-month=2", "-day=29"}, time.Time{}, true},
+		{"april 31st", []string{"-month=4", "-day=31"}, time.Time{}, true},
+		{"hour out of range", []string{"-hours=24"}, time.Time{}, true},
+		{"minute out of range", []string{"-minutes=60"}, time.Time{}, true},
+		{"second not a number", []string{"-seconds=abc"}, time.Time{}, true},
+		{"month out of range", []string{"-month=13"}, time.Time{}, true},
+		{"day out of range", []string{"-day=0"}, time.Time{}, true},
+		{"year out of range", []string{"-year=1899"}, time.Time{}, true},
+		{"at later today", []string{"-at=20:30"}, time.Date(2025, 6, 13, 20, 30, 0, 0, time.UTC), false},
+		{"at quoted", []string{`-at="13:00"`}, time.Date(2025, 6, 13, 13, 0, 0, 0, time.UTC), false},
+		{"at already passed rolls to tomorrow", []string{"-at=09:15"}, time.Date(2025, 6, 14, 9, 15, 0, 0, time.UTC), false},
+		{"at current minute rolls to tomorrow", []string{"-at=12:30"}, time.Date(2025, 6, 14, 12, 30, 0, 0, time.UTC), false},
+		{"at invalid", []string{"-at=8pm"}, time.Time{}, true},
+		{"at out of range", []string{"-at=25:00"}, time.Time{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := buildDatetime(tt.args, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if !got.Equal(tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParseTimeOfDay_RollsOverMonthEnd(t *testing.T) {
+	now := time.Date(2025, 6, 30, 23, 50, 0, 0, time.UTC)
+	got, err := parseTimeOfDay("00:10", now)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expected := time.Date(2025, 7, 1, 0, 10, 0, 0, time.UTC); !got.Equal(expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestHandleNextMovieCommand_Current(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "now", Title: "Casablanca", DateTime: time.Now().UTC().Add(-90 * time.Minute)})
+	bot.store.Create(Showtime{ID: "later", Title: "Vertigo", DateTime: time.Now().UTC().Add(2 * time.Hour)})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	expected := []string{"1 hour, 30 minutes into Casablanca, then Vertigo in 2 hours"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_CurrentWindow(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.CurrentWindowHours = 1
+	bot.store.Create(Showtime{ID: "now", Title: "Casablanca", DateTime: time.Now().UTC().Add(-90 * time.Minute)})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	expected := []string{"No movies scheduled!"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_JustStarted(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "now", Title: "Casablanca", DateTime: time.Now().UTC().Add(-10 * time.Second)})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	expected := []string{"Casablanca just started!"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_Next(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "old", Title: "Metropolis", DateTime: time.Now().UTC().Add(-5 * time.Hour)})
+	bot.store.Create(Showtime{ID: "later", Title: "Vertigo", DateTime: time.Now().UTC().Add(2*time.Hour + 30*time.Minute)})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	expected := []string{"In 2 hours, 30 minutes, Vertigo is playing!"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_Following(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Now().UTC()
+	bot.store.Create(Showtime{ID: "first", Title: "Vertigo", DateTime: now.Add(time.Hour + 30*time.Second)})
+	bot.store.Create(Showtime{ID: "second", Title: "Psycho", DateTime: now.Add(3*time.Hour + 30*time.Second)})
+	bot.store.Create(Showtime{ID: "third", Title: "Rebecca", DateTime: now.Add(5 * time.Hour)})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+	bot.store.Create(Showtime{ID: "playing", Title: "Casablanca", DateTime: now.Add(-10 * time.Second)})
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	expected := []string{
+		"In 1 hour, Vertigo is playing! Then Psycho in 3 hours.",
+		"Casablanca just started! Then Vertigo in 1 hour.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_StartingSoon(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "soon", Title: "Vertigo", DateTime: time.Now().UTC().Add(10*time.Minute + 30*time.Second)})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+	bot.config.SoonThresholdMinutes = 15
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+	bot.config.SoonThresholdMinutes = 5
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	expected := []string{
+		"In 10 minutes, 30 seconds, Vertigo is playing!",
+		"\x02Starting soon!\x02 In 10 minutes, 30 seconds, Vertigo is playing!",
+		"In 10 minutes, 30 seconds, Vertigo is playing!",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %q, got %q", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_ByID(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Now().UTC()
+	bot.store.Create(Showtime{ID: "soon", Title: "Casablanca", DateTime: now.Add(time.Hour + 30*time.Second)})
+	bot.store.Create(Showtime{ID: "later", Title: "Vertigo", DateTime: now.Add(3*24*time.Hour + 30*time.Second)})
+	bot.store.Create(Showtime{ID: "playing", Title: "Psycho", DateTime: now.Add(-45 * time.Minute)})
+	bot.store.Create(Showtime{ID: "old", Title: "Metropolis", DateTime: now.Add(-2 * 24 * time.Hour)})
+
+	for _, id := range []string{"later", "playing", "old", "missing"} {
+		bot.handleNextMovieCommand([]string{".nextmovie", id})
+	}
+
+	expected := []string{
+		"In 3 days, Vertigo is playing!",
+		"45 minutes into Psycho",
+		"Metropolis already played (2 days ago).",
+		"Showtime with ID 'missing' not found.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_SkipsCancelled(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Now().UTC()
+	bot.store.Create(Showtime{ID: "first", Title: "Vertigo", DateTime: now.Add(time.Hour + 30*time.Second), Cancelled: true})
+	bot.store.Create(Showtime{ID: "second", Title: "Psycho", DateTime: now.Add(3*time.Hour + 30*time.Second)})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+	bot.config.NextMovieMentionCancelled = true
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+	bot.handleNextMovieCommand([]string{".nextmovie", "first"})
+
+	expected := []string{
+		"In 3 hours, Psycho is playing!",
+		"In 3 hours, Psycho is playing! (Vertigo was cancelled.)",
+		fmt.Sprintf("Vertigo (%s) was cancelled.", bot.formatTime(now.Add(time.Hour+30*time.Second))),
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_JustEnded(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Now().UTC()
+	bot.store.Create(Showtime{ID: "short", Title: "Rope", DateTime: now.Add(-85 * time.Minute), RuntimeMinutes: 80})
+	bot.store.Create(Showtime{ID: "later", Title: "Vertigo", DateTime: now.Add(2*time.Hour + 30*time.Second)})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+	bot.handleNextMovieCommand([]string{".nextmovie", "short"})
+
+	expected := []string{
+		"In 2 hours, Vertigo is playing!",
+		"Rope already played (1 hour, 25 minutes ago).",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_LongRuntime(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "epic", Title: "Lawrence of Arabia", DateTime: time.Now().UTC().Add(-200 * time.Minute), RuntimeMinutes: 228})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	expected := []string{"3 hours, 20 minutes into Lawrence of Arabia"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleNextMovieCommand_Empty(t *testing.T) {
+	bot, sender := newTestBot()
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	expected := []string{"No movies scheduled!"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleShowtimeCommand_Soonest(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "now", Title: "Casablanca", DateTime: time.Now().UTC().Add(-90 * time.Minute)})
+	bot.store.Create(Showtime{ID: "later", Title: "Vertigo", DateTime: time.Now().UTC().Add(2 * time.Hour)})
+
+	bot.handleShowtimeCommand(".showtime -soonest", "alice")
+
+	expected := []string{"In 2 hours, Vertigo is playing!"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestBriefWhen(t *testing.T) {
+	bot := newConfigCommand(Config{})
+	now := time.Date(2025, 6, 13, 10, 0, 0, 0, time.UTC) // a Friday
+	tests := []struct {
+		t        time.Time
+		expected string
+	}{
+		{now.Add(45 * time.Minute), "45m"},
+		{now.Add(2 * time.Hour), "2h"},
+		{now.Add(11 * time.Hour), "11h"},
+		{time.Date(2025, 6, 14, 20, 0, 0, 0, time.UTC), "tomorrow"},
+		{time.Date(2025, 6, 17, 20, 0, 0, 0, time.UTC), "Tue"},
+		{time.Date(2025, 6, 20, 20, 0, 0, 0, time.UTC), "Jun 20"},
+	}
+	for _, tt := range tests {
+		if got := bot.briefWhen(tt.t, now); got != tt.expected {
+			t.Errorf("briefWhen(%v): expected %q, got %q", tt.t, tt.expected, got)
+		}
+	}
+}
+
+func TestBriefShowtimes(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 10, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "old", Title: "Metropolis", DateTime: now.Add(-time.Hour)})
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: now.Add(2 * time.Hour)})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: now.Add(30 * time.Hour)})
+
+	bot.briefShowtimes(now)
+
+	expected := []string{"Soon: Casablanca (2h), Vertigo (tomorrow)"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestBriefShowtimes_Chunked(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 40; i++ {
+		bot.store.Create(Showtime{ID: fmt.Sprint(i), Title: strings.Repeat("x", 20), DateTime: now.Add(time.Duration(i+1) * time.Hour)})
+	}
+
+	bot.briefShowtimes(now)
+
+	if len(sender.messages) < 2 {
+		t.Fatalf("expected output to be chunked, got %d messages", len(sender.messages))
+	}
+	for _, msg := range sender.messages {
+		if len(msg) > maxMessageBytes {
+			t.Errorf("message exceeds %d bytes: %d", maxMessageBytes, len(msg))
+		}
+	}
+	if !strings.HasPrefix(sender.messages[0], "Soon: ") {
+		t.Errorf("expected first message to start with Soon:, got %s", sender.messages[0])
+	}
+}
+
+func TestListShowtimes_Empty(t *testing.T) {
+	bot, sender := newTestBot()
+
+	bot.listShowtimes(listOptions{}, "")
+
+	expected := []string{"No showtimes scheduled."}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestListShowtimes(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: time.Date(2025, 6, 14, 20, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.listShowtimes(listOptions{}, "")
+
+	expected := []string{
+		"Scheduled showtimes:",
+		"⏮ [a] Casablanca - 2025-06-13 19:00:00 UTC (by alice)",
+		"⏮ [b] Vertigo - 2025-06-14 20:00:00 UTC (by bob)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestListShowtimes_Grouped(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: time.Date(2025, 6, 13, 22, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+	bot.store.Create(Showtime{ID: "c", Title: "Psycho", DateTime: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+
+	bot.handleShowtimeCommand(".showtime -list -grouped", "carol")
+
+	expected := []string{
+		"Scheduled showtimes:",
+		"— 2025-06-13 —",
+		"⏮ [a] Casablanca - 2025-06-13 19:00:00 UTC (by alice)",
+		"⏮ [b] Vertigo - 2025-06-13 22:00:00 UTC (by bob)",
+		"— 2025-06-15 —",
+		"⏮ [c] Psycho - 2025-06-15 20:00:00 UTC (by bob)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestListShowtimes_DM(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(".showtime -list -dm", "carol")
+	bot.handleShowtimeCommand(".showtime -list -dm -compact", "carol")
+
+	expected := []string{
+		"Scheduled showtimes:",
+		"⏮ [a] Casablanca - 2025-06-13 19:00:00 UTC (by alice)",
+		"a:Casablanca@06-13 19:00",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	for _, target := range sender.targets {
+		if target != "carol" {
+			t.Errorf("expected every message to go to carol, got one for %s", target)
+		}
+	}
+}
+
+func TestListShowtimes_MaxEntries(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.MaxListEntries = 2
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: time.Date(2025, 6, 14, 20, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+	bot.store.Create(Showtime{ID: "c", Title: "Psycho", DateTime: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+
+	bot.handleShowtimeCommand(".showtime -list", "carol")
+
+	expected := []string{
+		"Scheduled showtimes:",
+		"⏮ [a] Casablanca - 2025-06-13 19:00:00 UTC (by alice)",
+		"⏮ [b] Vertigo - 2025-06-14 20:00:00 UTC (by bob)",
+		"…and 1 more. Narrow the list with -from, -to or -tag, or add -dm to get all of it privately.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+
+	sender.messages = nil
+	bot.handleShowtimeCommand(".showtime -list -format=json", "carol")
+	for _, message := range sender.messages {
+		if !json.Valid([]byte(message)) {
+			t.Errorf("expected every JSON list message to be valid JSON, got %q", message)
+		}
+	}
+	if last := sender.messages[len(sender.messages)-1]; last != `{"more":1}` {
+		t.Errorf("expected the trailer as a JSON object, got %q", last)
+	}
+
+	sender.messages = nil
+	bot.handleShowtimeCommand(".showtime -list -from=2025-06-13", "carol")
+	bot.handleShowtimeCommand(".showtime -list -dm", "carol")
+	if len(sender.messages) != 8 {
+		t.Errorf("expected filtered and -dm lists to be complete, got %v", sender.messages)
+	}
+}
+
+func TestListShowtimesCompact(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	bot.listShowtimesCompact(bot.config.Channel, []Showtime{
+		{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)},
+		{ID: "b", Title: "Vertigo", DateTime: time.Date(2025, 6, 13, 22, 0, 0, 0, time.UTC)},
+		{ID: "c", Title: "Psycho", DateTime: time.Date(2025, 6, 15, 20, 0, 0, 0, time.UTC)},
+		{ID: "d", Title: "Rebecca", DateTime: time.Date(2025, 6, 15, 22, 30, 0, 0, time.UTC)},
+	}, now)
+
+	expected := []string{"a:Casablanca@19:00 | b:Vertigo@22:00 | c:Psycho@06-15 20:00 | d:Rebecca@22:30"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+
+	sender.messages = nil
+	var showtimes []Showtime
+	for i := 0; i < 40; i++ {
+		showtimes = append(showtimes, Showtime{ID: fmt.Sprintf("movie%d", i), Title: "The Cabinet of Dr. Caligari", DateTime: now.Add(time.Duration(i) * time.Hour)})
+	}
+	bot.listShowtimesCompact(bot.config.Channel, showtimes, now)
+	if len(sender.messages) < 2 || len(sender.messages) > 5 {
+		t.Errorf("expected 40 entries packed into a few lines, got %d", len(sender.messages))
+	}
+	for _, message := range sender.messages {
+		if len(message) > maxMessageBytes {
+			t.Errorf("message of %d bytes exceeds the limit", len(message))
+		}
+	}
+}
+
+func TestListShowtimes_CompactDefault(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.CompactList = true
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(".showtime -list", "carol")
+	bot.handleShowtimeCommand(".showtime -list -detailed", "carol")
+
+	expected := []string{
+		"a:Casablanca@06-13 19:00",
+		"Scheduled showtimes:",
+		"⏮ [a] Casablanca - 2025-06-13 19:00:00 UTC (by alice)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestListShowtimes_Full(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Now().UTC()
+	start := now.Add(3*time.Hour + 30*time.Second)
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: start, CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: now.Add(-26 * time.Hour), CreatedBy: "bob"})
+
+	bot.handleShowtimeCommand(".showtime -list -full", "carol")
+
+	expected := []string{
+		"Scheduled showtimes:",
+		fmt.Sprintf("⏮ [b] Vertigo - %s (1 day, 2 hours ago) (by bob)", bot.formatTime(now.Add(-26*time.Hour))),
+		fmt.Sprintf("⏭ [a] Casablanca - %s (in 3 hours) (by alice)", bot.formatTime(start)),
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestStatusIndicator(t *testing.T) {
+	now := time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC)
+	tests := []struct {
+		start   time.Time
+		runtime int
+		emoji   string
+		plain   string
+	}{
+		{now.Add(time.Hour), 0, "⏭", "[soon]"},
+		{now, 0, "▶", "[live]"},
+		{now.Add(-2 * time.Hour), 0, "▶", "[live]"},
+		{now.Add(-defaultCurrentWindowHours * time.Hour), 0, "⏮", "[past]"},
+		{now.Add(-24 * time.Hour), 0, "⏮", "[past]"},
+		{now.Add(-2 * time.Hour), 90, "⏮", "[past]"},
+		{now.Add(-4 * time.Hour), 300, "▶", "[live]"},
+	}
+	for _, tt := range tests {
+		showtime := Showtime{DateTime: tt.start, RuntimeMinutes: tt.runtime}
+		bot, _ := newTestBot()
+		if got := bot.statusIndicator(showtime, now); got != tt.emoji {
+			t.Errorf("start %v: expected %q, got %q", tt.start.Sub(now), tt.emoji, got)
+		}
+		bot.config.PlainIndicators = true
+		if got := bot.statusIndicator(showtime, now); got != tt.plain {
+			t.Errorf("start %v: expected %q, got %q", tt.start.Sub(now), tt.plain, got)
+		}
+	}
+}
+
+func TestListShowtimes_DisplayTimezone(t *testing.T) {
+	bot, sender := newTestBot()
+	location, err := time.LoadLocation("Asia/Tokyo")
+	if err != nil {
+		t.Fatalf("failed to load location: %v", err)
+	}
+	bot.config.location = location
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.listShowtimes(listOptions{grouped: true}, "")
+
+	expected := []string{
+		"Scheduled showtimes:",
+		"— 2025-06-14 —",
+		"⏮ [a] Casablanca - 2025-06-14 04:00:00 JST (by alice)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestListShowtimes_DateRange(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 5, 31, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: time.Date(2025, 6, 7, 22, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+	bot.store.Create(Showtime{ID: "c", Title: "Psycho", DateTime: time.Date(2025, 6, 8, 20, 0, 0, 0, time.UTC), CreatedBy: "bob"})
+
+	bot.handleShowtimeCommand(".showtime -list -from=2025-06-01 -to=2025-06-07", "alice")
+
+	expected := []string{
+		"Scheduled showtimes:",
+		"⏮ [b] Vertigo - 2025-06-07 22:00:00 UTC (by bob)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestCloneWeek(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "past", Title: "Metropolis", DateTime: now.Add(-time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "fri", Title: "Casablanca", DateTime: now.Add(7 * time.Hour), CreatedBy: "alice", PosterURL: "https://example.com/c.jpg"})
+	bot.store.Create(Showtime{ID: "tue", Title: "Vertigo", DateTime: now.Add(4 * 24 * time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "tue-0624", Title: "Already cloned", DateTime: now.Add(11 * 24 * time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "far", Title: "Psycho", DateTime: now.Add(7 * 24 * time.Hour), CreatedBy: "alice"})
+
+	bot.cloneWeek(now, "bob")
+
+	if expected := "Cloned 1 showtime to next week. Skipped 1 with existing ids."; len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+	clone, _ := bot.store.GetByID("fri-0620")
+	if clone == nil {
+		t.Fatal("expected fri-0620 to be created")
+	}
+	if !clone.DateTime.Equal(now.Add(7*time.Hour+7*24*time.Hour)) || clone.Title != "Casablanca" || clone.CreatedBy != "bob" || clone.PosterURL == "" {
+		t.Errorf("unexpected clone %+v", clone)
+	}
+	if existing, _ := bot.store.GetByID("tue-0624"); existing.Title != "Already cloned" {
+		t.Errorf("expected existing showtime to be left alone, got %+v", existing)
+	}
+	if showtimes, _ := bot.store.List(ShowtimeFilter{}); len(showtimes) != 6 {
+		t.Errorf("expected 6 showtimes, got %d", len(showtimes))
+	}
+}
+
+func TestCloneWeek_IDs(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	long := strings.Repeat("x", defaultMaxIDLength)
+	bot.store.Create(Showtime{ID: "fri-0613", Title: "Casablanca", DateTime: now.Add(7 * time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: long, Title: "Vertigo", DateTime: now.Add(24 * time.Hour), CreatedBy: "alice"})
+
+	bot.cloneWeek(now, "bob")
+
+	if expected := "Cloned 2 showtimes to next week."; len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+	if clone, _ := bot.store.GetByID("fri-0620"); clone == nil {
+		t.Error("expected a clone of a clone to replace its date suffix")
+	}
+	if clone, _ := bot.store.GetByID(long[:defaultMaxIDLength-5] + "-0621"); clone == nil {
+		t.Errorf("expected the clone id to fit within max_id_length, got %v", bot.store.(*memoryStore).showtimes)
+	}
+}
+
+func TestCloneWeek_Empty(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.cloneWeek(time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC), "bob")
+	if expected := "No showtimes in the next 7 days to clone."; len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+}
+
+func TestCreateShowtime_Link(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=plain -title=Casablanca -date="2025-06-13 19:00"`), "alice")
+	bot.config.PublicBaseURL = "https://cinema.example.com/"
+	// Ids needing escaping only get through a looser id_pattern
+	bot.config.IDPattern = "^[a-z ]+$"
+	bot.config.idPattern = regexp.MustCompile(bot.config.IDPattern)
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id="sat night" -title=Vertigo -date="2025-06-14 19:00"`), "alice")
+
+	expected := []string{
+		"Created showtime: [plain] Casablanca - 2025-06-13 19:00:00 UTC",
+		"Created showtime: [sat night] Vertigo - 2025-06-14 19:00:00 UTC - https://cinema.example.com/showtimes.html#sat%20night",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestChainedStart(t *testing.T) {
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	tests := []struct {
+		runtime  time.Duration
+		gap      time.Duration
+		expected time.Time
+	}{
+		{102 * time.Minute, 15 * time.Minute, time.Date(2025, 6, 13, 20, 57, 0, 0, time.UTC)},
+		{90 * time.Minute, 0, time.Date(2025, 6, 13, 20, 30, 0, 0, time.UTC)},
+		{4*time.Hour + 30*time.Minute, 45 * time.Minute, time.Date(2025, 6, 14, 0, 15, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := chainedStart(start, tt.runtime, tt.gap); !got.Equal(tt.expected) {
+			t.Errorf("runtime %v gap %v: expected %v, got %v", tt.runtime, tt.gap, tt.expected, got)
+		}
+	}
+}
+
+func TestCreateShowtime_After(t *testing.T) {
+	bot, sender := newTestBot()
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "first", Title: "Casablanca", DateTime: start, CreatedBy: "alice", RuntimeMinutes: 102})
+	bot.store.Create(Showtime{ID: "cached", Title: "Vertigo", DateTime: start, CreatedBy: "alice"})
+	bot.store.CacheMovieInfo("Vertigo", MovieInfo{Runtime: "128 min"})
+	bot.store.Create(Showtime{ID: "unknown", Title: "Psycho", DateTime: start, CreatedBy: "alice"})
+
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=second -title="Key Largo" -after=first -gap=15m`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=third -title="Rear Window" -after=cached`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=fourth -title=Rebecca -after=unknown -gap=15m`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=fifth -title=Rebecca -after=first -gap=soon`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=sixth -title=Rebecca -after=missing`), "alice")
+
+	expected := []string{
+		"Created showtime: [second] Key Largo - 2025-06-13 20:57:00 UTC",
+		"Created showtime: [third] Rear Window - 2025-06-13 21:08:00 UTC",
+		`[unknown] Psycho has no runtime to schedule after. Set one with .showtime -set-runtime="unknown" -runtime=minutes.`,
+		"Invalid -gap 'soon' (e.g. 15m, 1h).",
+		"Showtime with ID 'missing' not found.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestCreateShowtime_DuplicateTitle(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.DuplicateTitleCheck = true
+	bot.store.Create(Showtime{ID: "first", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 1, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=again -title=casablanca -date="2025-06-13 23:00"`), "bob")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=tomorrow -title=Casablanca -date="2025-06-14 01:00"`), "bob")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=again -title=casablanca -date="2025-06-13 23:00" -force`), "bob")
+
+	expected := []string{
+		"[first] Casablanca is already scheduled on 2025-06-13. Add -force to create it anyway.",
+		"Created showtime: [tomorrow] Casablanca - 2025-06-14 01:00:00 UTC",
+		"Created showtime: [again] casablanca - 2025-06-13 23:00:00 UTC",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleTitlesCommand(t *testing.T) {
+	bot, sender := newTestBot()
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	for i, title := range []string{"Vertigo", "Casablanca", "Psycho", "Vertigo"} {
+		bot.store.Create(Showtime{ID: fmt.Sprint(i), Title: title, DateTime: start, CreatedBy: "alice"})
+	}
+
+	bot.handleTitlesCommand([]string{".titles"})
+	bot.handleTitlesCommand([]string{".titles", "-search=CHO"})
+	bot.handleTitlesCommand([]string{".titles", "-search=Solaris"})
+
+	expected := []string{
+		"3 titles scheduled: Casablanca, Psycho, Vertigo",
+		"1 title matching 'CHO': Psycho",
+		"0 titles matching 'Solaris'.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestNormalizeTags(t *testing.T) {
+	if got, expected := normalizeTags(" Horror, classics,,HORROR , b-movie"), []string{"horror", "classics", "b-movie"}; !equalStringSlices(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+	if got := normalizeTags(""); len(got) != 0 {
+		t.Errorf("expected no tags, got %v", got)
+	}
+}
+
+func TestShowtimeTags(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=a -title=Psycho -date="2025-06-13 19:00" -tags="Horror, Classics"`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=b -title=Casablanca -date="2025-06-14 19:00" -tags=classics`), "alice")
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=c -title=Alien -date="2025-06-15 19:00"`), "alice")
+
+	sender.messages = nil
+	bot.showtimeInfo([]string{".showtime", "-info=a"}, false)
+	bot.handleShowtimeCommand(`.showtime -list -tag="HORROR"`, "carol")
+
+	expected := []string{
+		"[a] Psycho - 2025-06-13 19:00:00 UTC (by alice) | Tags: horror, classics",
+		"Scheduled showtimes:",
+		"⏮ [a] Psycho - 2025-06-13 19:00:00 UTC (by alice)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestLongestGap(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "past", Title: "Alien", DateTime: now.AddDate(0, 0, -30), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 2, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.longestGap(now)
+
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: time.Date(2025, 6, 4, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "c", Title: "Psycho", DateTime: time.Date(2025, 6, 16, 22, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "d", Title: "Rebecca", DateTime: time.Date(2025, 6, 17, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.longestGap(now)
+
+	expected := []string{
+		"Need at least two upcoming showtimes to find a gap.",
+		"Longest gap: 1 week, 5 days between [b] Vertigo (2025-06-04 19:00:00 UTC) and [c] Psycho (2025-06-16 22:00:00 UTC).",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestAdminNote_OnlyInAuthorizedInfo(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=movie -title=Casablanca -date="2025-06-13 19:00" -note="waiting on licensing"`), "alice")
+
+	showtime, _ := bot.store.GetByID("movie")
+	if showtime == nil || showtime.AdminNote != "waiting on licensing" {
+		t.Fatalf("expected note to be stored, got %+v", showtime)
+	}
+
+	sender.messages = nil
+	bot.showtimeInfo([]string{".showtime", "-info=movie"}, true)
+	bot.showtimeInfo([]string{".showtime", "-info=movie"}, false)
+	bot.listShowtimes(listOptions{}, "")
+
+	base := "[movie] Casablanca - 2025-06-13 19:00:00 UTC (by alice)"
+	if sender.messages[0] != base+" | Note: waiting on licensing" {
+		t.Errorf("expected note for admins, got %q", sender.messages[0])
+	}
+	if sender.messages[1] != base {
+		t.Errorf("expected no note, got %q", sender.messages[1])
+	}
+	for _, message := range sender.messages[2:] {
+		if strings.Contains(message, "licensing") {
+			t.Errorf("expected note to stay out of the list, got %q", message)
+		}
+	}
+}
+
+func TestConfigureKeepAlive(t *testing.T) {
+	bot := &CinemaBot{conn: irc.IRC("bot", "bot")}
+	defaultKeepAlive, defaultPingFreq := bot.conn.KeepAlive, bot.conn.PingFreq
+
+	bot.configureKeepAlive()
+	if bot.conn.KeepAlive != defaultKeepAlive || bot.conn.PingFreq != defaultPingFreq {
+		t.Errorf("expected library defaults without keepalive_seconds, got %v/%v", bot.conn.KeepAlive, bot.conn.PingFreq)
+	}
+
+	bot.config.KeepAliveSeconds = 90
+	bot.configureKeepAlive()
+	if bot.conn.KeepAlive != 90*time.Second || bot.conn.PingFreq != 90*time.Second {
+		t.Errorf("expected 90s keepalive, got %v/%v", bot.conn.KeepAlive, bot.conn.PingFreq)
+	}
+}
+
+func TestIdentifyMessage(t *testing.T) {
+	bot := &CinemaBot{config: Config{Nick: "cinemabot"}}
+	bot.config.NickServ.Password = "secret"
+
+	if target, message := bot.identifyMessage(); target != "NickServ" || message != "IDENTIFY secret" {
+		t.Errorf("expected default NickServ IDENTIFY, got %s %q", target, message)
+	}
+
+	bot.config.NickServ.IdentifyCommand = "IDENTIFY {nick} {password}"
+	if target, message := bot.identifyMessage(); target != "NickServ" || message != "IDENTIFY cinemabot secret" {
+		t.Errorf("expected IDENTIFY with nick, got %s %q", target, message)
+	}
+
+	bot.config.NickServ.Service = "Q@CServe.quakenet.org"
+	bot.config.NickServ.IdentifyCommand = "AUTH {nick} {password}"
+	if target, message := bot.identifyMessage(); target != "Q@CServe.quakenet.org" || message != "AUTH cinemabot secret" {
+		t.Errorf("expected QuakeNet AUTH, got %s %q", target, message)
+	}
+}
+
+func TestAnnounceJoin(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.announceJoin()
+	if len(sender.messages) != 0 {
+		t.Errorf("expected silence without join_message, got %v", sender.messages)
+	}
+
+	bot.config.JoinMessage = "CinemaBot online — .nextmovie for what's playing"
+	bot.announceJoin()
+	if !equalStringSlices(sender.messages, []string{bot.config.JoinMessage}) || sender.targets[0] != "#testchan" {
+		t.Errorf("expected join message in #testchan, got %v to %v", sender.messages, sender.targets)
+	}
+}
+
+func TestHandleWhatPlayedCommand(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.CurrentWindowHours = defaultCurrentWindowHours
+	bot.store.Create(Showtime{ID: "short", Title: "Casablanca", DateTime: time.Date(2025, 6, 7, 19, 0, 0, 0, time.UTC)})
+	bot.store.CacheMovieInfo("Casablanca", MovieInfo{Runtime: "102 min"})
+	bot.store.Create(Showtime{ID: "unknown", Title: "Vertigo", DateTime: time.Date(2025, 6, 8, 19, 0, 0, 0, time.UTC)})
+
+	for _, args := range [][]string{
+		{".whatplayed", "2025-06-07", "20:00"},
+		{".whatplayed", "2025-06-07 20:45"},
+		{".whatplayed", "2025-06-08", "21:30"},
+		{".whatplayed", "2025-06-08", "18:59"},
+		{".whatplayed", "last", "Saturday"},
+	} {
+		bot.handleWhatPlayedCommand(args)
+	}
+
+	expected := []string{
+		"At 2025-06-07 20:00:00 UTC: [short] Casablanca (started 2025-06-07 19:00:00 UTC)",
+		"Nothing was playing at 2025-06-07 20:45:00 UTC.",
+		"At 2025-06-08 21:30:00 UTC: [unknown] Vertigo (started 2025-06-08 19:00:00 UTC)",
+		"Nothing was playing at 2025-06-08 18:59:00 UTC.",
+		"Invalid date format. Supported formats: 2006-01-02 15:04:05, 01-02-2006 15:04:05, 2006/01/02 15:04:05",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestReassignShowtime(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(`.showtime -reassign="movie" -to="bob"`, "admin")
+
+	if expected := "Reassigned [movie] Casablanca from alice to bob."; len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+	if showtime, _ := bot.store.GetByID("movie"); showtime.CreatedBy != "bob" {
+		t.Errorf("expected bob to own the showtime, got %s", showtime.CreatedBy)
+	}
+	audit := bot.store.(*memoryStore).audit
+	if len(audit) != 1 || audit[0].Actor != "admin" || audit[0].Action != "reassign" || audit[0].ShowtimeID != "movie" || audit[0].Details != "created_by alice -> bob" {
+		t.Errorf("unexpected audit log %+v", audit)
+	}
+
+	// The new owner can now delete it
+	bot.handleShowtimeCommand(`.showtime -delete="movie"`, "bob")
+	if showtime, _ := bot.store.GetByID("movie"); showtime != nil {
+		t.Errorf("expected bob to be able to delete, got %+v", showtime)
+	}
+}
+
+func TestReassignShowtime_Invalid(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.handleShowtimeCommand(`.showtime -reassign="movie"`, "admin")
+	bot.handleShowtimeCommand(`.showtime -reassign="missing" -to="bob"`, "admin")
+	bot.store.Create(Showtime{ID: "psycho", Title: "Psycho", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	bot.handleShowtimeCommand(`.showtime -reassign="psychos" -to="bob"`, "admin")
+
+	expected := []string{
+		`Usage: .showtime -reassign="id" -to="nick"`,
+		"Showtime with ID 'missing' not found.",
+		"Showtime with ID 'psychos' not found. Did you mean 'psycho'?",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	if len(bot.store.(*memoryStore).audit) != 0 {
+		t.Error("expected nothing to be audited")
+	}
+}
+
+func TestSetRuntime(t *testing.T) {
+	bot, sender := newTestBot()
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: start, CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "b", Title: "casablanca", DateTime: start.AddDate(0, 0, 7), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "c", Title: "Vertigo", DateTime: start, CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(`.showtime -set-runtime="Casablanca" -runtime=102`, "admin")
+	bot.handleShowtimeCommand(`.showtime -set-runtime=c -runtime=128`, "admin")
+	bot.handleShowtimeCommand(`.showtime -set-runtime=Solaris -runtime=167`, "admin")
+	bot.handleShowtimeCommand(`.showtime -set-runtime=cc -runtime=167`, "admin")
+	bot.handleShowtimeCommand(`.showtime -set-runtime=c -runtime=long`, "admin")
+
+	expected := []string{
+		"Set the runtime of 2 showtimes to 102 minutes.",
+		"Set the runtime of 1 showtime to 128 minutes.",
+		"Showtime with ID 'Solaris' not found.",
+		"Showtime with ID 'cc' not found. Did you mean 'c'?",
+		`Usage: .showtime -set-runtime="id or title" -runtime=minutes`,
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	for id, minutes := range map[string]int{"a": 102, "b": 102, "c": 128} {
+		if showtime, _ := bot.store.GetByID(id); showtime.RuntimeMinutes != minutes {
+			t.Errorf("expected %s to run %d minutes, got %d", id, minutes, showtime.RuntimeMinutes)
+		}
+	}
+	if audit := bot.store.(*memoryStore).audit; len(audit) != 2 || audit[0].Details != "runtime_minutes = 102 on 2 row(s)" {
+		t.Errorf("unexpected audit log %+v", audit)
+	}
+
+	// A stored runtime decides how long the showtime counts as playing
+	showtime, _ := bot.store.GetByID("c")
+	if got := bot.playingDuration(*showtime); got != 128*time.Minute {
+		t.Errorf("expected stored runtime to be used, got %v", got)
+	}
+}
+
+func TestRetzShowtime(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(`.showtime -retz="movie" -from=UTC -to="America/New_York"`, "admin")
+
+	expected := "[movie] Casablanca: read 2025-06-13 19:00 as America/New_York instead of UTC. Now 2025-06-13 23:00:00 UTC (was 2025-06-13 19:00:00 UTC)."
+	if len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+	if showtime, _ := bot.store.GetByID("movie"); !showtime.DateTime.Equal(time.Date(2025, 6, 13, 23, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected corrected time, got %v", showtime.DateTime)
+	}
+	audit := bot.store.(*memoryStore).audit
+	if len(audit) != 1 || audit[0].Action != "retz" || audit[0].Details != "2025-06-13 19:00 as UTC -> America/New_York: 2025-06-13T19:00:00Z -> 2025-06-13T23:00:00Z" {
+		t.Errorf("unexpected audit log %+v", audit)
+	}
+}
+
+func TestPruneAudit(t *testing.T) {
+	bot, _ := newTestBot()
+	now := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Audit(AuditEntry{At: now.AddDate(0, 0, -10), Actor: "admin", Action: "retz"})
+	bot.store.Audit(AuditEntry{At: now.AddDate(0, 0, -1), Actor: "admin", Action: "retz"})
+
+	bot.pruneAudit(now)
+	if audit := bot.store.(*memoryStore).audit; len(audit) != 2 {
+		t.Fatalf("expected nothing pruned while disabled, got %+v", audit)
+	}
+
+	bot.config.AuditRetentionDays = 7
+	bot.pruneAudit(now)
+	if audit := bot.store.(*memoryStore).audit; len(audit) != 1 || !audit[0].At.Equal(now.AddDate(0, 0, -1)) {
+		t.Errorf("unexpected audit log %+v", audit)
+	}
+}
+
+func TestRetzShowtime_Invalid(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(`.showtime -retz="movie" -from=UTC`, "admin")
+	bot.handleShowtimeCommand(`.showtime -retz="movie" -from=UTC -to=Mars/Olympus`, "admin")
+	bot.handleShowtimeCommand(`.showtime -retz="missing" -from=UTC -to=Europe/London`, "admin")
+	bot.handleShowtimeCommand(`.showtime -retz="movies" -from=UTC -to=Europe/London`, "admin")
+
+	expected := []string{
+		`Usage: .showtime -retz="id" -from=UTC -to="America/New_York"`,
+		"Unknown timezone 'Mars/Olympus'.",
+		"Showtime with ID 'missing' not found.",
+		"Showtime with ID 'movies' not found. Did you mean 'movie'?",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	if len(bot.store.(*memoryStore).audit) != 0 {
+		t.Error("expected nothing to be audited")
+	}
+}
+
+func TestWhoAdded(t *testing.T) {
+	bot, sender := newTestBot()
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "b", Title: "casablanca", DateTime: start, CreatedBy: "bob", CreatedAt: start.Add(-24 * time.Hour)})
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start.Add(-48 * time.Hour)})
+	bot.store.Create(Showtime{ID: "c", Title: "Return to Casablanca", DateTime: start, CreatedBy: "carol", CreatedAt: start})
+
+	bot.handleShowtimeCommand(`.showtime -whoadded="Casablanca"`, "admin")
+	bot.handleShowtimeCommand(`.showtime -whoadded="return" -like`, "admin")
+	bot.handleShowtimeCommand(`.showtime -whoadded="Vertigo"`, "admin")
+	bot.handleShowtimeCommand(`.showtime -whoadded`, "admin")
+
+	expected := []string{
+		"[a] Casablanca added by alice on 2025-06-11 19:00:00 UTC",
+		"[b] casablanca added by bob on 2025-06-12 19:00:00 UTC",
+		"[c] Return to Casablanca added by carol on 2025-06-13 19:00:00 UTC",
+		"No showtimes titled 'Vertigo'.",
+		`Usage: .showtime -whoadded="title" [-like]`,
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestRawShowtime(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(`.showtime -raw="movie"`, "admin")
+	location, _ := time.LoadLocation("America/New_York")
+	bot.config.location = location
+	bot.handleShowtimeCommand(`.showtime -raw="movie"`, "admin")
+	bot.handleShowtimeCommand(`.showtime -raw="missing"`, "admin")
+	bot.handleShowtimeCommand(`.showtime -raw`, "admin")
+
+	expected := []string{
+		`[movie] stored "2025-06-13T19:00:00Z" | UTC: 2025-06-13 19:00:00 UTC`,
+		`[movie] stored "2025-06-13T19:00:00Z" | UTC: 2025-06-13 19:00:00 UTC | America/New_York: 2025-06-13 15:00:00 EDT`,
+		"Showtime with ID 'missing' not found.",
+		`Usage: .showtime -raw="id"`,
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestShiftShowtimes(t *testing.T) {
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "past", Title: "Vertigo", DateTime: now.Add(-time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "soon", Title: "Casablanca", DateTime: now.Add(20 * time.Minute), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "later", Title: "Psycho", DateTime: now.Add(3 * time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "other", Title: "Metropolis", DateTime: now.Add(3 * time.Hour), CreatedBy: "bob"})
+
+	bot.shiftShowtimes(bot.parseArgs(`.showtime -shift-by=-30m -creator="alice"`), "admin", now)
+	if got, _ := bot.store.GetByID("soon"); !got.DateTime.Equal(now.Add(20 * time.Minute)) {
+		t.Errorf("expected a refused shift to change nothing, got %v", got.DateTime)
+	}
+
+	bot.shiftShowtimes(bot.parseArgs(`.showtime -shift-by=1h -creator="alice"`), "admin", now)
+	expectedTimes := map[string]time.Time{
+		"past":  now.Add(-time.Hour),
+		"soon":  now.Add(80 * time.Minute),
+		"later": now.Add(4 * time.Hour),
+		"other": now.Add(3 * time.Hour),
+	}
+	for id, expected := range expectedTimes {
+		if got, _ := bot.store.GetByID(id); !got.DateTime.Equal(expected) {
+			t.Errorf("%s: expected %v, got %v", id, expected, got.DateTime)
+		}
+	}
+
+	bot.shiftShowtimes(bot.parseArgs(`.showtime -shift-by=-2h -creator="alice" -force`), "admin", now)
+	if got, _ := bot.store.GetByID("soon"); !got.DateTime.Equal(now.Add(-40 * time.Minute)) {
+		t.Errorf("expected a forced shift into the past, got %v", got.DateTime)
+	}
+
+	expected := []string{
+		"Shifting by -30m0s would move 1 showtime into the past. Add -force to shift anyway.",
+		"Shifted alice's 2 upcoming showtimes by 1h0m0s.",
+		"Shifted alice's 2 upcoming showtimes by -2h0m0s.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	if audit := bot.store.(*memoryStore).audit; len(audit) != 4 || audit[0].Action != "shift" || audit[0].Details != "1h0m0s: 2025-06-13T12:20:00Z -> 2025-06-13T13:20:00Z" {
+		t.Errorf("unexpected audit log %+v", audit)
+	}
+}
+
+func TestShiftShowtimes_Invalid(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+
+	bot.shiftShowtimes(bot.parseArgs(`.showtime -shift-by=soon -creator="alice"`), "admin", now)
+	bot.shiftShowtimes(bot.parseArgs(`.showtime -shift-by=1h`), "admin", now)
+	bot.shiftShowtimes(bot.parseArgs(`.showtime -shift-by=1h -creator="alice"`), "admin", now)
+
+	expected := []string{
+		`Usage: .showtime -shift-by=1h -creator="nick" [-force]`,
+		`Usage: .showtime -shift-by=1h -creator="nick" [-force]`,
+		"alice has no upcoming showtimes.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleDebugCommand_Parse(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.handleDebugCommand(`.debug parse -create -title="My Movie" -id=abc\"x`)
+	bot.handleDebugCommand(`.debug parse`)
+	bot.handleDebugCommand(`.debug`)
+
+	expected := []string{
+		`3 tokens: "-create" "-title=My Movie" "-id=abc\"x"`,
+		"0 tokens",
+		"Usage: .debug parse <text> | .debug irc on|off",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleDebugCommand_IRC(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.conn = irc.IRC("testbot", "testbot")
+	bot.configureIRCLog()
+
+	if bot.conn.Debug || bot.conn.VerboseCallbackHandler {
+		t.Error("expected IRC debug logging to start off")
+	}
+	bot.handleDebugCommand(".debug irc on")
+	if !bot.conn.Debug || !bot.conn.VerboseCallbackHandler {
+		t.Error("expected IRC debug logging to be on")
+	}
+	bot.handleDebugCommand(".debug irc off")
+	if bot.conn.Debug || bot.conn.VerboseCallbackHandler {
+		t.Error("expected IRC debug logging to be off")
+	}
+	bot.handleDebugCommand(".debug irc maybe")
+
+	expected := []string{
+		"IRC debug logging is now on.",
+		"IRC debug logging is now off.",
+		"Usage: .debug parse <text> | .debug irc on|off",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestListShowtimes_RowsPerMessage(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.RowsPerMessage = 2
+	for i, title := range []string{"Casablanca", "Vertigo", "Psycho"} {
+		bot.store.Create(Showtime{ID: fmt.Sprint(i), Title: title, DateTime: time.Date(2020, 6, 13+i, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	}
+
+	bot.listShowtimes(listOptions{}, "")
+
+	expected := []string{
+		"Scheduled showtimes:",
+		"⏮ [0] Casablanca - 2020-06-13 19:00:00 UTC (by alice) | ⏮ [1] Vertigo - 2020-06-14 19:00:00 UTC (by alice)",
+		"⏮ [2] Psycho - 2020-06-15 19:00:00 UTC (by alice)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestSendRows_RespectsLineLimit(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.RowsPerMessage = 10
+	row := strings.Repeat("x", maxMessageBytes/2)
+
+	bot.sendRows([]string{row, row, row})
+
+	if len(sender.messages) != 3 {
+		t.Errorf("expected rows too long to merge to be sent separately, got %d messages", len(sender.messages))
+	}
+	for _, message := range sender.messages {
+		if len(message) > maxMessageBytes {
+			t.Errorf("message exceeds %d bytes: %d", maxMessageBytes, len(message))
+		}
+	}
+}
+
+func TestListShowtimes_DeleteHint(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2020, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.listShowtimes(listOptions{}, "bob")
+	if len(sender.messages) != 2 {
+		t.Errorf("expected no hint for a non-creator, got %v", sender.messages)
+	}
+
+	sender.messages = nil
+	bot.listShowtimes(listOptions{}, "alice")
+	expected := []string{
+		"Scheduled showtimes:",
+		"⏮ [movie] Casablanca - 2020-06-13 19:00:00 UTC (by alice)",
+		`To delete: .showtime -delete="<id>" using the id in [brackets]`,
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestNotFoundSuggestions(t *testing.T) {
+	bot, sender := newTestBot()
+	for _, id := range []string{"casablanca", "vertigo-1", "vertigo-2", "psycho"} {
+		bot.store.Create(Showtime{ID: id, Title: id, DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	}
+
+	bot.showtimeInfo([]string{".showtime", "-info=Casa"}, false)
+	bot.deleteShowtime([]string{".showtime", "-delete=vertigo"}, "alice")
+	bot.deleteShowtime([]string{".showtime", "-delete=psychos"}, "alice")
+	bot.showtimeInfo([]string{".showtime", "-info=metropolis"}, false)
+
+	expected := []string{
+		"Showtime with ID 'Casa' not found. Did you mean 'casablanca'?",
+		"Showtime with ID 'vertigo' not found. Did you mean one of: vertigo-1, vertigo-2?",
+		"Showtime with ID 'psychos' not found. Did you mean 'psycho'?",
+		"Showtime with ID 'metropolis' not found.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleQuietCommand(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+
+	bot.handleQuietCommand([]string{".quiet", "30m"}, now)
+	if !bot.quiet(now.Add(29*time.Minute)) || bot.quiet(now.Add(30*time.Minute)) {
+		t.Errorf("expected quiet for exactly 30 minutes, until %v", bot.quietUntil)
+	}
+
+	bot.handleQuietCommand([]string{".quiet", "off"}, now)
+	if bot.quiet(now) {
+		t.Error("expected quiet mode to be lifted")
+	}
+
+	bot.handleQuietCommand([]string{".quiet", "soon"}, now)
+	bot.handleQuietCommand([]string{".quiet", "-5m"}, now)
+	bot.handleQuietCommand([]string{".quiet"}, now)
+
+	expected := []string{
+		"Going quiet until 2025-06-13 19:30:00 UTC. Use .quiet off to resume early.",
+		"Quiet mode off.",
+		"Invalid duration (e.g. 30m, 1h30m).",
+		"Invalid duration (e.g. 30m, 1h30m).",
+		"Usage: .quiet 30m | .quiet off",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestNewCommand(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+
+	bot.quietUntil = now.Add(time.Hour)
+	bot.newCommand(now).handleDateCommand()
+	if len(sender.messages) != 0 {
+		t.Errorf("expected no output while quiet, got %v", sender.messages)
+	}
+
+	bot.quietUntil = time.Time{}
+	bot.staging = newMemoryStore()
+	bot.newCommand(now).handleDateCommand()
+	if len(sender.messages) != 1 || !strings.HasPrefix(sender.messages[0], stagingTag) {
+		t.Errorf("expected a tagged reply on staging, got %v", sender.messages)
+	}
+	if bot.CinemaBot.sender != Sender(sender) {
+		t.Errorf("expected the bot's own sender to be left alone")
+	}
+}
+
+func TestListShowtimes_Active(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Now().UTC()
+	bot.store.Create(Showtime{ID: "ended", Title: "Casablanca", DateTime: now.Add(-2 * time.Hour), CreatedBy: "alice"})
+	bot.store.CacheMovieInfo("Casablanca", MovieInfo{Runtime: "102 min"})
+	bot.store.Create(Showtime{ID: "playing", Title: "Vertigo", DateTime: now.Add(-2 * time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "old", Title: "Psycho", DateTime: now.Add(-4 * time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "next", Title: "Metropolis", DateTime: now.Add(time.Hour), CreatedBy: "alice"})
+
+	opts, err := bot.parseListOptions([]string{".showtime", "-list", "-active", "-format=json"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	bot.listShowtimes(opts, "")
+
+	if len(sender.messages) != 1 || !strings.Contains(sender.messages[0], `"playing"`) || !strings.Contains(sender.messages[0], `"next"`) ||
+		strings.Contains(sender.messages[0], `"ended"`) || strings.Contains(sender.messages[0], `"old"`) {
+		t.Errorf("expected only playing and next, got %v", sender.messages)
+	}
+}
+
+func TestHandleUptimeCommand(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.Server = "irc.example.com:6667"
+	now := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.startedAt = now.Add(-(2*24*time.Hour + 3*time.Hour))
+
+	bot.handleUptimeCommand(now)
+	bot.connectedAt = now.Add(-90 * time.Minute)
+	bot.handleUptimeCommand(now)
+
+	expected := []string{
+		"Up 2 days, 3 hours | not connected yet",
+		"Up 2 days, 3 hours | connected for 1 hour, 30 minutes to irc.example.com:6667 in #testchan",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestHandleLeaderboardCommand(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.handleLeaderboardCommand()
+
+	for i, nick := range []string{"alice", "bob", "alice", "carol", "alice", "bob"} {
+		bot.store.Create(Showtime{ID: fmt.Sprint(i), Title: "Movie", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: nick})
+	}
+	bot.handleLeaderboardCommand()
+
+	expected := []string{
+		"No showtimes scheduled yet.",
+		"Top schedulers: 1. alice (3), 2. bob (2), 3. carol (1)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestCommandName(t *testing.T) {
+	tests := map[string]string{
+		".showtime -create -id=x": "showtime",
+		".NextMovie":              "nextmovie",
+		".":                       "",
+		"hello .date":             "",
+	}
+	for message, expected := range tests {
+		if got := commandName(message); got != expected {
+			t.Errorf("commandName(%q): expected %q, got %q", message, expected, got)
+		}
+	}
+}
+
+func TestCommandEnabled(t *testing.T) {
+	bot := newConfigCommand(Config{ChannelCommands: map[string][]string{
+		"#ReadOnly": {"nextmovie", ".date"},
+	}})
+
+	tests := []struct {
+		channel, command string
+		expected         bool
+	}{
+		{"#readonly", "nextmovie", true},
+		{"#readonly", "date", true},
+		{"#readonly", "showtime", false},
+		{"#readonly", "", true},
+		{"#other", "showtime", true},
+	}
+	for _, tt := range tests {
+		if got := bot.commandEnabled(tt.channel, tt.command); got != tt.expected {
+			t.Errorf("commandEnabled(%s, %s): expected %v, got %v", tt.channel, tt.command, tt.expected, got)
+		}
+	}
+}
+
+func TestSuggestCommand(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.ChannelCommands = map[string][]string{"#readonly": {"nextmovie"}}
+	bot.config.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"alice": true}}
+
+	bot.suggestCommand("#testchan", "show", "alice", "user/alice")
+	bot.suggestCommand("#testchan", "nextmvoie", "alice", "user/alice")
+	bot.suggestCommand("#testchan", "dat", "alice", "user/alice")
+	bot.suggestCommand("#testchan", "weather", "alice", "user/alice")
+	bot.suggestCommand("#testchan", "..", "alice", "user/alice")
+	bot.suggestCommand("#readonly", "show", "alice", "user/alice")
+	bot.suggestCommand("#testchan", "show", "bob", "user/bob")
+	bot.suggestCommand("#testchan", "qiet", "bob", "user/bob")
+	bot.suggestCommand("#testchan", "ok", "bob", "user/bob")
+	bot.suggestCommand("#testchan", "da", "bob", "user/bob")
+	bot.suggestCommand("#testchan", "remnd", "bob", "user/bob")
+
+	expected := []string{
+		"alice: Did you mean .showtime?",
+		"alice: Did you mean .nextmovie?",
+		"alice: Did you mean .date?",
+		"bob: Did you mean .remind?",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestKnownCommand(t *testing.T) {
+	for _, name := range []string{"showtime", "showtimes", "date", "quiet"} {
+		if !knownCommand(name) {
+			t.Errorf("expected %s to be known", name)
+		}
+	}
+	for _, name := range []string{"show", "dat", "help"} {
+		if knownCommand(name) {
+			t.Errorf("expected %s to be unknown", name)
+		}
+	}
+}
+
+// blockingStore holds TopCreators until release is closed, to stand in for a
+// slow query
+type blockingStore struct {
+	ShowtimeStore
+	entered chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingStore) TopCreators(limit int) ([]CreatorCount, error) {
+	close(b.entered)
+	<-b.release
+	return b.ShowtimeStore.TopCreators(limit)
+}
+
+func TestHandlePrivmsg_SlowQueryDoesNotBlockOthers(t *testing.T) {
+	bot, sender := newTestBot()
+	store := &blockingStore{ShowtimeStore: bot.store, entered: make(chan struct{}), release: make(chan struct{})}
+	bot.CinemaBot.store = store
+
+	done := make(chan struct{})
+	go func() {
+		bot.handlePrivmsg("#testchan", "alice", "host", ".leaderboard")
+		close(done)
+	}()
+	<-store.entered
+
+	// Another command and a background reader both get through while the
+	// leaderboard query is still running
+	finished := make(chan struct{})
+	go func() {
+		bot.handlePrivmsg("#testchan", "bob", "host", ".date")
+		bot.mu.RLock()
+		bot.sendDueReminders(time.Now().UTC())
+		bot.mu.RUnlock()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("expected .date to run while another command waits on the store")
+	}
+
+	close(store.release)
+	<-done
+	if len(sender.messages) != 2 || !strings.HasPrefix(sender.messages[0], "Current time") || sender.messages[1] != "No showtimes scheduled yet." {
+		t.Errorf("unexpected replies %v", sender.messages)
+	}
+}
+
+func TestHandlePrivmsg_SlowQueryDoesNotBlockWriters(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"admin": true}}
+	store := &blockingStore{ShowtimeStore: bot.store, entered: make(chan struct{}), release: make(chan struct{})}
+	bot.CinemaBot.store = store
+
+	done := make(chan struct{})
+	go func() {
+		bot.handlePrivmsg("#testchan", "alice", "host", ".leaderboard")
+		close(done)
+	}()
+	<-store.entered
+
+	// .quiet and a config reload take mu exclusively, and a command arriving
+	// after them must not queue behind the leaderboard query either
+	finished := make(chan struct{})
+	go func() {
+		bot.handlePrivmsg("#testchan", "admin", "user/admin", ".quiet 1h")
+		bot.mu.Lock()
+		bot.config.SoonThresholdMinutes = 5
+		bot.mu.Unlock()
+		bot.handlePrivmsg("#testchan", "bob", "host", ".date")
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-time.After(time.Second):
+		t.Fatal("expected writers to get mu while a command waits on the store")
+	}
+
+	close(store.release)
+	<-done
+	// The leaderboard arrived before .quiet, so it still answers
+	if len(sender.messages) != 2 || !strings.HasPrefix(sender.messages[0], "Going quiet until") || sender.messages[1] != "No showtimes scheduled yet." {
+		t.Errorf("unexpected replies %v", sender.messages)
+	}
+}
+
+// slowStore answers TopCreators after delay, standing in for a busy database
+type slowStore struct {
+	ShowtimeStore
+	delay time.Duration
+}
+
+func (s *slowStore) TopCreators(limit int) ([]CreatorCount, error) {
+	time.Sleep(s.delay)
+	return s.ShowtimeStore.TopCreators(limit)
+}
+
+// BenchmarkHandlePrivmsg_SlowQueries runs commands that each wait a
+// millisecond on the store, in parallel with a writer taking mu the way a
+// config reload does. Commands only hold mu while copying the bot's state, so
+// throughput scales with -cpu instead of being serialized by the writer.
+func BenchmarkHandlePrivmsg_SlowQueries(b *testing.B) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	bot, _ := newTestBot()
+	bot.CinemaBot.sender = discardSender{}
+	bot.CinemaBot.store = &slowStore{ShowtimeStore: bot.store, delay: time.Millisecond}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(100 * time.Microsecond):
+				bot.mu.Lock()
+				bot.mu.Unlock()
+			}
+		}
+	}()
+
+	b.SetParallelism(8)
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			bot.handlePrivmsg("#testchan", "alice", "host", ".leaderboard")
+		}
+	})
+}
+
+func TestHandlePrivmsg_QuietCommandExclusive(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"admin": true}}
+
+	bot.handlePrivmsg("#testchan", "admin", "user/admin", ".quiet 1h")
+	bot.handlePrivmsg("#testchan", "bob", "host", ".date")
+	bot.handlePrivmsg("#testchan", "admin", "user/admin", ".quiet off")
+	bot.handlePrivmsg("#otherchan", "bob", "host", ".date")
+
+	if len(sender.messages) != 2 || !strings.HasPrefix(sender.messages[0], "Going quiet until") || sender.messages[1] != "Quiet mode off." {
+		t.Errorf("expected only the .quiet replies, got %v", sender.messages)
+	}
+}
+
+func containsString(items []string, item string) bool {
+	for _, candidate := range items {
+		if candidate == item {
+			return true
+		}
+	}
+	return false
+}
+
+// newTestBot returns a bot backed by an in-memory store that records its
+// replies, as a command sees it so handlers can be called directly
+func newTestBot() (*command, *captureSender) {
+	sender := &captureSender{}
+	bot := &CinemaBot{
+		config: Config{Channel: "#testchan"},
+		sender: sender,
+		store:  newMemoryStore(),
+	}
+	return &command{CinemaBot: bot, sender: sender, store: bot.store, config: &bot.config}, sender
+}
+
+// newConfigCommand returns a command for a bot with only config set, for
+// tests of helpers that just read settings
+func newConfigCommand(config Config) *command {
+	bot := &CinemaBot{config: config}
+	return &command{CinemaBot: bot, config: &bot.config}
+}
+
+// captureSender records every message sent to it
+type captureSender struct {
+	targets  []string
+	messages []string
+}
+
+func (c *captureSender) Privmsg(target, message string) {
+	c.targets = append(c.targets, target)
+	c.messages = append(c.messages, message)
+}
+
+// memoryStore is an in-memory ShowtimeStore for handler tests
+type memoryStore struct {
+	showtimes  map[string]Showtime
+	movieInfo  map[string]MovieInfo
+	reminders  map[string][]string
+	audit      []AuditEntry
+	channelLog []ChannelMessage
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{showtimes: make(map[string]Showtime), movieInfo: make(map[string]MovieInfo), reminders: make(map[string][]string)}
+}
+
+func (m *memoryStore) Create(showtime Showtime) error {
+	m.showtimes[showtime.ID] = showtime
+	return nil
+}
+
+func (m *memoryStore) CreateAll(showtimes []Showtime) ([]Showtime, error) {
+	var created []Showtime
+	for _, showtime := range showtimes {
+		if _, exists := m.showtimes[showtime.ID]; exists {
+			continue
+		}
+		m.showtimes[showtime.ID] = showtime
+		created = append(created, showtime)
+	}
+	return created, nil
+}
+
+func (m *memoryStore) Update(showtime Showtime) error {
+	if _, ok := m.showtimes[showtime.ID]; ok {
+		m.showtimes[showtime.ID] = showtime
+	}
+	return nil
+}
+
+func (m *memoryStore) DeleteAll() (int, error) {
+	deleted := len(m.showtimes)
+	m.showtimes = make(map[string]Showtime)
+	m.reminders = make(map[string][]string)
+	return deleted, nil
+}
+
+func (m *memoryStore) UpdateAll(showtimes []Showtime) error {
+	for _, showtime := range showtimes {
+		m.Update(showtime)
+	}
+	return nil
+}
+
+func (m *memoryStore) Delete(id string) error {
+	delete(m.showtimes, id)
+	delete(m.reminders, id)
+	return nil
+}
+
+func (m *memoryStore) GetByID(id string) (*Showtime, error) {
+	showtime, ok := m.showtimes[id]
+	if !ok {
+		return nil, nil
+	}
+	return &showtime, nil
+}
+
+func (m *memoryStore) RawDateTime(id string) (string, error) {
+	showtime, ok := m.showtimes[id]
+	if !ok {
+		return "", nil
+	}
+	return storedTime(showtime.DateTime), nil
+}
+
+func (m *memoryStore) SimilarIDs(id string, limit int) ([]string, error) {
+	var ids []string
+	lower := strings.ToLower(id)
+	for existing := range m.showtimes {
+		candidate := strings.ToLower(existing)
+		if strings.Contains(candidate, lower) || strings.HasPrefix(lower, candidate) {
+			ids = append(ids, existing)
+		}
+	}
+	sort.Strings(ids)
+	if len(ids) > limit {
+		ids = ids[:limit]
+	}
+	return ids, nil
+}
+
+func (m *memoryStore) List(filter ShowtimeFilter) ([]Showtime, error) {
+	var showtimes []Showtime
+	for _, showtime := range m.showtimes {
+		if !filter.From.IsZero() && showtime.DateTime.Before(filter.From) {
+			continue
+		}
+		if !filter.To.IsZero() && showtime.DateTime.After(filter.To) {
+			continue
+		}
+		if filter.Tag != "" && !containsString(showtime.Tags, filter.Tag) {
+			continue
+		}
+		showtimes = append(showtimes, showtime)
+	}
+	sort.Slice(showtimes, func(i, j int) bool {
+		return showtimes[i].DateTime.Before(showtimes[j].DateTime)
+	})
+	return showtimes, nil
+}
+
+func (m *memoryStore) Next(now time.Time) (*Showtime, error) {
+	showtimes, _ := m.List(ShowtimeFilter{})
+	for _, showtime := range showtimes {
+		if showtime.DateTime.After(now) && !showtime.Cancelled {
+			return &showtime, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) Upcoming(now time.Time, limit int) ([]Showtime, error) {
+	showtimes, _ := m.List(ShowtimeFilter{})
+	var upcoming []Showtime
+	for _, showtime := range showtimes {
+		if showtime.DateTime.After(now) && !showtime.Cancelled && (limit <= 0 || len(upcoming) < limit) {
+			upcoming = append(upcoming, showtime)
+		}
+	}
+	return upcoming, nil
+}
+
+func (m *memoryStore) Current(now time.Time, window time.Duration) (*Showtime, error) {
+	showtimes, _ := m.List(ShowtimeFilter{})
+	for i := len(showtimes) - 1; i >= 0; i-- {
+		showtime := showtimes[i]
+		if !showtime.DateTime.After(now) && !showtime.DateTime.Before(now.Add(-window)) && !showtime.Cancelled {
+			return &showtime, nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *memoryStore) TopCreators(limit int) ([]CreatorCount, error) {
+	totals := make(map[string]int)
+	for _, showtime := range m.showtimes {
+		totals[showtime.CreatedBy]++
+	}
+	var counts []CreatorCount
+	for nick, count := range totals {
+		counts = append(counts, CreatorCount{Nick: nick, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Count != counts[j].Count {
+			return counts[i].Count > counts[j].Count
+		}
+		return counts[i].Nick < counts[j].Nick
+	})
+	if len(counts) > limit {
+		counts = counts[:limit]
+	}
+	return counts, nil
+}
+
+func (m *memoryStore) SetRuntime(idOrTitle string, minutes int) (int, error) {
+	if showtime, ok := m.showtimes[idOrTitle]; ok {
+		showtime.RuntimeMinutes = minutes
+		m.showtimes[idOrTitle] = showtime
+		return 1, nil
+	}
+	changed := 0
+	for id, showtime := range m.showtimes {
+		if strings.EqualFold(showtime.Title, idOrTitle) {
+			showtime.RuntimeMinutes = minutes
+			m.showtimes[id] = showtime
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+func (m *memoryStore) ByTitle(title string, contains bool) ([]Showtime, error) {
+	var matches []Showtime
+	for _, showtime := range m.showtimes {
+		if strings.EqualFold(showtime.Title, title) ||
+			(contains && strings.Contains(strings.ToLower(showtime.Title), strings.ToLower(title))) {
+			matches = append(matches, showtime)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if !matches[i].CreatedAt.Equal(matches[j].CreatedAt) {
+			return matches[i].CreatedAt.Before(matches[j].CreatedAt)
+		}
+		return matches[i].ID < matches[j].ID
+	})
+	return matches, nil
+}
+
+func (m *memoryStore) Titles(search string) ([]string, error) {
+	seen := make(map[string]bool)
+	var titles []string
+	for _, showtime := range m.showtimes {
+		key := strings.ToLower(showtime.Title)
+		if seen[key] || !strings.Contains(key, strings.ToLower(search)) {
+			continue
+		}
+		seen[key] = true
+		titles = append(titles, showtime.Title)
+	}
+	sort.Slice(titles, func(i, j int) bool { return strings.ToLower(titles[i]) < strings.ToLower(titles[j]) })
+	return titles, nil
+}
+
+func (m *memoryStore) CachedMovieInfo(title string) (*MovieInfo, error) {
+	info, ok := m.movieInfo[strings.ToLower(title)]
+	if !ok {
+		return nil, nil
+	}
+	return &info, nil
+}
+
+func (m *memoryStore) CacheMovieInfo(title string, info MovieInfo) error {
+	m.movieInfo[strings.ToLower(title)] = info
+	return nil
+}
+
+func (m *memoryStore) AddReminder(showtimeID, nick string) error {
+	for _, existing := range m.reminders[showtimeID] {
+		if existing == nick {
+			return nil
+		}
+	}
+	m.reminders[showtimeID] = append(m.reminders[showtimeID], nick)
+	return nil
+}
+
+func (m *memoryStore) RemoveReminder(showtimeID, nick string) (bool, error) {
+	nicks := m.reminders[showtimeID]
+	for i, existing := range nicks {
+		if existing == nick {
+			m.reminders[showtimeID] = append(nicks[:i:i], nicks[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memoryStore) Reminders(showtimeID string) ([]string, error) {
+	return m.reminders[showtimeID], nil
+}
+
+func (m *memoryStore) ClearReminders(showtimeID string) error {
+	delete(m.reminders, showtimeID)
+	return nil
+}
+
+func (m *memoryStore) Audit(entry AuditEntry) error {
+	m.audit = append(m.audit, entry)
+	return nil
+}
+
+func (m *memoryStore) PruneAudit(cutoff time.Time) (int, error) {
+	var kept []AuditEntry
+	for _, entry := range m.audit {
+		if !entry.At.Before(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	pruned := len(m.audit) - len(kept)
+	m.audit = kept
+	return pruned, nil
+}
+
+func (m *memoryStore) LogChannelMessage(entry ChannelMessage) error {
+	m.channelLog = append(m.channelLog, entry)
+	return nil
+}
+
+func (m *memoryStore) PruneChannelLog(cutoff time.Time) (int, error) {
+	var kept []ChannelMessage
+	for _, entry := range m.channelLog {
+		if !entry.At.Before(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	pruned := len(m.channelLog) - len(kept)
+	m.channelLog = kept
+	return pruned, nil
+}
+
+func (m *memoryStore) Stats() (StoreStats, error) {
+	stats := StoreStats{Showtimes: len(m.showtimes), AuditEntries: len(m.audit)}
+	for _, nicks := range m.reminders {
+		stats.Reminders += len(nicks)
+	}
+	return stats, nil
+}
+
+func (m *memoryStore) Maintain(vacuum bool) error {
+	return nil
+}
+
+func (m *memoryStore) Close() error {
+	return nil
+}
+
+// Helper for comparing slices
+func equalStringSlices(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCompareShowtimes(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), RuntimeMinutes: 102})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: time.Date(2025, 6, 13, 20, 30, 0, 0, time.UTC), RuntimeMinutes: 128})
+	bot.store.Create(Showtime{ID: "c", Title: "Psycho", DateTime: time.Date(2025, 6, 13, 23, 0, 0, 0, time.UTC)})
+
+	bot.handleShowtimeCommand(`.showtime -between="b" "a"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -between="b" "c"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -between="c" "c"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -between="a" "zzz"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -between="a"`, "alice")
+
+	expected := []string{
+		"[a] Casablanca starts 1 hour, 30 minutes before [b] Vertigo. They overlap by 12 minutes.",
+		"[b] Vertigo starts 2 hours, 30 minutes before [c] Psycho. Vertigo ends 22 minutes before Psycho starts.",
+		"[c] Psycho and [c] Psycho start at the same time. Psycho has no known runtime, so overlap can't be checked.",
+		"Showtime with ID 'zzz' not found.",
+		`Usage: .showtime -between="id" "other id"`,
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestCancelShowtime(t *testing.T) {
+	bot, sender := newTestBot()
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: start, CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: start.Add(time.Hour), CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(`.showtime -cancel="a"`, "bob")
+	bot.handleShowtimeCommand(`.showtime -cancel="a"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -cancel="a"`, "alice")
+
+	expected := []string{
+		"You can only cancel showtimes you created.",
+		fmt.Sprintf(`Cancelled [a] Casablanca - %s. It stays in the list; .showtime -uncancel="a" brings it back.`, bot.formatTime(start)),
+		"[a] Casablanca is already cancelled.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+
+	sender.messages = nil
+	bot.handleShowtimeCommand(".showtime -list -compact", "alice")
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+	if len(sender.messages) != 2 || !strings.HasPrefix(sender.messages[0], "a:[CANCELLED] Casablanca@") || !strings.Contains(sender.messages[1], "Vertigo") {
+		t.Errorf("expected a marked list entry and Vertigo next, got %v", sender.messages)
+	}
+
+	sender.messages = nil
+	bot.handleShowtimeCommand(`.showtime -uncancel="a"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -uncancel="a"`, "alice")
+	expected = []string{
+		fmt.Sprintf("Restored [a] Casablanca - %s.", bot.formatTime(start)),
+		"[a] Casablanca isn't cancelled.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	if len(bot.store.(*memoryStore).audit) != 2 {
+		t.Errorf("expected the cancel and uncancel to be audited, got %+v", bot.store.(*memoryStore).audit)
+	}
+}
+
+func TestCancelShowtime_Admin(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"carol": true}}
+	start := time.Now().UTC().Add(time.Hour).Truncate(time.Second)
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: start, CreatedBy: "alice"})
+
+	bot.handleShowtimeCommand(`.showtime -cancel="a"`, "carol")
+	bot.handleShowtimeCommand(`.showtime -uncancel="a"`, "carol")
+	bot.handleShowtimeCommand(`.showtime -cancel="a"`, "bob")
+
+	expected := []string{
+		fmt.Sprintf(`Cancelled [a] Casablanca - %s. It stays in the list; .showtime -uncancel="a" brings it back.`, bot.formatTime(start)),
+		fmt.Sprintf("Restored [a] Casablanca - %s.", bot.formatTime(start)),
+		"You can only cancel showtimes you created.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
 }
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"log"
+	"time"
+)
+
+// vacuumInterval is the minimum time between VACUUMs; the cheaper PRAGMA
+// optimize runs on every maintenance pass
+const vacuumInterval = 24 * time.Hour
+
+// maintenanceQuietPeriod is how far ahead nothing may be scheduled for a pass
+// to count as low activity and VACUUM
+const maintenanceQuietPeriod = time.Hour
+
+// runMaintenance periodically optimizes the database, vacuuming it at most
+// once per vacuumInterval and only when no showtime is playing or about to
+// start
+func (bot *CinemaBot) runMaintenance(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastVacuum time.Time
+	for now := range ticker.C {
+		vacuum := now.Sub(lastVacuum) >= vacuumInterval && bot.lowActivity(now.UTC())
+		if err := bot.store.Maintain(vacuum); err != nil {
+			log.Printf("Error during database maintenance: %v", err)
+			continue
+		}
+		if vacuum {
+			lastVacuum = now
+		}
+	}
+}
+
+// lowActivity reports whether nothing is playing at now and nothing starts
+// within maintenanceQuietPeriod
+func (bot *CinemaBot) lowActivity(now time.Time) bool {
+	bot.mu.RLock()
+	window := bot.snapshot().currentWindow()
+	bot.mu.RUnlock()
+
+	current, err := bot.store.Current(now, window)
+	if err != nil || current != nil {
+		return false
+	}
+	next, err := bot.store.Next(now)
+	if err != nil {
+		return false
+	}
+	return next == nil || next.DateTime.Sub(now) > maintenanceQuietPeriod
+}
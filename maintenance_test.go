@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLowActivity(t *testing.T) {
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		start    time.Time
+		expected bool
+	}{
+		{"playing", now.Add(-time.Hour), false},
+		{"starting soon", now.Add(30 * time.Minute), false},
+		{"starting later", now.Add(2 * time.Hour), true},
+		{"long finished", now.Add(-6 * time.Hour), true},
+	}
+	for _, tt := range tests {
+		bot, _ := newTestBot()
+		bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: tt.start, CreatedBy: "alice"})
+		if got := bot.lowActivity(now); got != tt.expected {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.expected, got)
+		}
+	}
+
+	bot, _ := newTestBot()
+	if !bot.lowActivity(now) {
+		t.Error("expected an empty schedule to be low activity")
+	}
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+// ghostTimeout is how long after a GHOST the bot still takes its nick back
+// when NickServ or the ghosted holder answer
+const ghostTimeout = 30 * time.Second
+
+// defaultNickLen is the RFC 1459 nick length, assumed until the server
+// advertises NICKLEN in RPL_ISUPPORT, which only arrives after registration
+const defaultNickLen = 9
+
+// nickState is a GHOST waiting for its answer and the server's NICKLEN. Both
+// are set from IRC callbacks, so it has its own lock.
+type nickState struct {
+	mu        sync.Mutex
+	ghostedAt time.Time
+	nickLen   int
+}
+
+// handleNickInUse answers ERR_NICKNAMEINUSE during registration by trying the
+// next fallback nick. Once registered the rejection comes from the IRC
+// library's own attempts to take the configured nick back, which it retries
+// by itself, so those are ignored.
+func (bot *CinemaBot) handleNickInUse(e *irc.Event) {
+	if len(e.Arguments) < 2 || e.Arguments[0] != "*" {
+		return
+	}
+
+	rejected := e.Arguments[1]
+	fallback := bot.nextNick(rejected)
+	log.Printf("Nick %s is already in use, trying %s", rejected, fallback)
+	bot.conn.SendRawf("NICK %s", fallback)
+}
+
+// nextNick returns the nick to try after rejected was refused: the configured
+// alternate_nicks in order, then the last rejected nick with "_" appended.
+// A nick already at the server's NICKLEN would be cut back to itself, so its
+// last character becomes "_" instead and is then counted up through the
+// digits.
+func (bot *CinemaBot) nextNick(rejected string) string {
+	candidates := append([]string{bot.config.Nick}, bot.config.AlternateNicks...)
+	for i, candidate := range candidates[:len(candidates)-1] {
+		if strings.EqualFold(candidate, rejected) {
+			return candidates[i+1]
+		}
+	}
+
+	limit := bot.nickLen()
+	if len(rejected) < limit {
+		return rejected + "_"
+	}
+	base := rejected[:limit-1]
+	last := rejected[limit-1]
+	switch {
+	case last >= '0' && last < '9':
+		return base + string(last+1)
+	case last == '9':
+		return base + "0"
+	case last == '_':
+		return base + "1"
+	default:
+		return base + "_"
+	}
+}
+
+// nickLen returns the server's NICKLEN, or defaultNickLen until it is known
+func (bot *CinemaBot) nickLen() int {
+	bot.nick.mu.Lock()
+	defer bot.nick.mu.Unlock()
+	if bot.nick.nickLen < 2 {
+		return defaultNickLen
+	}
+	return bot.nick.nickLen
+}
+
+// handleISupport records NICKLEN from RPL_ISUPPORT
+func (bot *CinemaBot) handleISupport(e *irc.Event) {
+	for _, token := range e.Arguments {
+		value, ok := strings.CutPrefix(token, "NICKLEN=")
+		if !ok {
+			continue
+		}
+		if n, err := strconv.Atoi(value); err == nil {
+			bot.nick.mu.Lock()
+			bot.nick.nickLen = n
+			bot.nick.mu.Unlock()
+		}
+	}
+}
+
+// regainNick is called once registered as current. When that isn't the
+// configured nick and a NickServ password is set, whoever holds the nick is
+// ghosted; the bot switches back to it once handleGhostReply sees the answer.
+func (bot *CinemaBot) regainNick(current string, now time.Time) {
+	if strings.EqualFold(current, bot.config.Nick) {
+		return
+	}
+	if bot.config.NickServ.Password == "" {
+		log.Printf("Registered as %s because %s is in use; set a nickserv password to reclaim it", current, bot.config.Nick)
+		return
+	}
+
+	log.Printf("Registered as %s because %s is in use, ghosting it through NickServ", current, bot.config.Nick)
+	bot.nick.mu.Lock()
+	bot.nick.ghostedAt = now
+	bot.nick.mu.Unlock()
+	bot.sender.Privmsg(bot.ghostMessage())
+}
+
+// handleGhostReply sends NICK for the configured nick once a pending GHOST
+// has been answered
+func (bot *CinemaBot) handleGhostReply(e *irc.Event) {
+	if bot.ghostAnswered(e, time.Now()) {
+		bot.conn.Nick(bot.config.Nick)
+	}
+}
+
+// ghostAnswered reports whether e shows the configured nick is free or about
+// to be: a NOTICE from NickServ about it, or its holder quitting or changing
+// nick. Any of NickServ's notices may come first, so the GHOST stays pending
+// until the QUIT, our own switch to the nick or ghostTimeout.
+func (bot *CinemaBot) ghostAnswered(e *irc.Event, now time.Time) bool {
+	bot.nick.mu.Lock()
+	defer bot.nick.mu.Unlock()
+	if bot.nick.ghostedAt.IsZero() {
+		return false
+	}
+	if now.Sub(bot.nick.ghostedAt) > ghostTimeout {
+		bot.nick.ghostedAt = time.Time{}
+		return false
+	}
+
+	service, _ := bot.identifyMessage()
+	service, _, _ = strings.Cut(service, "@")
+	switch e.Code {
+	case "NOTICE":
+		return strings.EqualFold(e.Nick, service) &&
+			strings.Contains(strings.ToLower(e.Message()), strings.ToLower(bot.config.Nick))
+	case "QUIT":
+		if !strings.EqualFold(e.Nick, bot.config.Nick) {
+			return false
+		}
+		bot.nick.ghostedAt = time.Time{}
+		return true
+	case "NICK":
+		if strings.EqualFold(e.Message(), bot.config.Nick) {
+			bot.nick.ghostedAt = time.Time{}
+			return false
+		}
+		return strings.EqualFold(e.Nick, bot.config.Nick)
+	}
+	return false
+}
+
+// ghostMessage returns the services nick and the message that disconnects
+// whoever holds the configured nick, following the nickserv settings
+func (bot *CinemaBot) ghostMessage() (target, message string) {
+	target, _ = bot.identifyMessage()
+	command := bot.config.NickServ.GhostCommand
+	if command == "" {
+		command = "GHOST {nick} {password}"
+	}
+	message = strings.NewReplacer("{nick}", bot.config.Nick, "{password}", bot.config.NickServ.Password).Replace(command)
+	return target, message
+}
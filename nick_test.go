@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+func TestNextNick(t *testing.T) {
+	bot := &CinemaBot{config: Config{Nick: "cinemabot", AlternateNicks: []string{"cinemabot2", "popcorn"}}}
+	tests := []struct {
+		rejected string
+		expected string
+	}{
+		{"cinemabot", "cinemabot2"},
+		{"CinemaBot2", "popcorn"},
+		{"popcorn", "popcorn_"},
+		{"popcorn_", "popcorn__"},
+		{"popcorn__", "popcorn_1"},
+	}
+	for _, tt := range tests {
+		if got := bot.nextNick(tt.rejected); got != tt.expected {
+			t.Errorf("nextNick(%q): expected %q, got %q", tt.rejected, tt.expected, got)
+		}
+	}
+
+	bot.config.AlternateNicks = nil
+	if got := bot.nextNick("cinemabot"); got != "cinemabo_" {
+		t.Errorf("expected cinemabo_ at the default NICKLEN, got %q", got)
+	}
+
+	bot.handleISupport(&irc.Event{Code: "005", Arguments: []string{"cinemabot_", "CHANTYPES=#", "NICKLEN=12", "are supported by this server"}})
+	tests = []struct {
+		rejected string
+		expected string
+	}{
+		{"cinemabot", "cinemabot_"},
+		{"cinemabot___", "cinemabot__1"},
+		{"cinemabot__1", "cinemabot__2"},
+		{"cinemabot__9", "cinemabot__0"},
+		{"cinemabot_long", "cinemabot_l_"},
+	}
+	for _, tt := range tests {
+		if got := bot.nextNick(tt.rejected); got != tt.expected {
+			t.Errorf("nextNick(%q) with NICKLEN=12: expected %q, got %q", tt.rejected, tt.expected, got)
+		}
+	}
+}
+
+func TestGhostMessage(t *testing.T) {
+	bot := &CinemaBot{config: Config{Nick: "cinemabot"}}
+	bot.config.NickServ.Password = "secret"
+
+	if target, message := bot.ghostMessage(); target != "NickServ" || message != "GHOST cinemabot secret" {
+		t.Errorf("expected default NickServ GHOST, got %s %q", target, message)
+	}
+
+	bot.config.NickServ.Service = "NickServ@services.example.net"
+	bot.config.NickServ.GhostCommand = "REGAIN {nick} {password}"
+	if target, message := bot.ghostMessage(); target != "NickServ@services.example.net" || message != "REGAIN cinemabot secret" {
+		t.Errorf("expected REGAIN to the configured service, got %s %q", target, message)
+	}
+}
+
+func TestRegainNick_WithoutPassword(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.Nick = "cinemabot"
+
+	bot.regainNick("cinemabot", time.Now())
+	bot.regainNick("cinemabot_", time.Now())
+	if len(sender.messages) != 0 {
+		t.Errorf("expected no services messages without a password, got %v", sender.messages)
+	}
+}
+
+func TestRegainNick_WaitsForGhost(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.Nick = "cinemabot"
+	bot.config.NickServ.Password = "secret"
+	now := time.Now()
+
+	stranger := &irc.Event{Code: "QUIT", Nick: "someone", Arguments: []string{"bye"}}
+	holderQuit := &irc.Event{Code: "QUIT", Nick: "cinemabot", Arguments: []string{"GHOST command used by cinemabot_"}}
+	if bot.ghostAnswered(holderQuit, now) {
+		t.Error("expected a QUIT without a pending GHOST to be ignored")
+	}
+
+	bot.regainNick("cinemabot_", now)
+	if len(sender.messages) != 1 || sender.targets[0] != "NickServ" {
+		t.Fatalf("expected one GHOST to NickServ, got %v to %v", sender.messages, sender.targets)
+	}
+
+	identified := &irc.Event{Code: "NOTICE", Nick: "NickServ", Arguments: []string{"cinemabot_", "Password accepted"}}
+	notOnline := &irc.Event{Code: "NOTICE", Nick: "NickServ", Arguments: []string{"cinemabot_", "cinemabot is not online."}}
+	impostor := &irc.Event{Code: "NOTICE", Nick: "mallory", Arguments: []string{"cinemabot_", "cinemabot has been ghosted."}}
+	for _, e := range []*irc.Event{stranger, identified, impostor} {
+		if bot.ghostAnswered(e, now) {
+			t.Errorf("expected %s from %s %q not to free the nick", e.Code, e.Nick, e.Message())
+		}
+	}
+	if !bot.ghostAnswered(notOnline, now) {
+		t.Error("expected NickServ's answer about the nick to free it")
+	}
+	if !bot.ghostAnswered(holderQuit, now.Add(time.Second)) {
+		t.Error("expected the holder's QUIT to free the nick")
+	}
+	if bot.ghostAnswered(holderQuit, now.Add(2*time.Second)) {
+		t.Error("expected the GHOST to be settled after the holder's QUIT")
+	}
+
+	bot.regainNick("cinemabot_", now)
+	if bot.ghostAnswered(holderQuit, now.Add(ghostTimeout+time.Second)) {
+		t.Error("expected a QUIT after ghostTimeout to be ignored")
+	}
+
+	bot.regainNick("cinemabot_", now)
+	renamed := &irc.Event{Code: "NICK", Nick: "cinemabot", Arguments: []string{"somebody"}}
+	if !bot.ghostAnswered(renamed, now) {
+		t.Error("expected the holder changing nick to free it")
+	}
+	ours := &irc.Event{Code: "NICK", Nick: "cinemabot_", Arguments: []string{"cinemabot"}}
+	if bot.ghostAnswered(ours, now) || bot.ghostAnswered(holderQuit, now) {
+		t.Error("expected our own switch to the nick to settle the GHOST")
+	}
+}
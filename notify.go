@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// Event is something that happened to a showtime, passed to every notifier
+type Event struct {
+	// Kind is "created", "deleted", "cancelled", "uncancelled" or "reminded"
+	Kind     string
+	Showtime Showtime
+}
+
+// Notifier is told about showtime events. Notify is called while a command
+// or background loop is running, so anything slow belongs in a goroutine.
+type Notifier interface {
+	Notify(event Event)
+}
+
+// notify passes event to every configured notifier, announcing it in
+// notify_channel through bot's sender so quiet mode applies to it too. Events
+// on staging are only logged, so test data never reaches webhooks or the
+// organizers.
+func (bot *command) notify(event Event) {
+	if bot.onStaging {
+		log.Printf("Staging event: %s", event.describe())
+		return
+	}
+	for _, notifier := range bot.notifiers {
+		if channel, ok := notifier.(channelNotifier); ok {
+			channel.bot = bot
+			notifier = channel
+		}
+		notifier.Notify(event)
+	}
+}
+
+// configureNotifiers rebuilds bot.notifiers from the config: the log always,
+// plus webhooks and notify_channel when they are set
+func (bot *CinemaBot) configureNotifiers() {
+	notifiers := []Notifier{logNotifier{}}
+	if len(bot.config.Webhooks) > 0 {
+		notifiers = append(notifiers, webhookNotifier{urls: bot.config.Webhooks})
+	}
+	if bot.config.NotifyChannel != "" {
+		notifiers = append(notifiers, channelNotifier{channel: bot.config.NotifyChannel})
+	}
+	bot.notifiers = notifiers
+}
+
+// eventVerbs phrase each event kind for people reading the log or a channel
+var eventVerbs = map[string]string{
+	"created":     "Scheduled",
+	"deleted":     "Deleted",
+	"cancelled":   "Cancelled",
+	"uncancelled": "Restored",
+	"reminded":    "Sent reminders for",
+}
+
+// describe renders the event as "Scheduled [id] Title", falling back to the
+// raw kind for one without a verb
+func (event Event) describe() string {
+	verb, ok := eventVerbs[event.Kind]
+	if !ok {
+		verb = event.Kind
+	}
+	return fmt.Sprintf("%s [%s] %s", verb, event.Showtime.ID, event.Showtime.Title)
+}
+
+// logNotifier writes every event to the log
+type logNotifier struct{}
+
+func (logNotifier) Notify(event Event) {
+	log.Printf("Event: %s at %s", event.describe(), event.Showtime.DateTime.UTC().Format(time.RFC3339))
+}
+
+// channelNotifier announces events in another channel, such as an
+// organizers' channel. bot is the command or background pass the event came
+// from, filled in by notify.
+type channelNotifier struct {
+	bot     *command
+	channel string
+}
+
+func (n channelNotifier) Notify(event Event) {
+	n.bot.sender.Privmsg(n.channel, fmt.Sprintf("%s - %s", event.describe(), n.bot.formatTime(event.Showtime.DateTime)))
+}
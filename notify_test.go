@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// recordingNotifier keeps every event it is told about
+type recordingNotifier struct {
+	events []Event
+}
+
+func (n *recordingNotifier) Notify(event Event) {
+	n.events = append(n.events, event)
+}
+
+func (n *recordingNotifier) kinds() []string {
+	var kinds []string
+	for _, event := range n.events {
+		kinds = append(kinds, event.Kind+" "+event.Showtime.ID)
+	}
+	return kinds
+}
+
+func TestNotify_CommandsAndReminders(t *testing.T) {
+	bot, _ := newTestBot()
+	recorder := &recordingNotifier{}
+	bot.notifiers = []Notifier{recorder}
+	now := time.Now().UTC()
+	bot.store.Create(Showtime{ID: "soon", Title: "Vertigo", DateTime: now.Add(5 * time.Minute), CreatedBy: "alice"})
+	bot.store.AddReminder("soon", "carol")
+
+	bot.handleShowtimeCommand(`.showtime -create -id=a -title="Casablanca" -date="2099-06-13 19:00"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -cancel="a"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -uncancel="a"`, "alice")
+	bot.handleShowtimeCommand(`.showtime -delete="a"`, "alice")
+	bot.sendDueReminders(now)
+
+	expected := []string{"created a", "cancelled a", "uncancelled a", "deleted a", "reminded soon"}
+	if !equalStringSlices(recorder.kinds(), expected) {
+		t.Errorf("expected %v, got %v", expected, recorder.kinds())
+	}
+}
+
+func TestNotify_StagingAndQuiet(t *testing.T) {
+	bot, sender := newTestBot()
+	recorder := &recordingNotifier{}
+	bot.CinemaBot.notifiers = []Notifier{recorder, channelNotifier{channel: "#ops"}}
+	event := Event{Kind: "created", Showtime: Showtime{ID: "a", Title: "Casablanca"}}
+
+	bot.staging = newMemoryStore()
+	bot.CinemaBot.newCommand(time.Now()).notify(event)
+	bot.staging = nil
+	if len(recorder.events) != 0 || len(sender.messages) != 0 {
+		t.Errorf("expected staging events to stay out of notifiers, got %v and %v", recorder.kinds(), sender.messages)
+	}
+
+	bot.quietUntil = time.Now().Add(time.Hour)
+	bot.CinemaBot.newCommand(time.Now()).notify(event)
+	if !equalStringSlices(recorder.kinds(), []string{"created a"}) || len(sender.messages) != 0 {
+		t.Errorf("expected quiet to silence only notify_channel, got %v and %v", recorder.kinds(), sender.messages)
+	}
+}
+
+func TestConfigureNotifiers(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.configureNotifiers()
+	if len(bot.CinemaBot.notifiers) != 1 {
+		t.Errorf("expected only the log notifier, got %+v", bot.CinemaBot.notifiers)
+	}
+
+	bot.config.Webhooks = []string{"http://127.0.0.1:0/hook"}
+	bot.config.NotifyChannel = "#ops"
+	bot.configureNotifiers()
+	if len(bot.CinemaBot.notifiers) != 3 {
+		t.Errorf("expected log, webhook and channel notifiers, got %+v", bot.CinemaBot.notifiers)
+	}
+
+	channel := channelNotifier{bot: bot, channel: "#ops"}
+	channel.Notify(Event{Kind: "cancelled", Showtime: Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)}})
+	expected := []string{"Cancelled [a] Casablanca - 2025-06-13 19:00:00 UTC"}
+	if !equalStringSlices(sender.messages, expected) || sender.targets[0] != "#ops" {
+		t.Errorf("expected %v to #ops, got %v to %v", expected, sender.messages, sender.targets)
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// omdbBaseURL is a variable so tests can point it at a local server
+	omdbBaseURL = "https://www.omdbapi.com/"
+
+	omdbClient = &http.Client{Timeout: 5 * time.Second}
+)
+
+// MovieInfo holds the OMDb details shown by .showtime -info. Empty fields mean
+// OMDb had no value, and an all-empty MovieInfo records a title with no match.
+type MovieInfo struct {
+	IMDbRating string
+	Runtime    string
+}
+
+// RuntimeDuration parses OMDb's "102 min" runtime, returning zero when the
+// runtime is unknown or in another format
+func (info MovieInfo) RuntimeDuration() time.Duration {
+	minutes, err := strconv.Atoi(strings.TrimSuffix(info.Runtime, " min"))
+	if err != nil || minutes <= 0 {
+		return 0
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// lookupOMDb fetches a title from OMDb, returning nil when OMDb has no match
+func lookupOMDb(apiKey, title string) (*MovieInfo, error) {
+	query := url.Values{}
+	query.Set("apikey", apiKey)
+	query.Set("t", title)
+
+	resp, err := omdbClient.Get(omdbBaseURL + "?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var result struct {
+		Response   string `json:"Response"`
+		Error      string `json:"Error"`
+		IMDbRating string `json:"imdbRating"`
+		Runtime    string `json:"Runtime"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if result.Response != "True" {
+		if result.Error == "Movie not found!" {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("omdb error: %s", result.Error)
+	}
+
+	info := &MovieInfo{IMDbRating: result.IMDbRating, Runtime: result.Runtime}
+	if info.IMDbRating == "N/A" {
+		info.IMDbRating = ""
+	}
+	if info.Runtime == "N/A" {
+		info.Runtime = ""
+	}
+	return info, nil
+}
+
+// movieInfo returns OMDb details for title from the cache, fetching and caching
+// them on a miss. It returns nil when OMDb isn't configured, has no match, or
+// the lookup fails, so callers just omit the extra fields.
+func (bot *command) movieInfo(title string) *MovieInfo {
+	if bot.config.OMDbAPIKey == "" {
+		return nil
+	}
+
+	cached, err := bot.store.CachedMovieInfo(title)
+	if err != nil {
+		log.Printf("Error reading cached movie info for %q: %v", title, err)
+	} else if cached != nil {
+		return cached
+	}
+
+	info, err := lookupOMDb(bot.config.OMDbAPIKey, title)
+	if err != nil {
+		// Not cached so a transient failure is retried next time
+		log.Printf("OMDb lookup for %q failed: %v", title, err)
+		return nil
+	}
+	if info == nil {
+		info = &MovieInfo{}
+	}
+
+	if err := bot.store.CacheMovieInfo(title, *info); err != nil {
+		log.Printf("Error caching movie info for %q: %v", title, err)
+	}
+	return info
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// withOMDbServer points the OMDb client at handler and counts its requests
+func withOMDbServer(t *testing.T, handler http.HandlerFunc) *int {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		handler(w, r)
+	}))
+	oldBase := omdbBaseURL
+	omdbBaseURL = server.URL + "/"
+	t.Cleanup(func() {
+		omdbBaseURL = oldBase
+		server.Close()
+	})
+	return &requests
+}
+
+func TestShowtimeInfo_WithOMDb(t *testing.T) {
+	requests := withOMDbServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("t") != "Casablanca" || r.URL.Query().Get("apikey") != "key" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `{"Response": "True", "imdbRating": "8.5", "Runtime": "102 min"}`)
+	})
+
+	bot, sender := newTestBot()
+	bot.config.OMDbAPIKey = "key"
+	bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.showtimeInfo([]string{".showtime", "-info=movie"}, false)
+	bot.showtimeInfo([]string{".showtime", "-info=movie"}, false)
+
+	expected := "[movie] Casablanca - 2025-06-13 19:00:00 UTC (by alice) | IMDb 8.5/10 | 102 min"
+	if !equalStringSlices(sender.messages, []string{expected, expected}) {
+		t.Errorf("expected %q twice, got %v", expected, sender.messages)
+	}
+	if *requests != 1 {
+		t.Errorf("expected second lookup to be cached, got %d requests", *requests)
+	}
+}
+
+func TestShowtimeInfo_OMDbNotFound(t *testing.T) {
+	requests := withOMDbServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"Response": "False", "Error": "Movie not found!"}`)
+	})
+
+	bot, sender := newTestBot()
+	bot.config.OMDbAPIKey = "key"
+	bot.store.Create(Showtime{ID: "home", Title: "Home Movies", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.showtimeInfo([]string{".showtime", "-info=home"}, false)
+	bot.showtimeInfo([]string{".showtime", "-info=home"}, false)
+
+	expected := "[home] Home Movies - 2025-06-13 19:00:00 UTC (by alice)"
+	if len(sender.messages) != 2 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+	if *requests != 1 {
+		t.Errorf("expected missing match to be cached, got %d requests", *requests)
+	}
+}
+
+func TestShowtimeInfo_OMDbFailure(t *testing.T) {
+	withOMDbServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+
+	bot, sender := newTestBot()
+	bot.config.OMDbAPIKey = "key"
+	bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.showtimeInfo([]string{".showtime", "-info=movie"}, false)
+
+	expected := []string{"[movie] Casablanca - 2025-06-13 19:00:00 UTC (by alice)"}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	if cached, _ := bot.store.CachedMovieInfo("Casablanca"); cached != nil {
+		t.Errorf("expected failures not to be cached, got %+v", cached)
+	}
+}
+
+func TestShowtimeInfo_NotFound(t *testing.T) {
+	bot, sender := newTestBot()
+
+	bot.showtimeInfo([]string{".showtime", "-info=missing"}, false)
+
+	expected := []string{"Showtime with ID 'missing' not found."}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestMovieInfo_RuntimeDuration(t *testing.T) {
+	tests := map[string]time.Duration{
+		"102 min": 102 * time.Minute,
+		"":        0,
+		"1h 42m":  0,
+	}
+	for runtime, expected := range tests {
+		if got := (MovieInfo{Runtime: runtime}).RuntimeDuration(); got != expected {
+			t.Errorf("RuntimeDuration(%q): expected %v, got %v", runtime, expected, got)
+		}
+	}
+}
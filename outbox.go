@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// maxPendingMessages caps how many replies are held while disconnected;
+	// the oldest are dropped first
+	maxPendingMessages = 20
+	// pendingMessageTTL is how long a held reply is still worth sending
+	pendingMessageTTL = 2 * time.Minute
+)
+
+// pendingMessage is a reply held back while the connection was down
+type pendingMessage struct {
+	target  string
+	message string
+	at      time.Time
+}
+
+// outbox is a Sender that holds replies while disconnected and delivers them
+// once the bot is back in its channel, so a command answered as the
+// connection drops isn't lost
+type outbox struct {
+	sender    Sender
+	connected func() bool
+	now       func() time.Time
+
+	mu      sync.Mutex
+	pending []pendingMessage
+}
+
+func newOutbox(sender Sender, connected func() bool) *outbox {
+	return &outbox{sender: sender, connected: connected, now: time.Now}
+}
+
+func (o *outbox) Privmsg(target, message string) {
+	if o.connected() {
+		o.sender.Privmsg(target, message)
+		return
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.pending = append(o.pending, pendingMessage{target, message, o.now()})
+	if len(o.pending) > maxPendingMessages {
+		o.pending = o.pending[len(o.pending)-maxPendingMessages:]
+	}
+}
+
+// flush sends every held reply that is still fresh and forgets the rest
+func (o *outbox) flush() {
+	o.mu.Lock()
+	pending := o.pending
+	o.pending = nil
+	o.mu.Unlock()
+
+	now := o.now()
+	stale := 0
+	for _, held := range pending {
+		if now.Sub(held.at) > pendingMessageTTL {
+			stale++
+			continue
+		}
+		o.sender.Privmsg(held.target, held.message)
+	}
+	if stale > 0 {
+		log.Printf("Dropped %d stale message(s) queued while disconnected", stale)
+	}
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestOutbox_SendsDirectlyWhenConnected(t *testing.T) {
+	capture := &captureSender{}
+	box := newOutbox(capture, func() bool { return true })
+
+	box.Privmsg("#testchan", "hello")
+
+	if !equalStringSlices(capture.messages, []string{"hello"}) {
+		t.Errorf("expected message sent immediately, got %v", capture.messages)
+	}
+}
+
+func TestOutbox_HoldsUntilFlush(t *testing.T) {
+	capture := &captureSender{}
+	connected := false
+	now := time.Date(2025, 6, 13, 20, 0, 0, 0, time.UTC)
+	box := newOutbox(capture, func() bool { return connected })
+	box.now = func() time.Time { return now }
+
+	box.Privmsg("#testchan", "stale")
+	now = now.Add(pendingMessageTTL)
+	box.Privmsg("alice", "fresh")
+	now = now.Add(time.Second)
+
+	if len(capture.messages) != 0 {
+		t.Fatalf("expected nothing sent while disconnected, got %v", capture.messages)
+	}
+
+	connected = true
+	box.flush()
+
+	if !equalStringSlices(capture.messages, []string{"fresh"}) || !equalStringSlices(capture.targets, []string{"alice"}) {
+		t.Errorf("expected only the fresh message, got %v to %v", capture.messages, capture.targets)
+	}
+
+	box.flush()
+	if len(capture.messages) != 1 {
+		t.Errorf("expected flush to empty the queue, got %v", capture.messages)
+	}
+}
+
+func TestOutbox_DropsOldestBeyondCap(t *testing.T) {
+	capture := &captureSender{}
+	box := newOutbox(capture, func() bool { return false })
+
+	for i := 0; i < maxPendingMessages+2; i++ {
+		box.Privmsg("#testchan", fmt.Sprint(i))
+	}
+	box.flush()
+
+	if len(capture.messages) != maxPendingMessages || capture.messages[0] != "2" {
+		t.Errorf("expected the newest %d messages, got %v", maxPendingMessages, capture.messages)
+	}
+}
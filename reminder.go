@@ -0,0 +1,114 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// reminderLead is how long before a showtime subscribers are DMed
+const reminderLead = 15 * time.Minute
+
+// reminderInterval is how often the reminder loop looks for due showtimes
+const reminderInterval = time.Minute
+
+const remindUsage = "Usage: .remind <id> | .remind -cancel <id>"
+
+// handleRemindCommand subscribes nick to a DM shortly before a showtime, or
+// cancels that subscription with -cancel
+func (bot *command) handleRemindCommand(args []string, nick string) {
+	cancel := len(args) == 3 && args[1] == "-cancel"
+	if len(args) != 2 && !cancel {
+		bot.sender.Privmsg(bot.config.Channel, remindUsage)
+		return
+	}
+	id := args[len(args)-1]
+
+	if cancel {
+		removed, err := bot.store.RemoveReminder(id, nick)
+		if err != nil {
+			log.Printf("Error removing reminder: %v", err)
+			bot.replyError(err, "Error cancelling reminder.")
+			return
+		}
+		if !removed {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: You have no reminder for '%s'.", nick, id))
+			return
+		}
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: Reminder for '%s' cancelled.", nick, id))
+		return
+	}
+
+	showtime, err := bot.store.GetByID(id)
+	if err != nil {
+		log.Printf("Error getting showtime: %v", err)
+		bot.replyError(err, "Error retrieving showtime.")
+		return
+	}
+	if showtime == nil {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Showtime with ID '%s' not found.", id))
+		return
+	}
+	if !showtime.DateTime.After(time.Now().UTC()) {
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("%s: %s has already started.", nick, showtime.Title))
+		return
+	}
+
+	if err := bot.store.AddReminder(id, nick); err != nil {
+		log.Printf("Error adding reminder: %v", err)
+		bot.replyError(err, "Error saving reminder.")
+		return
+	}
+	bot.sender.Privmsg(bot.config.Channel,
+		fmt.Sprintf("%s: I'll DM you %s before %s starts.", nick, pluralize(int(reminderLead.Minutes()), "minute"), showtime.Title))
+}
+
+// runReminders periodically DMs subscribers of showtimes that are about to
+// start
+func (bot *CinemaBot) runReminders() {
+	ticker := time.NewTicker(reminderInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		bot.mu.RLock()
+		bot.snapshot().sendDueReminders(now.UTC())
+		bot.mu.RUnlock()
+	}
+}
+
+// sendDueReminders DMs everyone subscribed to a showtime starting within
+// reminderLead of now, then drops those subscriptions so nobody is pinged twice
+func (bot *command) sendDueReminders(now time.Time) {
+	upcoming, err := bot.store.Upcoming(now, 0)
+	if err != nil {
+		log.Printf("Error getting upcoming showtimes for reminders: %v", err)
+		return
+	}
+
+	for _, showtime := range upcoming {
+		until := showtime.DateTime.Sub(now)
+		if until > reminderLead {
+			break
+		}
+
+		nicks, err := bot.store.Reminders(showtime.ID)
+		if err != nil {
+			log.Printf("Error getting reminders for %s: %v", showtime.ID, err)
+			continue
+		}
+		if len(nicks) == 0 {
+			continue
+		}
+
+		message := fmt.Sprintf("Reminder: [%s] %s - %s (%s)", showtime.ID, showtime.Title,
+			bot.formatTime(showtime.DateTime), bot.formatTimeUntil(until, coarseGranularity))
+		for _, nick := range nicks {
+			bot.sender.Privmsg(nick, message)
+		}
+		bot.notify(Event{Kind: "reminded", Showtime: showtime})
+
+		if err := bot.store.ClearReminders(showtime.ID); err != nil {
+			log.Printf("Error clearing reminders for %s: %v", showtime.ID, err)
+		}
+	}
+}
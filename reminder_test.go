@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHandleRemindCommand_SubscribeAndCancel(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Now().UTC().Add(time.Hour), CreatedBy: "alice"})
+
+	bot.handleRemindCommand([]string{".remind", "movie"}, "bob")
+	if nicks, _ := bot.store.Reminders("movie"); !equalStringSlices(nicks, []string{"bob"}) {
+		t.Errorf("expected [bob] subscribed, got %v", nicks)
+	}
+	if expected := "bob: I'll DM you 15 minutes before Casablanca starts."; sender.messages[0] != expected {
+		t.Errorf("expected %q, got %q", expected, sender.messages[0])
+	}
+
+	bot.handleRemindCommand([]string{".remind", "-cancel", "movie"}, "bob")
+	if nicks, _ := bot.store.Reminders("movie"); len(nicks) != 0 {
+		t.Errorf("expected no subscribers, got %v", nicks)
+	}
+	if expected := "bob: Reminder for 'movie' cancelled."; sender.messages[1] != expected {
+		t.Errorf("expected %q, got %q", expected, sender.messages[1])
+	}
+
+	bot.handleRemindCommand([]string{".remind", "-cancel", "movie"}, "bob")
+	if expected := "bob: You have no reminder for 'movie'."; sender.messages[2] != expected {
+		t.Errorf("expected %q, got %q", expected, sender.messages[2])
+	}
+}
+
+func TestHandleRemindCommand_Rejects(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.store.Create(Showtime{ID: "old", Title: "Metropolis", DateTime: time.Now().UTC().Add(-time.Hour), CreatedBy: "alice"})
+
+	bot.handleRemindCommand([]string{".remind"}, "bob")
+	bot.handleRemindCommand([]string{".remind", "missing"}, "bob")
+	bot.handleRemindCommand([]string{".remind", "old"}, "bob")
+
+	expected := []string{
+		remindUsage,
+		"Showtime with ID 'missing' not found.",
+		"bob: Metropolis has already started.",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestSendDueReminders(t *testing.T) {
+	bot, sender := newTestBot()
+	now := time.Date(2025, 6, 13, 18, 50, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "soon", Title: "Casablanca", DateTime: now.Add(10 * time.Minute), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "later", Title: "Vertigo", DateTime: now.Add(time.Hour), CreatedBy: "alice"})
+	bot.store.AddReminder("soon", "bob")
+	bot.store.AddReminder("soon", "carol")
+	bot.store.AddReminder("later", "bob")
+
+	bot.sendDueReminders(now)
+
+	if !equalStringSlices(sender.targets, []string{"bob", "carol"}) {
+		t.Errorf("expected DMs to bob and carol, got %v", sender.targets)
+	}
+	expected := "Reminder: [soon] Casablanca - 2025-06-13 19:00:00 UTC (In 10 minutes)"
+	if len(sender.messages) == 0 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+	if nicks, _ := bot.store.Reminders("soon"); len(nicks) != 0 {
+		t.Errorf("expected sent reminders to be cleared, got %v", nicks)
+	}
+	if nicks, _ := bot.store.Reminders("later"); !equalStringSlices(nicks, []string{"bob"}) {
+		t.Errorf("expected later reminder to remain, got %v", nicks)
+	}
+
+	bot.sendDueReminders(now.Add(time.Minute))
+	if len(sender.messages) != 2 {
+		t.Errorf("expected no repeat reminders, got %v", sender.messages)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// handleSelftestCommand checks the database and IRC connection and replies
+// with one line summarizing both and whether everything passed
+func (bot *command) handleSelftestCommand() {
+	var results []string
+	passed := true
+
+	stats, err := bot.store.Stats()
+	if err != nil {
+		log.Printf("Self-test database check failed: %v", err)
+		results = append(results, fmt.Sprintf("DB FAILED: %v", err))
+		passed = false
+	} else {
+		results = append(results, fmt.Sprintf("DB ok (%s, %s, %s)",
+			pluralize(stats.Showtimes, "showtime"), pluralize(stats.Reminders, "reminder"),
+			pluralize(stats.AuditEntries, "audit log row")))
+	}
+
+	switch {
+	case bot.conn == nil || !bot.conn.Connected():
+		results = append(results, "IRC FAILED: not connected")
+		passed = false
+	case !strings.EqualFold(bot.conn.GetNick(), bot.config.Nick):
+		results = append(results, fmt.Sprintf("IRC FAILED: using nick %s instead of %s", bot.conn.GetNick(), bot.config.Nick))
+		passed = false
+	default:
+		results = append(results, fmt.Sprintf("IRC ok (%s in %s)", bot.conn.GetNick(), bot.config.Channel))
+	}
+
+	verdict := "PASS"
+	if !passed {
+		verdict = "FAIL"
+	}
+	bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Self-test %s: %s", verdict, strings.Join(results, " | ")))
+}
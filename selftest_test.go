@@ -0,0 +1,30 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+func TestHandleSelftestCommand(t *testing.T) {
+	bot, sender := newTestBot()
+	bot.config.Nick = "marquee"
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	bot.store.AddReminder("a", "bob")
+
+	bot.handleSelftestCommand()
+	bot.conn = irc.IRC("marquee_", "marquee")
+	bot.handleSelftestCommand()
+	bot.conn = irc.IRC("marquee", "marquee")
+	bot.handleSelftestCommand()
+
+	expected := []string{
+		"Self-test FAIL: DB ok (1 showtime, 1 reminder, 0 audit log rows) | IRC FAILED: not connected",
+		"Self-test FAIL: DB ok (1 showtime, 1 reminder, 0 audit log rows) | IRC FAILED: using nick marquee_ instead of marquee",
+		"Self-test PASS: DB ok (1 showtime, 1 reminder, 0 audit log rows) | IRC ok (marquee in #testchan)",
+	}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"time"
+)
+
+// stagingTag starts every reply sent while the staging database is active
+const stagingTag = "[staging] "
+
+// handleStagingCommand switches commands between the production database and
+// staging_database_path with "on" and "off", or says which one is active.
+// Only commands use staging; reminders, the digest and the other background
+// loops stay on production. It runs holding mu exclusively.
+func (bot *command) handleStagingCommand(args []string, nick string) {
+	if len(args) == 1 {
+		if bot.staging != nil {
+			bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Using the staging database (%s). Use .staging off to switch back.", bot.config.StagingDatabasePath))
+		} else {
+			bot.sender.Privmsg(bot.config.Channel, "Using the production database.")
+		}
+		return
+	}
+
+	switch args[1] {
+	case "on":
+		if bot.config.StagingDatabasePath == "" {
+			bot.sender.Privmsg(bot.config.Channel, "No staging_database_path is configured.")
+			return
+		}
+		store, err := NewSQLiteStore(bot.config.StagingDatabasePath)
+		if err != nil {
+			log.Printf("Error opening staging database %s: %v", bot.config.StagingDatabasePath, err)
+			bot.sender.Privmsg(bot.config.Channel, "Error opening the staging database.")
+			return
+		}
+		if bot.config.QueryTimeoutSeconds > 0 {
+			store.timeout = time.Duration(bot.config.QueryTimeoutSeconds) * time.Second
+		}
+		bot.closeStaging()
+		bot.pendingClear.cancel()
+		bot.staging = store
+		log.Printf("%s switched to the staging database %s", nick, bot.config.StagingDatabasePath)
+		bot.sender.Privmsg(bot.config.Channel, fmt.Sprintf("Now using the staging database (%s). Every command reads and edits it, and replies start with %s, until .staging off.", bot.config.StagingDatabasePath, stagingTag))
+	case "off":
+		bot.closeStaging()
+		log.Printf("%s switched back to the production database", nick)
+		bot.sender.Privmsg(bot.config.Channel, "Back on the production database.")
+	default:
+		bot.sender.Privmsg(bot.config.Channel, "Usage: .staging on | .staging off")
+	}
+}
+
+// closeStaging closes the staging store, if one is open, and goes back to
+// production. A command still running against it gets an error from the
+// closed store. The caller holds mu exclusively.
+func (bot *CinemaBot) closeStaging() {
+	if bot.staging == nil {
+		return
+	}
+	bot.pendingClear.cancel()
+	if err := bot.staging.Close(); err != nil {
+		log.Printf("Error closing staging database: %v", err)
+	}
+	bot.staging = nil
+}
+
+// taggedSender prefixes every message with tag
+type taggedSender struct {
+	sender Sender
+	tag    string
+}
+
+func (s taggedSender) Privmsg(target, message string) {
+	s.sender.Privmsg(target, s.tag+message)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestStagingCommand(t *testing.T) {
+	bot, sender := newSQLiteTestBot(t)
+	bot.config.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"alice": true}}
+	bot.config.StagingDatabasePath = filepath.Join(t.TempDir(), "staging.db")
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: time.Date(2099, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", ".staging on")
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", `.showtime -create -id=b -title="Vertigo" -date="2099-06-14 20:00"`)
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", ".nextmovie")
+	if last := sender.messages[len(sender.messages)-1]; !strings.HasPrefix(last, "[staging] ") || !strings.Contains(last, "Vertigo") {
+		t.Errorf("expected a tagged reply from the staging schedule, got %q", last)
+	}
+
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", ".staging off")
+	if existing, _ := bot.store.GetByID("b"); existing != nil {
+		t.Errorf("expected the staging showtime to stay out of production")
+	}
+	if existing, _ := bot.store.GetByID("a"); existing == nil {
+		t.Errorf("expected production to be untouched")
+	}
+
+	sender.messages = nil
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", ".staging")
+	expected := []string{"Using the production database."}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+}
+
+func TestStagingCommand_BackgroundStaysOnProduction(t *testing.T) {
+	bot, sender := newSQLiteTestBot(t)
+	bot.config.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"alice": true}}
+	bot.config.StagingDatabasePath = filepath.Join(t.TempDir(), "staging.db")
+	now := time.Now().UTC().Truncate(time.Minute)
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: now.Add(time.Hour), CreatedBy: "alice"})
+
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", ".staging on")
+	defer bot.Close()
+	bot.handlePrivmsg("#testchan", "alice", "user/alice", `.showtime -create -id=b -title="Vertigo" -date="`+now.Add(2*time.Hour).Format("2006-01-02 15:04")+`"`)
+
+	sender.messages = nil
+	bot.postDigest(now)
+	if len(sender.messages) != 1 || !strings.Contains(sender.messages[0], "[a] Casablanca") ||
+		strings.Contains(sender.messages[0], "Vertigo") || strings.HasPrefix(sender.messages[0], stagingTag) {
+		t.Errorf("expected the digest to come from production, got %v", sender.messages)
+	}
+}
+
+func TestStagingCommand_ExactName(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	bot.config.AuthorizedNicks = AuthorizedNicks{Global: map[string]bool{"alice": true}}
+	bot.config.StagingDatabasePath = filepath.Join(t.TempDir(), "staging.db")
+
+	for _, message := range []string{".quieter on", ".quietly on", ".stagingx on"} {
+		bot.handlePrivmsg("#testchan", "alice", "user/alice", message)
+		if bot.staging != nil {
+			bot.Close()
+			t.Fatalf("expected %q not to switch to staging", message)
+		}
+	}
+}
+
+func TestStagingCommand_NotConfigured(t *testing.T) {
+	bot, sender := newTestBot()
+
+	bot.handleStagingCommand([]string{".staging", "on"}, "alice")
+
+	expected := []string{"No staging_database_path is configured."}
+	if !equalStringSlices(sender.messages, expected) {
+		t.Errorf("expected %v, got %v", expected, sender.messages)
+	}
+	if bot.staging != nil {
+		t.Errorf("expected to stay on production")
+	}
+}
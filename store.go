@@ -0,0 +1,950 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// ShowtimeStore persists showtimes independently of the IRC side of the bot.
+// It is made of focused interfaces so code and test fakes that only need one
+// part of it can depend on just that part.
+type ShowtimeStore interface {
+	ScheduleStore
+	ReminderStore
+	LogStore
+	MovieCache
+	AdminStore
+}
+
+// ScheduleStore reads and edits the showtimes themselves
+type ScheduleStore interface {
+	Create(showtime Showtime) error
+	// CreateAll inserts showtimes in one transaction, skipping any whose id is
+	// already taken, and returns the ones it inserted
+	CreateAll(showtimes []Showtime) ([]Showtime, error)
+	// Update overwrites the stored showtime with the same id
+	Update(showtime Showtime) error
+	// UpdateAll overwrites every given showtime in one transaction, so either
+	// all of them change or none do
+	UpdateAll(showtimes []Showtime) error
+	// Delete removes the showtime and its reminders in one transaction
+	Delete(id string) error
+	// DeleteAll removes every showtime and reminder in one transaction and
+	// returns how many showtimes there were
+	DeleteAll() (int, error)
+	// GetByID returns nil without an error when no showtime has the id
+	GetByID(id string) (*Showtime, error)
+	// RawDateTime returns the datetime column of the showtime with id exactly
+	// as stored, or "" when no showtime has the id
+	RawDateTime(id string) (string, error)
+	// SimilarIDs returns up to limit ids that contain id or that id starts
+	// with, ignoring case, for suggesting corrections to a mistyped id
+	SimilarIDs(id string, limit int) ([]string, error)
+	// List returns the showtimes matching filter in start order
+	List(filter ShowtimeFilter) ([]Showtime, error)
+	// Next returns the earliest showtime starting after now, or nil.
+	// Cancelled showtimes are skipped here, in Upcoming and in Current.
+	Next(now time.Time) (*Showtime, error)
+	// Upcoming returns showtimes starting after now in start order, at most
+	// limit of them unless limit is zero
+	Upcoming(now time.Time, limit int) ([]Showtime, error)
+	// Current returns the latest showtime that started within window before
+	// now, or nil
+	Current(now time.Time, window time.Duration) (*Showtime, error)
+	// TopCreators returns who created the most showtimes, busiest first
+	TopCreators(limit int) ([]CreatorCount, error)
+	// SetRuntime sets the runtime of the showtime with id idOrTitle or, when
+	// there is none, of every showtime titled idOrTitle (ignoring case), and
+	// returns how many were changed
+	SetRuntime(idOrTitle string, minutes int) (int, error)
+	// ByTitle returns the showtimes titled title, ignoring case, or whose title
+	// contains it when contains is set, oldest created first
+	ByTitle(title string, contains bool) ([]Showtime, error)
+	// Titles returns every distinct title ever scheduled in alphabetical
+	// order, ignoring case, limited to those containing search when it isn't
+	// empty
+	Titles(search string) ([]string, error)
+}
+
+// ReminderStore keeps who asked to be reminded of which showtime
+type ReminderStore interface {
+	// AddReminder subscribes nick to a DM before the showtime starts
+	AddReminder(showtimeID, nick string) error
+	// RemoveReminder reports whether nick had a reminder for the showtime
+	RemoveReminder(showtimeID, nick string) (bool, error)
+	// Reminders returns the nicks subscribed to the showtime
+	Reminders(showtimeID string) ([]string, error)
+	ClearReminders(showtimeID string) error
+}
+
+// LogStore keeps the audit log and the channel log
+type LogStore interface {
+	// Audit appends an entry to the audit log
+	Audit(entry AuditEntry) error
+	// PruneAudit deletes audit log entries from before cutoff and returns
+	// how many were removed
+	PruneAudit(cutoff time.Time) (int, error)
+	// LogChannelMessage appends a message to the channel log
+	LogChannelMessage(entry ChannelMessage) error
+	// PruneChannelLog deletes channel log entries from before cutoff and
+	// returns how many were removed
+	PruneChannelLog(cutoff time.Time) (int, error)
+}
+
+// MovieCache remembers movie details fetched from OMDb
+type MovieCache interface {
+	// CachedMovieInfo returns previously fetched OMDb details for title, or
+	// nil when the title has never been looked up
+	CachedMovieInfo(title string) (*MovieInfo, error)
+	CacheMovieInfo(title string, info MovieInfo) error
+}
+
+// AdminStore is the upkeep of the database as a whole
+type AdminStore interface {
+	// Stats checks the database is reachable and counts its rows
+	Stats() (StoreStats, error)
+	// Maintain refreshes query planner statistics and, when vacuum is set,
+	// rebuilds the database to reclaim space left by deletes
+	Maintain(vacuum bool) error
+	Close() error
+}
+
+// ShowtimeFilter narrows List; zero fields don't filter
+type ShowtimeFilter struct {
+	// From and To bound the start time, inclusive
+	From time.Time
+	To   time.Time
+	// Tag keeps only showtimes carrying this (lowercase) tag
+	Tag string
+}
+
+// StoreStats are the row counts .selftest reports
+type StoreStats struct {
+	Showtimes    int
+	Reminders    int
+	AuditEntries int
+}
+
+// CreatorCount is how many showtimes a nick has created
+type CreatorCount struct {
+	Nick  string
+	Count int
+}
+
+// defaultQueryTimeout bounds each store call unless query_timeout_seconds
+// says otherwise
+const defaultQueryTimeout = 10 * time.Second
+
+// SQLiteStore is the ShowtimeStore backed by a SQLite database file
+type SQLiteStore struct {
+	db   *sql.DB
+	path string
+	// timeout bounds every query; maintenance is exempt since VACUUM can
+	// legitimately take a while
+	timeout time.Duration
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Every connection to ":memory:" gets its own empty database, so keep the
+	// pool to a single connection that holds the schema
+	if path == ":memory:" {
+		db.SetMaxOpenConns(1)
+	}
+
+	// Test connection
+	if err := db.Ping(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// Create showtimes table if it doesn't exist
+	createTableSQL := `
+	CREATE TABLE IF NOT EXISTS showtimes (
+		id TEXT PRIMARY KEY,
+		title TEXT NOT NULL,
+		datetime DATETIME NOT NULL,
+		created_by TEXT NOT NULL,
+		created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_datetime ON showtimes(datetime);
+	CREATE INDEX IF NOT EXISTS idx_created_by ON showtimes(created_by);
+
+	CREATE TABLE IF NOT EXISTS movie_info (
+		title TEXT PRIMARY KEY,
+		imdb_rating TEXT NOT NULL,
+		runtime TEXT NOT NULL,
+		fetched_at DATETIME NOT NULL
+	);
+
+	CREATE TABLE IF NOT EXISTS reminders (
+		showtime_id TEXT NOT NULL,
+		nick TEXT NOT NULL,
+		PRIMARY KEY (showtime_id, nick)
+	);
+
+	CREATE TABLE IF NOT EXISTS audit_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		at DATETIME NOT NULL,
+		actor TEXT NOT NULL,
+		action TEXT NOT NULL,
+		showtime_id TEXT NOT NULL,
+		details TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_audit_at ON audit_log(at);
+
+	CREATE TABLE IF NOT EXISTS channel_log (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		at DATETIME NOT NULL,
+		channel TEXT NOT NULL,
+		nick TEXT NOT NULL,
+		host TEXT NOT NULL,
+		message TEXT NOT NULL
+	);
+
+	CREATE INDEX IF NOT EXISTS idx_channel_log_at ON channel_log(at);
+	`
+
+	if _, err := db.Exec(createTableSQL); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create table: %v", err)
+	}
+
+	if err := migrateColumns(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate table: %v", err)
+	}
+
+	log.Printf("Database initialized successfully at %s", path)
+	return &SQLiteStore{db: db, path: path, timeout: defaultQueryTimeout}, nil
+}
+
+// showtimeMigrations are columns added after the original schema. Each is
+// added with ALTER TABLE when missing so existing databases keep working.
+var showtimeMigrations = []struct {
+	column     string
+	definition string
+}{
+	{"tmdb_id", "INTEGER NOT NULL DEFAULT 0"},
+	{"poster_url", "TEXT NOT NULL DEFAULT ''"},
+	{"admin_note", "TEXT NOT NULL DEFAULT ''"},
+	{"runtime_minutes", "INTEGER NOT NULL DEFAULT 0"},
+	{"tags", "TEXT NOT NULL DEFAULT ''"},
+	{"cancelled", "INTEGER NOT NULL DEFAULT 0"},
+}
+
+// showtimeColumns is the column list scanShowtime expects, in order
+const showtimeColumns = "id, title, datetime, created_by, created_at, tmdb_id, poster_url, admin_note, runtime_minutes, tags, cancelled"
+
+func migrateColumns(db *sql.DB) error {
+	rows, err := db.Query("PRAGMA table_info(showtimes)")
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool)
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var defaultValue sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &defaultValue, &pk); err != nil {
+			rows.Close()
+			return err
+		}
+		existing[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, migration := range showtimeMigrations {
+		if existing[migration.column] {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE showtimes ADD COLUMN %s %s", migration.column, migration.definition)
+		if _, err := db.Exec(query); err != nil {
+			return err
+		}
+		log.Printf("Added column %s to showtimes", migration.column)
+	}
+	return nil
+}
+
+// writeFailure explains err when it means nothing can be written to the
+// database until someone intervenes (a full disk, a read-only file, an I/O
+// error), or returns "" for errors that are worth just retrying
+func writeFailure(err error) string {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return ""
+	}
+	switch sqliteErr.Code {
+	case sqlite3.ErrFull:
+		return "the disk is full"
+	case sqlite3.ErrReadonly:
+		return "the database is read-only"
+	case sqlite3.ErrIoErr:
+		return "the database file can't be written"
+	}
+	return ""
+}
+
+// queryContext bounds a single store call by the query timeout so a stuck
+// query fails instead of hanging the command that made it
+func (s *SQLiteStore) queryContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
+
+// storedTime formats t for the database. Times are always stored as UTC
+// RFC3339 strings: range queries compare them as text, which only orders
+// correctly when every value carries the same "Z" offset.
+func storedTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanShowtime reads a row selected with showtimeColumns
+func scanShowtime(row rowScanner) (*Showtime, error) {
+	var showtime Showtime
+	var datetimeStr, createdAtStr, tags string
+
+	err := row.Scan(&showtime.ID, &showtime.Title, &datetimeStr, &showtime.CreatedBy, &createdAtStr,
+		&showtime.TMDBID, &showtime.PosterURL, &showtime.AdminNote, &showtime.RuntimeMinutes, &tags, &showtime.Cancelled)
+	if err != nil {
+		return nil, err
+	}
+	if tags != "" {
+		showtime.Tags = strings.Split(tags, ",")
+	}
+
+	showtime.DateTime, err = time.Parse(time.RFC3339, datetimeStr)
+	if err != nil {
+		return nil, err
+	}
+
+	showtime.CreatedAt, err = time.Parse(time.RFC3339, createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return &showtime, nil
+}
+
+// queryShowtime runs a query expected to return at most one showtime
+func (s *SQLiteStore) queryShowtime(query string, args ...any) (*Showtime, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	showtime, err := scanShowtime(s.db.QueryRowContext(ctx, query, args...))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	return showtime, err
+}
+
+func (s *SQLiteStore) Current(now time.Time, window time.Duration) (*Showtime, error) {
+	// Look for movies that started within the current window
+	windowStart := now.Add(-window)
+
+	query := `
+		SELECT ` + showtimeColumns + `
+		FROM showtimes
+		WHERE datetime BETWEEN ? AND ? AND cancelled = 0
+		ORDER BY datetime DESC
+		LIMIT 1
+	`
+
+	return s.queryShowtime(query, storedTime(windowStart), storedTime(now))
+}
+
+func (s *SQLiteStore) Next(now time.Time) (*Showtime, error) {
+	query := `
+		SELECT ` + showtimeColumns + `
+		FROM showtimes
+		WHERE datetime > ? AND cancelled = 0
+		ORDER BY datetime ASC
+		LIMIT 1
+	`
+
+	return s.queryShowtime(query, storedTime(now))
+}
+
+func (s *SQLiteStore) GetByID(id string) (*Showtime, error) {
+	query := `
+		SELECT ` + showtimeColumns + `
+		FROM showtimes
+		WHERE id = ?
+	`
+
+	return s.queryShowtime(query, id)
+}
+
+func (s *SQLiteStore) RawDateTime(id string) (string, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	// The cast stops the driver from parsing the DATETIME column and
+	// reformatting it, which would hide what is actually stored
+	var datetime string
+	err := s.db.QueryRowContext(ctx, "SELECT CAST(datetime AS TEXT) FROM showtimes WHERE id = ?", id).Scan(&datetime)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return datetime, err
+}
+
+func (s *SQLiteStore) SimilarIDs(id string, limit int) ([]string, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	// LIKE is case-insensitive for ASCII; escape its wildcards in the typed id
+	pattern := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(id)
+	query := `
+		SELECT id FROM showtimes
+		WHERE id LIKE '%' || ? || '%' ESCAPE '\' OR ? LIKE id || '%'
+		ORDER BY id
+		LIMIT ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, pattern, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var match string
+		if err := rows.Scan(&match); err != nil {
+			return nil, err
+		}
+		ids = append(ids, match)
+	}
+	return ids, rows.Err()
+}
+
+func (s *SQLiteStore) List(filter ShowtimeFilter) ([]Showtime, error) {
+	var conditions []string
+	var args []any
+
+	if !filter.From.IsZero() && !filter.To.IsZero() {
+		conditions = append(conditions, "datetime BETWEEN ? AND ?")
+		args = append(args, storedTime(filter.From), storedTime(filter.To))
+	} else if !filter.From.IsZero() {
+		conditions = append(conditions, "datetime >= ?")
+		args = append(args, storedTime(filter.From))
+	} else if !filter.To.IsZero() {
+		conditions = append(conditions, "datetime <= ?")
+		args = append(args, storedTime(filter.To))
+	}
+	if filter.Tag != "" {
+		// Tags are stored comma-joined; wrapping both sides in commas matches
+		// whole tags only
+		conditions = append(conditions, "',' || tags || ',' LIKE '%,' || ? || ',%' ESCAPE '\\'")
+		args = append(args, strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(filter.Tag))
+	}
+
+	query := "SELECT " + showtimeColumns + " FROM showtimes"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY datetime ASC"
+
+	return s.queryShowtimes(query, args...)
+}
+
+func (s *SQLiteStore) Upcoming(now time.Time, limit int) ([]Showtime, error) {
+	query := `
+		SELECT ` + showtimeColumns + `
+		FROM showtimes
+		WHERE datetime > ? AND cancelled = 0
+		ORDER BY datetime ASC
+	`
+	args := []any{storedTime(now)}
+	if limit > 0 {
+		query += " LIMIT ?"
+		args = append(args, limit)
+	}
+
+	return s.queryShowtimes(query, args...)
+}
+
+func (s *SQLiteStore) TopCreators(limit int) ([]CreatorCount, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := `
+		SELECT created_by, COUNT(*)
+		FROM showtimes
+		GROUP BY created_by
+		ORDER BY COUNT(*) DESC, created_by ASC
+		LIMIT ?
+	`
+	rows, err := s.db.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var counts []CreatorCount
+	for rows.Next() {
+		var count CreatorCount
+		if err := rows.Scan(&count.Nick, &count.Count); err != nil {
+			return nil, err
+		}
+		counts = append(counts, count)
+	}
+	return counts, rows.Err()
+}
+
+func (s *SQLiteStore) Titles(search string) ([]string, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := `
+		SELECT MIN(title)
+		FROM showtimes
+		WHERE title LIKE ? ESCAPE '\'
+		GROUP BY title COLLATE NOCASE
+		ORDER BY title COLLATE NOCASE
+	`
+	pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(search) + "%"
+	rows, err := s.db.QueryContext(ctx, query, pattern)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var titles []string
+	for rows.Next() {
+		var title string
+		if err := rows.Scan(&title); err != nil {
+			return nil, err
+		}
+		titles = append(titles, title)
+	}
+	return titles, rows.Err()
+}
+
+func (s *SQLiteStore) ByTitle(title string, contains bool) ([]Showtime, error) {
+	if contains {
+		query := `
+			SELECT ` + showtimeColumns + `
+			FROM showtimes
+			WHERE title LIKE ? ESCAPE '\'
+			ORDER BY created_at, id
+		`
+		pattern := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(title) + "%"
+		return s.queryShowtimes(query, pattern)
+	}
+
+	query := `
+		SELECT ` + showtimeColumns + `
+		FROM showtimes
+		WHERE title = ? COLLATE NOCASE
+		ORDER BY created_at, id
+	`
+	return s.queryShowtimes(query, title)
+}
+
+// queryShowtimes runs a query returning any number of showtimes
+func (s *SQLiteStore) queryShowtimes(query string, args ...any) ([]Showtime, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var showtimes []Showtime
+	for rows.Next() {
+		showtime, err := scanShowtime(rows)
+		if err != nil {
+			return nil, err
+		}
+		showtimes = append(showtimes, *showtime)
+	}
+
+	return showtimes, rows.Err()
+}
+
+func (s *SQLiteStore) Create(showtime Showtime) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := `
+		INSERT INTO showtimes (` + showtimeColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	_, err := s.db.ExecContext(ctx, query,
+		showtime.ID,
+		showtime.Title,
+		storedTime(showtime.DateTime),
+		showtime.CreatedBy,
+		storedTime(showtime.CreatedAt),
+		showtime.TMDBID,
+		showtime.PosterURL,
+		showtime.AdminNote,
+		showtime.RuntimeMinutes,
+		strings.Join(showtime.Tags, ","),
+		showtime.Cancelled)
+	return err
+}
+
+func (s *SQLiteStore) CreateAll(showtimes []Showtime) ([]Showtime, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	query := `
+		INSERT OR IGNORE INTO showtimes (` + showtimeColumns + `)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+	var created []Showtime
+	for _, showtime := range showtimes {
+		result, err := tx.ExecContext(ctx, query,
+			showtime.ID,
+			showtime.Title,
+			storedTime(showtime.DateTime),
+			showtime.CreatedBy,
+			storedTime(showtime.CreatedAt),
+			showtime.TMDBID,
+			showtime.PosterURL,
+			showtime.AdminNote,
+			showtime.RuntimeMinutes,
+			strings.Join(showtime.Tags, ","),
+			showtime.Cancelled)
+		if err != nil {
+			return nil, err
+		}
+		if affected, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if affected > 0 {
+			created = append(created, showtime)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func (s *SQLiteStore) Update(showtime Showtime) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := `
+		UPDATE showtimes
+		SET title = ?, datetime = ?, created_by = ?, tmdb_id = ?, poster_url = ?, admin_note = ?, runtime_minutes = ?, tags = ?, cancelled = ?
+		WHERE id = ?
+	`
+	_, err := s.db.ExecContext(ctx, query,
+		showtime.Title,
+		storedTime(showtime.DateTime),
+		showtime.CreatedBy,
+		showtime.TMDBID,
+		showtime.PosterURL,
+		showtime.AdminNote,
+		showtime.RuntimeMinutes,
+		strings.Join(showtime.Tags, ","),
+		showtime.Cancelled,
+		showtime.ID)
+	return err
+}
+
+func (s *SQLiteStore) UpdateAll(showtimes []Showtime) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE showtimes
+		SET title = ?, datetime = ?, created_by = ?, tmdb_id = ?, poster_url = ?, admin_note = ?, runtime_minutes = ?, tags = ?, cancelled = ?
+		WHERE id = ?
+	`
+	for _, showtime := range showtimes {
+		_, err := tx.ExecContext(ctx, query,
+			showtime.Title,
+			storedTime(showtime.DateTime),
+			showtime.CreatedBy,
+			showtime.TMDBID,
+			showtime.PosterURL,
+			showtime.AdminNote,
+			showtime.RuntimeMinutes,
+			strings.Join(showtime.Tags, ","),
+			showtime.Cancelled,
+			showtime.ID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) SetRuntime(idOrTitle string, minutes int) (int, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, "UPDATE showtimes SET runtime_minutes = ? WHERE id = ?", minutes, idOrTitle)
+	if err != nil {
+		return 0, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected > 0 {
+		return int(affected), err
+	}
+
+	result, err = s.db.ExecContext(ctx, "UPDATE showtimes SET runtime_minutes = ? WHERE title = ? COLLATE NOCASE", minutes, idOrTitle)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (s *SQLiteStore) Delete(id string) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM showtimes WHERE id = ?", id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM reminders WHERE showtime_id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) DeleteAll() (int, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, "DELETE FROM showtimes")
+	if err != nil {
+		return 0, err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM reminders"); err != nil {
+		return 0, err
+	}
+
+	return int(deleted), tx.Commit()
+}
+
+func (s *SQLiteStore) CachedMovieInfo(title string) (*MovieInfo, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := "SELECT imdb_rating, runtime FROM movie_info WHERE title = ?"
+	var info MovieInfo
+	err := s.db.QueryRowContext(ctx, query, strings.ToLower(title)).Scan(&info.IMDbRating, &info.Runtime)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &info, nil
+}
+
+func (s *SQLiteStore) CacheMovieInfo(title string, info MovieInfo) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := `
+		INSERT OR REPLACE INTO movie_info (title, imdb_rating, runtime, fetched_at)
+		VALUES (?, ?, ?, ?)
+	`
+	_, err := s.db.ExecContext(ctx, query, strings.ToLower(title), info.IMDbRating, info.Runtime, storedTime(time.Now()))
+	return err
+}
+
+func (s *SQLiteStore) AddReminder(showtimeID, nick string) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := "INSERT OR IGNORE INTO reminders (showtime_id, nick) VALUES (?, ?)"
+	_, err := s.db.ExecContext(ctx, query, showtimeID, nick)
+	return err
+}
+
+func (s *SQLiteStore) RemoveReminder(showtimeID, nick string) (bool, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := "DELETE FROM reminders WHERE showtime_id = ? AND nick = ?"
+	result, err := s.db.ExecContext(ctx, query, showtimeID, nick)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected > 0, err
+}
+
+func (s *SQLiteStore) Reminders(showtimeID string) ([]string, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	rows, err := s.db.QueryContext(ctx, "SELECT nick FROM reminders WHERE showtime_id = ? ORDER BY nick", showtimeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var nicks []string
+	for rows.Next() {
+		var nick string
+		if err := rows.Scan(&nick); err != nil {
+			return nil, err
+		}
+		nicks = append(nicks, nick)
+	}
+	return nicks, rows.Err()
+}
+
+func (s *SQLiteStore) ClearReminders(showtimeID string) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx, "DELETE FROM reminders WHERE showtime_id = ?", showtimeID)
+	return err
+}
+
+func (s *SQLiteStore) Audit(entry AuditEntry) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	query := `
+		INSERT INTO audit_log (at, actor, action, showtime_id, details)
+		VALUES (?, ?, ?, ?, ?)
+	`
+	_, err := s.db.ExecContext(ctx, query, storedTime(entry.At), entry.Actor, entry.Action, entry.ShowtimeID, entry.Details)
+	return err
+}
+
+func (s *SQLiteStore) PruneAudit(cutoff time.Time) (int, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM audit_log WHERE at < ?", storedTime(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (s *SQLiteStore) LogChannelMessage(entry ChannelMessage) error {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	_, err := s.db.ExecContext(ctx,
+		"INSERT INTO channel_log (at, channel, nick, host, message) VALUES (?, ?, ?, ?, ?)",
+		storedTime(entry.At), entry.Channel, entry.Nick, entry.Host, entry.Message)
+	return err
+}
+
+func (s *SQLiteStore) PruneChannelLog(cutoff time.Time) (int, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	result, err := s.db.ExecContext(ctx, "DELETE FROM channel_log WHERE at < ?", storedTime(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	return int(affected), err
+}
+
+func (s *SQLiteStore) Stats() (StoreStats, error) {
+	ctx, cancel := s.queryContext()
+	defer cancel()
+
+	var stats StoreStats
+	if err := s.db.PingContext(ctx); err != nil {
+		return stats, err
+	}
+	query := `
+		SELECT
+			(SELECT COUNT(*) FROM showtimes),
+			(SELECT COUNT(*) FROM reminders),
+			(SELECT COUNT(*) FROM audit_log)
+	`
+	err := s.db.QueryRowContext(ctx, query).Scan(&stats.Showtimes, &stats.Reminders, &stats.AuditEntries)
+	return stats, err
+}
+
+func (s *SQLiteStore) Maintain(vacuum bool) error {
+	before := s.fileSize()
+
+	if _, err := s.db.Exec("PRAGMA optimize"); err != nil {
+		return fmt.Errorf("optimize failed: %v", err)
+	}
+	if vacuum {
+		if _, err := s.db.Exec("VACUUM"); err != nil {
+			return fmt.Errorf("vacuum failed: %v", err)
+		}
+	}
+
+	log.Printf("Database maintenance done (vacuum: %v), size %d -> %d bytes", vacuum, before, s.fileSize())
+	return nil
+}
+
+// fileSize returns the database file's size in bytes, or 0 when it can't be
+// read, e.g. for in-memory databases
+func (s *SQLiteStore) fileSize() int64 {
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
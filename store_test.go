@@ -0,0 +1,886 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// newSQLiteTestBot returns a bot backed by a fresh in-memory SQLite database
+// that records its replies
+func newSQLiteTestBot(t *testing.T) (*command, *captureSender) {
+	t.Helper()
+	store, err := NewSQLiteStore(":memory:")
+	if err != nil {
+		t.Fatalf("failed to open in-memory store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	bot, sender := newTestBot()
+	bot.CinemaBot.store = store
+	bot.store = store
+	return bot, sender
+}
+
+func TestSQLiteStore_CreateGetDelete(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	showtime := Showtime{
+		ID:        "movie",
+		Title:     "A Streetcar Named Desire",
+		DateTime:  time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC),
+		CreatedBy: "alice",
+		CreatedAt: time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC),
+		AdminNote: "waiting on licensing",
+		Tags:      []string{"classics"},
+	}
+	if err := store.Create(showtime); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, err := store.GetByID("movie")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got == nil || !reflect.DeepEqual(*got, showtime) {
+		t.Errorf("expected %+v, got %+v", showtime, got)
+	}
+
+	if err := store.Delete("movie"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	got, err = store.GetByID("movie")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got != nil {
+		t.Errorf("expected showtime to be deleted, got %+v", got)
+	}
+}
+
+func TestSQLiteStore_Upcoming(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	for i, id := range []string{"past", "now", "next", "later"} {
+		start := now.Add(time.Duration(i-1) * time.Hour)
+		if err := store.Create(Showtime{ID: id, Title: id, DateTime: start, CreatedBy: "alice", CreatedAt: now}); err != nil {
+			t.Fatalf("failed to create %s: %v", id, err)
+		}
+	}
+
+	showtimes, err := store.Upcoming(now, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var ids []string
+	for _, showtime := range showtimes {
+		ids = append(ids, showtime.ID)
+	}
+	if !equalStringSlices(ids, []string{"next", "later"}) {
+		t.Errorf("expected [next later], got %v", ids)
+	}
+
+	showtimes, err = store.Upcoming(now, 1)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(showtimes) != 1 || showtimes[0].ID != "next" {
+		t.Errorf("expected only next, got %v", showtimes)
+	}
+}
+
+func TestNewSQLiteStore_MigratesOldSchema(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "old.db")
+	db, err := sql.Open("sqlite3", path)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	_, err = db.Exec(`
+		CREATE TABLE showtimes (
+			id TEXT PRIMARY KEY,
+			title TEXT NOT NULL,
+			datetime DATETIME NOT NULL,
+			created_by TEXT NOT NULL,
+			created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP
+		);
+		INSERT INTO showtimes VALUES ('old', 'Metropolis', '2025-06-13T19:00:00Z', 'alice', '2025-06-01T12:00:00Z');
+	`)
+	db.Close()
+	if err != nil {
+		t.Fatalf("failed to create old schema: %v", err)
+	}
+
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	showtime, err := store.GetByID("old")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if showtime == nil || showtime.Title != "Metropolis" || showtime.TMDBID != 0 || showtime.PosterURL != "" {
+		t.Errorf("expected migrated showtime, got %+v", showtime)
+	}
+}
+
+func TestSQLiteStore_MovieInfoCache(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	if info, err := store.CachedMovieInfo("Casablanca"); err != nil || info != nil {
+		t.Fatalf("expected cache miss, got %+v, %v", info, err)
+	}
+	if err := store.CacheMovieInfo("Casablanca", MovieInfo{IMDbRating: "8.5", Runtime: "102 min"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	info, err := store.CachedMovieInfo("casablanca")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if info == nil || info.IMDbRating != "8.5" || info.Runtime != "102 min" {
+		t.Errorf("expected cached info, got %+v", info)
+	}
+}
+
+func TestSQLiteStore_ListDateRange(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	for day := 0; day < 5; day++ {
+		id := fmt.Sprintf("day%d", day)
+		if err := store.Create(Showtime{ID: id, Title: id, DateTime: start.AddDate(0, 0, day), CreatedBy: "alice", CreatedAt: start}); err != nil {
+			t.Fatalf("failed to create %s: %v", id, err)
+		}
+	}
+
+	tests := []struct {
+		filter   ShowtimeFilter
+		expected []string
+	}{
+		{ShowtimeFilter{}, []string{"day0", "day1", "day2", "day3", "day4"}},
+		{ShowtimeFilter{From: start.AddDate(0, 0, 1), To: start.AddDate(0, 0, 3)}, []string{"day1", "day2", "day3"}},
+		{ShowtimeFilter{From: start.AddDate(0, 0, 3)}, []string{"day3", "day4"}},
+		{ShowtimeFilter{To: start.AddDate(0, 0, 1)}, []string{"day0", "day1"}},
+	}
+	for _, tt := range tests {
+		showtimes, err := store.List(tt.filter)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var ids []string
+		for _, showtime := range showtimes {
+			ids = append(ids, showtime.ID)
+		}
+		if !equalStringSlices(ids, tt.expected) {
+			t.Errorf("filter %+v: expected %v, got %v", tt.filter, tt.expected, ids)
+		}
+	}
+}
+
+func TestSQLiteStore_Cancelled(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: now.Add(time.Hour), CreatedBy: "alice", CreatedAt: now, Cancelled: true})
+	store.Create(Showtime{ID: "b", Title: "Vertigo", DateTime: now.Add(2 * time.Hour), CreatedBy: "alice", CreatedAt: now})
+
+	if next, _ := store.Next(now); next == nil || next.ID != "b" {
+		t.Errorf("expected the cancelled showtime to be skipped, got %+v", next)
+	}
+	if upcoming, _ := store.Upcoming(now, 0); len(upcoming) != 1 {
+		t.Errorf("expected one upcoming showtime, got %+v", upcoming)
+	}
+	if current, _ := store.Current(now.Add(90*time.Minute), 3*time.Hour); current != nil {
+		t.Errorf("expected nothing current, got %+v", current)
+	}
+	if listed, _ := store.List(ShowtimeFilter{}); len(listed) != 2 || !listed[0].Cancelled {
+		t.Errorf("expected both showtimes listed with the flag kept, got %+v", listed)
+	}
+
+	showtime, _ := store.GetByID("a")
+	showtime.Cancelled = false
+	if err := store.Update(*showtime); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if next, _ := store.Next(now); next == nil || next.ID != "a" {
+		t.Errorf("expected the uncancelled showtime to be next, got %+v", next)
+	}
+}
+
+func TestSQLiteStore_Reminders(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "alice"})
+	for _, nick := range []string{"carol", "bob", "bob"} {
+		if err := store.AddReminder("movie", nick); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	nicks, err := store.Reminders("movie")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !equalStringSlices(nicks, []string{"bob", "carol"}) {
+		t.Errorf("expected [bob carol], got %v", nicks)
+	}
+
+	if removed, err := store.RemoveReminder("movie", "carol"); err != nil || !removed {
+		t.Errorf("expected carol removed, got %v, %v", removed, err)
+	}
+	if removed, err := store.RemoveReminder("movie", "carol"); err != nil || removed {
+		t.Errorf("expected nothing to remove, got %v, %v", removed, err)
+	}
+
+	if err := store.Delete("movie"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if nicks, _ := store.Reminders("movie"); len(nicks) != 0 {
+		t.Errorf("expected reminders deleted with showtime, got %v", nicks)
+	}
+}
+
+func TestSQLiteStore_CreateAllSkipsExisting(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	store.Create(Showtime{ID: "taken", Title: "Original", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+
+	created, err := store.CreateAll([]Showtime{
+		{ID: "new", Title: "Casablanca", DateTime: start, CreatedBy: "bob", CreatedAt: start},
+		{ID: "taken", Title: "Duplicate", DateTime: start, CreatedBy: "bob", CreatedAt: start},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(created) != 1 || created[0].ID != "new" {
+		t.Errorf("expected only new to be created, got %+v", created)
+	}
+	if showtime, _ := store.GetByID("taken"); showtime == nil || showtime.Title != "Original" {
+		t.Errorf("expected original showtime untouched, got %+v", showtime)
+	}
+}
+
+func TestSQLiteStore_InMemorySharesSchema(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+
+	// Interleave writes and reads so a second pooled connection would see an
+	// empty database without the schema
+	for _, id := range []string{"a", "b", "c"} {
+		if err := bot.store.Create(Showtime{ID: id, Title: id, DateTime: start, CreatedBy: "alice", CreatedAt: start}); err != nil {
+			t.Fatalf("failed to create %s: %v", id, err)
+		}
+		if showtime, err := bot.store.GetByID(id); err != nil || showtime == nil {
+			t.Fatalf("expected %s to be readable, got %+v, %v", id, showtime, err)
+		}
+	}
+	showtimes, err := bot.store.List(ShowtimeFilter{})
+	if err != nil || len(showtimes) != 3 {
+		t.Errorf("expected 3 showtimes, got %d, %v", len(showtimes), err)
+	}
+}
+
+func TestSQLiteStore_Next(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+
+	if next, err := bot.store.Next(now); err != nil || next != nil {
+		t.Fatalf("expected no next showtime, got %+v, %v", next, err)
+	}
+
+	bot.store.Create(Showtime{ID: "now", Title: "Starting", DateTime: now, CreatedBy: "alice", CreatedAt: now})
+	bot.store.Create(Showtime{ID: "later", Title: "Later", DateTime: now.Add(2 * time.Hour), CreatedBy: "alice", CreatedAt: now})
+	bot.store.Create(Showtime{ID: "soon", Title: "Soon", DateTime: now.Add(time.Second), CreatedBy: "alice", CreatedAt: now})
+
+	next, err := bot.store.Next(now)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if next == nil || next.ID != "soon" {
+		t.Errorf("expected soon, got %+v", next)
+	}
+}
+
+func TestSQLiteStore_CurrentWindowEdges(t *testing.T) {
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	window := defaultCurrentWindowHours * time.Hour
+
+	tests := []struct {
+		name     string
+		start    time.Time
+		expected bool
+	}{
+		{"starting now", now, true},
+		{"exactly at window", now.Add(-window), true},
+		{"just past window", now.Add(-window - time.Second), false},
+		{"not started", now.Add(time.Second), false},
+	}
+	for _, tt := range tests {
+		bot, _ := newSQLiteTestBot(t)
+		bot.store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: tt.start, CreatedBy: "alice", CreatedAt: now})
+
+		current, err := bot.store.Current(now, window)
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.name, err)
+		}
+		if (current != nil) != tt.expected {
+			t.Errorf("%s: expected current=%v, got %+v", tt.name, tt.expected, current)
+		}
+	}
+}
+
+func TestSQLiteStore_CurrentPrefersLatestStart(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	now := time.Date(2025, 6, 13, 12, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "earlier", Title: "Earlier", DateTime: now.Add(-2 * time.Hour), CreatedBy: "alice", CreatedAt: now})
+	bot.store.Create(Showtime{ID: "recent", Title: "Recent", DateTime: now.Add(-30 * time.Minute), CreatedBy: "alice", CreatedAt: now})
+
+	current, err := bot.store.Current(now, 3*time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if current == nil || current.ID != "recent" {
+		t.Errorf("expected recent, got %+v", current)
+	}
+}
+
+func TestHandleNextMovieCommand_SQLite(t *testing.T) {
+	bot, sender := newSQLiteTestBot(t)
+	now := time.Now().UTC()
+	bot.store.Create(Showtime{ID: "old", Title: "Metropolis", DateTime: now.Add(-4 * time.Hour), CreatedBy: "alice", CreatedAt: now})
+	bot.store.Create(Showtime{ID: "next", Title: "Casablanca", DateTime: now.Add(2*time.Hour + 30*time.Second), CreatedBy: "alice", CreatedAt: now})
+
+	bot.handleNextMovieCommand([]string{".nextmovie"})
+
+	if expected := "In 2 hours, Casablanca is playing!"; len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+}
+
+func TestSQLiteStore_StoresUTC(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	store := bot.store.(*SQLiteStore)
+
+	// 19:00 in UTC+05:30 is 13:30 UTC; stored with its offset it would sort
+	// after "2025-06-13T14:00:00Z" even though it starts earlier
+	india := time.FixedZone("IST", 5*60*60+30*60)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, india)
+	if err := store.Create(Showtime{ID: "offset", Title: "Pather Panchali", DateTime: start, CreatedBy: "alice", CreatedAt: start}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	var raw string
+	if err := store.db.QueryRow("SELECT datetime FROM showtimes WHERE id = ?", "offset").Scan(&raw); err != nil {
+		t.Fatalf("failed to read raw datetime: %v", err)
+	}
+	if raw != "2025-06-13T13:30:00Z" {
+		t.Errorf("expected datetime stored as UTC, got %q", raw)
+	}
+
+	// Range queries with offset-bearing bounds must still match
+	now := time.Date(2025, 6, 13, 10, 0, 0, 0, time.FixedZone("EDT", -4*60*60)) // 14:00 UTC
+	current, err := store.Current(now, time.Hour)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if current == nil || current.ID != "offset" || !current.DateTime.Equal(start) {
+		t.Errorf("expected offset showtime to be current, got %+v", current)
+	}
+	if next, err := store.Next(now.Add(-time.Hour)); err != nil || next == nil || next.ID != "offset" {
+		t.Errorf("expected offset showtime to be next, got %+v, %v", next, err)
+	}
+}
+
+func TestSQLiteStore_UpdateAndAudit(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	store := bot.store.(*SQLiteStore)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+
+	updated := Showtime{ID: "movie", Title: "Casablanca", DateTime: start.Add(time.Hour), CreatedBy: "bob", CreatedAt: start, AdminNote: "moved"}
+	if err := store.Update(updated); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got, _ := store.GetByID("movie"); got == nil || !reflect.DeepEqual(*got, updated) {
+		t.Errorf("expected %+v, got %+v", updated, got)
+	}
+
+	if err := store.Audit(AuditEntry{At: start, Actor: "admin", Action: "reassign", ShowtimeID: "movie", Details: "created_by alice -> bob"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	var actor, details string
+	if err := store.db.QueryRow("SELECT actor, details FROM audit_log").Scan(&actor, &details); err != nil {
+		t.Fatalf("failed to read audit log: %v", err)
+	}
+	if actor != "admin" || details != "created_by alice -> bob" {
+		t.Errorf("unexpected audit row %s %s", actor, details)
+	}
+}
+
+func TestSQLiteStore_RawDateTime(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	store := bot.store.(*SQLiteStore)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	store.Create(Showtime{ID: "movie", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	store.db.Exec("INSERT INTO showtimes (id, title, datetime, created_by, created_at) VALUES ('typo', 'Vertigo', '2025-06-13 19:00', 'bob', ?)", storedTime(start))
+
+	tests := []struct {
+		id       string
+		expected string
+	}{
+		{"movie", "2025-06-13T19:00:00Z"},
+		{"typo", "2025-06-13 19:00"},
+		{"missing", ""},
+	}
+	for _, tt := range tests {
+		raw, err := store.RawDateTime(tt.id)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if raw != tt.expected {
+			t.Errorf("RawDateTime(%q): expected %q, got %q", tt.id, tt.expected, raw)
+		}
+	}
+}
+
+func TestSQLiteStore_UpdateAll(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "one", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	bot.store.Create(Showtime{ID: "two", Title: "Vertigo", DateTime: start.Add(time.Hour), CreatedBy: "alice", CreatedAt: start})
+
+	shifted := []Showtime{
+		{ID: "one", Title: "Casablanca", DateTime: start.Add(time.Hour), CreatedBy: "alice", CreatedAt: start},
+		{ID: "two", Title: "Vertigo", DateTime: start.Add(2 * time.Hour), CreatedBy: "alice", CreatedAt: start},
+	}
+	if err := bot.store.UpdateAll(shifted); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	for _, expected := range shifted {
+		if got, _ := bot.store.GetByID(expected.ID); got == nil || !reflect.DeepEqual(*got, expected) {
+			t.Errorf("expected %+v, got %+v", expected, got)
+		}
+	}
+}
+
+func TestSQLiteStore_ByTitle(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "b", Title: "casablanca", DateTime: start, CreatedBy: "bob", CreatedAt: start})
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start.Add(-time.Hour)})
+	bot.store.Create(Showtime{ID: "c", Title: "100% Casablanca", DateTime: start, CreatedBy: "carol", CreatedAt: start})
+	bot.store.Create(Showtime{ID: "d", Title: "1000 Casablancas", DateTime: start, CreatedBy: "dave", CreatedAt: start})
+
+	tests := []struct {
+		title    string
+		contains bool
+		expected []string
+	}{
+		{"CASABLANCA", false, []string{"a", "b"}},
+		{"casa", false, nil},
+		{"casa", true, []string{"a", "b", "c", "d"}},
+		{"0% c", true, []string{"c"}},
+	}
+	for _, tt := range tests {
+		showtimes, err := bot.store.ByTitle(tt.title, tt.contains)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var ids []string
+		for _, showtime := range showtimes {
+			ids = append(ids, showtime.ID)
+		}
+		if !equalStringSlices(ids, tt.expected) {
+			t.Errorf("ByTitle(%q, %v): expected %v, got %v", tt.title, tt.contains, tt.expected, ids)
+		}
+	}
+}
+
+func TestSQLiteStore_ChannelLog(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	store := bot.store.(*SQLiteStore)
+	now := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{now.AddDate(0, 0, -10), now} {
+		if err := store.LogChannelMessage(ChannelMessage{At: at, Channel: "#testchan", Nick: "alice", Host: "user/alice", Message: "hi"}); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	}
+
+	pruned, err := store.PruneChannelLog(now.AddDate(0, 0, -7))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if pruned != 1 {
+		t.Errorf("expected 1 row pruned, got %d", pruned)
+	}
+	var nick, host, message string
+	if err := store.db.QueryRow("SELECT nick, host, message FROM channel_log").Scan(&nick, &host, &message); err != nil {
+		t.Fatalf("failed to read channel log: %v", err)
+	}
+	if nick != "alice" || host != "user/alice" || message != "hi" {
+		t.Errorf("unexpected channel log row %s %s %s", nick, host, message)
+	}
+}
+
+func TestSQLiteStore_DeleteAll(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "one", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	bot.store.Create(Showtime{ID: "two", Title: "Vertigo", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	bot.store.AddReminder("one", "carol")
+
+	deleted, err := bot.store.DeleteAll()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if deleted != 2 {
+		t.Errorf("expected 2 showtimes deleted, got %d", deleted)
+	}
+	if stats, _ := bot.store.Stats(); stats.Showtimes != 0 || stats.Reminders != 0 {
+		t.Errorf("expected an empty schedule, got %+v", stats)
+	}
+}
+
+func TestSQLiteStore_DeleteRemovesReminders(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "one", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	bot.store.Create(Showtime{ID: "two", Title: "Vertigo", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	bot.store.AddReminder("one", "carol")
+	bot.store.AddReminder("two", "carol")
+
+	if err := bot.store.Delete("one"); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if stats, _ := bot.store.Stats(); stats.Showtimes != 1 || stats.Reminders != 1 {
+		t.Errorf("expected only two and its reminder left, got %+v", stats)
+	}
+}
+
+func TestSQLiteStore_PruneAudit(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	now := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	for _, at := range []time.Time{now.AddDate(0, 0, -40), now.AddDate(0, 0, -31), now.AddDate(0, 0, -29), now} {
+		bot.store.Audit(AuditEntry{At: at, Actor: "admin", Action: "retz", ShowtimeID: "movie"})
+	}
+
+	pruned, err := bot.store.PruneAudit(now.AddDate(0, 0, -30))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if pruned != 2 {
+		t.Errorf("expected 2 rows pruned, got %d", pruned)
+	}
+	if stats, _ := bot.store.Stats(); stats.AuditEntries != 2 {
+		t.Errorf("expected 2 rows left, got %d", stats.AuditEntries)
+	}
+}
+
+func TestSQLiteStore_SimilarIDs(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	for _, id := range []string{"casablanca", "vertigo-1", "vertigo-2", "psycho", "100%_real"} {
+		bot.store.Create(Showtime{ID: id, Title: id, DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	}
+
+	tests := []struct {
+		id       string
+		limit    int
+		expected []string
+	}{
+		{"CASA", 5, []string{"casablanca"}},
+		{"vertigo", 5, []string{"vertigo-1", "vertigo-2"}},
+		{"vertigo", 1, []string{"vertigo-1"}},
+		{"psycho2", 5, []string{"psycho"}},
+		{"%", 5, []string{"100%_real"}},
+		{"metropolis", 5, nil},
+	}
+	for _, tt := range tests {
+		ids, err := bot.store.SimilarIDs(tt.id, tt.limit)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if !equalStringSlices(ids, tt.expected) {
+			t.Errorf("SimilarIDs(%q): expected %v, got %v", tt.id, tt.expected, ids)
+		}
+	}
+}
+
+func TestSQLiteStore_FractionalOffsetRoundTrip(t *testing.T) {
+	tests := []struct {
+		zone string
+		utc  string
+	}{
+		{"Asia/Kolkata", "2025-06-13T14:30:00Z"},    // +05:30
+		{"Australia/Eucla", "2025-06-13T11:15:00Z"}, // +08:45
+		{"Asia/Kathmandu", "2025-06-13T14:15:00Z"},  // +05:45
+	}
+	for _, tt := range tests {
+		location, err := time.LoadLocation(tt.zone)
+		if err != nil {
+			t.Fatalf("failed to load %s: %v", tt.zone, err)
+		}
+		bot, _ := newSQLiteTestBot(t)
+		store := bot.store.(*SQLiteStore)
+
+		// 20:00 wall clock in the zone
+		start := time.Date(2025, 6, 13, 20, 0, 0, 0, location)
+		if err := store.Create(Showtime{ID: "movie", Title: "Movie", DateTime: start, CreatedBy: "alice", CreatedAt: start}); err != nil {
+			t.Fatalf("%s: expected no error, got %v", tt.zone, err)
+		}
+
+		var raw string
+		if err := store.db.QueryRow("SELECT datetime FROM showtimes WHERE id = 'movie'").Scan(&raw); err != nil {
+			t.Fatalf("%s: failed to read raw datetime: %v", tt.zone, err)
+		}
+		if raw != tt.utc {
+			t.Errorf("%s: expected %s stored, got %s", tt.zone, tt.utc, raw)
+		}
+
+		showtime, err := store.GetByID("movie")
+		if err != nil || showtime == nil {
+			t.Fatalf("%s: expected showtime, got %+v, %v", tt.zone, showtime, err)
+		}
+		if wall := showtime.DateTime.In(location).Format("15:04"); wall != "20:00" {
+			t.Errorf("%s: expected 20:00 back in the zone, got %s", tt.zone, wall)
+		}
+	}
+}
+
+func TestSQLiteStore_Maintain(t *testing.T) {
+	store, err := NewSQLiteStore(filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open store: %v", err)
+	}
+	defer store.Close()
+
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	for i := 0; i < 500; i++ {
+		id := fmt.Sprintf("movie%d", i)
+		store.Create(Showtime{ID: id, Title: strings.Repeat("x", 200), DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	}
+	for i := 0; i < 500; i++ {
+		store.Delete(fmt.Sprintf("movie%d", i))
+	}
+
+	before := store.fileSize()
+	if err := store.Maintain(false); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if err := store.Maintain(true); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if after := store.fileSize(); after >= before {
+		t.Errorf("expected VACUUM to shrink the file, got %d -> %d bytes", before, after)
+	}
+}
+
+func TestSQLiteStore_TopCreators(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	for i, nick := range []string{"bob", "alice", "carol", "alice", "bob", "alice"} {
+		bot.store.Create(Showtime{ID: fmt.Sprint(i), Title: "Movie", DateTime: start, CreatedBy: nick, CreatedAt: start})
+	}
+
+	counts, err := bot.store.TopCreators(2)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := []CreatorCount{{"alice", 3}, {"bob", 2}}
+	if len(counts) != len(expected) || counts[0] != expected[0] || counts[1] != expected[1] {
+		t.Errorf("expected %v, got %v", expected, counts)
+	}
+}
+
+func TestSQLiteStore_Titles(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	for i, title := range []string{"Vertigo", "casablanca", "Casablanca", "100% Wolf", "Psycho"} {
+		bot.store.Create(Showtime{ID: fmt.Sprint(i), Title: title, DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	}
+
+	titles, err := bot.store.Titles("")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expected := []string{"100% Wolf", "Casablanca", "Psycho", "Vertigo"}; !equalStringSlices(titles, expected) {
+		t.Errorf("expected %v, got %v", expected, titles)
+	}
+
+	titles, _ = bot.store.Titles("%")
+	if expected := []string{"100% Wolf"}; !equalStringSlices(titles, expected) {
+		t.Errorf("expected %% to match literally, got %v", titles)
+	}
+}
+
+func TestSQLiteStore_QueryTimeout(t *testing.T) {
+	bot, sender := newSQLiteTestBot(t)
+	store := bot.store.(*SQLiteStore)
+	store.timeout = time.Nanosecond
+
+	if _, err := store.GetByID("movie"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+	if err := store.Create(Showtime{ID: "movie", Title: "Casablanca"}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected a deadline error, got %v", err)
+	}
+
+	bot.handleRemindCommand([]string{".remind", "movie"}, "alice")
+	if expected := "The database is temporarily unavailable, please try again shortly."; len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+}
+
+func TestSQLiteStore_SetRuntime(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	bot.store.Create(Showtime{ID: "b", Title: "CASABLANCA", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	bot.store.Create(Showtime{ID: "casablanca", Title: "Vertigo", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+
+	// An id match wins over title matches
+	if changed, err := bot.store.SetRuntime("casablanca", 128); err != nil || changed != 1 {
+		t.Errorf("expected 1 row changed by id, got %d (%v)", changed, err)
+	}
+	if changed, err := bot.store.SetRuntime("Casablanca", 102); err != nil || changed != 2 {
+		t.Errorf("expected 2 rows changed by title, got %d (%v)", changed, err)
+	}
+
+	for id, minutes := range map[string]int{"a": 102, "b": 102, "casablanca": 128} {
+		if showtime, _ := bot.store.GetByID(id); showtime == nil || showtime.RuntimeMinutes != minutes {
+			t.Errorf("expected %s to run %d minutes, got %+v", id, minutes, showtime)
+		}
+	}
+}
+
+func TestWriteFailure(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{sqlite3.Error{Code: sqlite3.ErrFull}, "the disk is full"},
+		{fmt.Errorf("wrapped: %w", sqlite3.Error{Code: sqlite3.ErrReadonly}), "the database is read-only"},
+		{sqlite3.Error{Code: sqlite3.ErrIoErr}, "the database file can't be written"},
+		{sqlite3.Error{Code: sqlite3.ErrConstraint}, ""},
+		{errors.New("boom"), ""},
+	}
+	for _, tt := range tests {
+		if got := writeFailure(tt.err); got != tt.expected {
+			t.Errorf("writeFailure(%v): expected %q, got %q", tt.err, tt.expected, got)
+		}
+	}
+}
+
+func TestSQLiteStore_ReadOnlyCreate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.db")
+	store, err := NewSQLiteStore(path)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	store.Close()
+
+	readOnly, err := NewSQLiteStore("file:" + path + "?mode=ro")
+	if err != nil {
+		t.Fatalf("failed to open read-only store: %v", err)
+	}
+	defer readOnly.Close()
+
+	bot, sender := newTestBot()
+	bot.store = readOnly
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=movie -title=Casablanca -date="2025-06-13 19:00"`), "alice")
+
+	expected := "Can't save changes: the database is read-only. Please let the bot's operator know."
+	if len(sender.messages) != 1 || sender.messages[0] != expected {
+		t.Errorf("expected %q, got %v", expected, sender.messages)
+	}
+}
+
+func TestSQLiteStore_Tags(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "a", Title: "Psycho", DateTime: start, CreatedBy: "alice", CreatedAt: start, Tags: []string{"horror", "classics"}})
+	bot.store.Create(Showtime{ID: "b", Title: "Scream", DateTime: start, CreatedBy: "alice", CreatedAt: start, Tags: []string{"horror-comedy"}})
+	bot.store.Create(Showtime{ID: "c", Title: "Alien", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+
+	showtime, err := bot.store.GetByID("a")
+	if err != nil || showtime == nil || !equalStringSlices(showtime.Tags, []string{"horror", "classics"}) {
+		t.Fatalf("expected tags to round-trip, got %+v (%v)", showtime, err)
+	}
+
+	for tag, expected := range map[string][]string{
+		"horror":   {"a"},
+		"classics": {"a"},
+		"horror%":  nil,
+		"":         {"a", "b", "c"},
+	} {
+		showtimes, err := bot.store.List(ShowtimeFilter{Tag: tag})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		var ids []string
+		for _, showtime := range showtimes {
+			ids = append(ids, showtime.ID)
+		}
+		sort.Strings(ids)
+		if !equalStringSlices(ids, expected) {
+			t.Errorf("tag %q: expected %v, got %v", tag, expected, ids)
+		}
+	}
+}
+
+func TestSQLiteStore_Stats(t *testing.T) {
+	bot, _ := newSQLiteTestBot(t)
+	start := time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)
+	bot.store.Create(Showtime{ID: "a", Title: "Casablanca", DateTime: start, CreatedBy: "alice", CreatedAt: start})
+	bot.store.AddReminder("a", "bob")
+	bot.store.AddReminder("a", "carol")
+	bot.audit("alice", "delete", "b", "")
+
+	stats, err := bot.store.Stats()
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expected := (StoreStats{Showtimes: 1, Reminders: 2, AuditEntries: 1}); stats != expected {
+		t.Errorf("expected %+v, got %+v", expected, stats)
+	}
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+var (
+	// tmdbBaseURL and tmdbImageBaseURL are variables so tests can point them
+	// at a local server
+	tmdbBaseURL      = "https://api.themoviedb.org/3"
+	tmdbImageBaseURL = "https://image.tmdb.org/t/p/w500"
+
+	tmdbClient = &http.Client{Timeout: 5 * time.Second}
+)
+
+// tmdbMovie is the subset of a TMDB search result the bot stores
+type tmdbMovie struct {
+	ID          int    `json:"id"`
+	Title       string `json:"title"`
+	PosterPath  string `json:"poster_path"`
+	ReleaseDate string `json:"release_date"`
+}
+
+// PosterURL returns the full poster image URL, or "" when TMDB has no poster
+func (m tmdbMovie) PosterURL() string {
+	if m.PosterPath == "" {
+		return ""
+	}
+	return tmdbImageBaseURL + m.PosterPath
+}
+
+// lookupTMDB searches TMDB for title and returns the best match, or nil when
+// nothing matched
+func lookupTMDB(apiKey, title string) (*tmdbMovie, error) {
+	query := url.Values{}
+	query.Set("api_key", apiKey)
+	query.Set("query", title)
+
+	resp, err := tmdbClient.Get(tmdbBaseURL + "/search/movie?" + query.Encode())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	var result struct {
+		Results []tmdbMovie `json:"results"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+
+	if len(result.Results) == 0 {
+		return nil, nil
+	}
+	return &result.Results[0], nil
+}
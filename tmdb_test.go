@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// withTMDBServer points the TMDB client at handler for the duration of the test
+func withTMDBServer(t *testing.T, handler http.HandlerFunc) {
+	server := httptest.NewServer(handler)
+	oldBase := tmdbBaseURL
+	tmdbBaseURL = server.URL
+	t.Cleanup(func() {
+		tmdbBaseURL = oldBase
+		server.Close()
+	})
+}
+
+func TestLookupTMDB(t *testing.T) {
+	withTMDBServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/search/movie" || r.URL.Query().Get("api_key") != "key" || r.URL.Query().Get("query") != "streetcar" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		fmt.Fprint(w, `{"results": [{"id": 702, "title": "A Streetcar Named Desire", "poster_path": "/poster.jpg"}, {"id": 1, "title": "Other"}]}`)
+	})
+
+	movie, err := lookupTMDB("key", "streetcar")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if movie == nil || movie.ID != 702 || movie.Title != "A Streetcar Named Desire" {
+		t.Fatalf("unexpected movie %+v", movie)
+	}
+	if movie.PosterURL() != tmdbImageBaseURL+"/poster.jpg" {
+		t.Errorf("unexpected poster URL %s", movie.PosterURL())
+	}
+}
+
+func TestLookupTMDB_NoResults(t *testing.T) {
+	withTMDBServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results": []}`)
+	})
+
+	movie, err := lookupTMDB("key", "nothing")
+	if err != nil || movie != nil {
+		t.Errorf("expected no match and no error, got %+v, %v", movie, err)
+	}
+}
+
+func TestApplyTMDBLookup_DegradesOnError(t *testing.T) {
+	withTMDBServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	})
+
+	bot := newConfigCommand(Config{TMDBAPIKey: "bad"})
+	showtime := Showtime{Title: "streetcar"}
+	bot.applyTMDBLookup(&showtime)
+	if showtime.Title != "streetcar" || showtime.TMDBID != 0 {
+		t.Errorf("expected showtime to be unchanged, got %+v", showtime)
+	}
+}
+
+func TestCreateShowtime_Lookup(t *testing.T) {
+	withTMDBServer(t, func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"results": [{"id": 702, "title": "A Streetcar Named Desire", "poster_path": "/poster.jpg"}]}`)
+	})
+
+	bot, sender := newTestBot()
+	bot.config.TMDBAPIKey = "key"
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=movie -title=streetcar -date="2025-06-13 19:00" -lookup`), "alice")
+
+	showtime, _ := bot.store.GetByID("movie")
+	if showtime == nil {
+		t.Fatalf("expected showtime to be created, replies: %v", sender.messages)
+	}
+	if showtime.Title != "A Streetcar Named Desire" || showtime.TMDBID != 702 || showtime.PosterURL == "" {
+		t.Errorf("expected TMDB metadata, got %+v", showtime)
+	}
+}
+
+func TestCreateShowtime_LookupWithoutKey(t *testing.T) {
+	bot, _ := newTestBot()
+	bot.createShowtime(bot.parseArgs(`.showtime -create -id=movie -title=streetcar -date="2025-06-13 19:00" -lookup`), "alice")
+
+	showtime, _ := bot.store.GetByID("movie")
+	if showtime == nil || showtime.Title != "streetcar" || showtime.TMDBID != 0 {
+		t.Errorf("expected manual title without TMDB metadata, got %+v", showtime)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"html/template"
+	"log"
+	"net/http"
+	"time"
+)
+
+// schedulePage renders the upcoming showtimes; html/template escapes every field
+var schedulePage = template.Must(template.New("showtimes").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Upcoming showtimes</title>
+</head>
+<body>
+<h1>Upcoming showtimes</h1>
+{{if .}}<table>
+<thead><tr><th>Time (UTC)</th><th>Title</th><th>Created by</th></tr></thead>
+<tbody>
+{{range .}}<tr id="{{.ID}}"><td>{{.DateTime.Format "2006-01-02 15:04"}}</td><td>{{.Title}}</td><td>{{.CreatedBy}}</td></tr>
+{{end}}</tbody>
+</table>{{else}}<p>No showtimes scheduled.</p>{{end}}
+</body>
+</html>
+`))
+
+// handleSchedulePage serves an HTML table of upcoming showtimes, read fresh
+// from the store on every request
+func (bot *CinemaBot) handleSchedulePage(w http.ResponseWriter, r *http.Request) {
+	showtimes, err := bot.store.Upcoming(time.Now().UTC(), 0)
+	if err != nil {
+		log.Printf("Error getting upcoming showtimes: %v", err)
+		http.Error(w, "Error retrieving showtimes", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	if err := schedulePage.Execute(w, showtimes); err != nil {
+		log.Printf("Error rendering schedule page: %v", err)
+	}
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandleSchedulePage(t *testing.T) {
+	bot, _ := newTestBot()
+	bot.store.Create(Showtime{ID: "old", Title: "Metropolis", DateTime: time.Now().UTC().Add(-24 * time.Hour), CreatedBy: "alice"})
+	bot.store.Create(Showtime{ID: "xss", Title: "<script>alert(1)</script>", DateTime: time.Date(2099, 6, 13, 19, 0, 0, 0, time.UTC), CreatedBy: "bob&co"})
+
+	rec := httptest.NewRecorder()
+	bot.handleSchedulePage(rec, httptest.NewRequest(http.MethodGet, "/showtimes.html", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	body := rec.Body.String()
+	if strings.Contains(body, "<script>") {
+		t.Error("expected title to be escaped")
+	}
+	for _, want := range []string{"&lt;script&gt;", "bob&amp;co", "2099-06-13 19:00"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected body to contain %q", want)
+		}
+	}
+	if strings.Contains(body, "Metropolis") {
+		t.Error("expected past showtimes to be omitted")
+	}
+}
+
+func TestHandleSchedulePage_Empty(t *testing.T) {
+	bot, _ := newTestBot()
+
+	rec := httptest.NewRecorder()
+	bot.handleSchedulePage(rec, httptest.NewRequest(http.MethodGet, "/showtimes.html", nil))
+
+	if !strings.Contains(rec.Body.String(), "No showtimes scheduled.") {
+		t.Errorf("expected empty schedule message, got %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+)
+
+// webhookTimeout bounds each webhook POST so a slow endpoint can't pile up goroutines
+const webhookTimeout = 10 * time.Second
+
+// WebhookPayload is the JSON body POSTed to every configured webhook
+type WebhookPayload struct {
+	Event    string   `json:"event"`
+	Showtime Showtime `json:"showtime"`
+}
+
+var webhookClient = &http.Client{Timeout: webhookTimeout}
+
+// webhookNotifier POSTs every event to each of urls in the background so IRC
+// handling is never blocked; failures are only logged
+type webhookNotifier struct {
+	urls []string
+}
+
+func (n webhookNotifier) Notify(event Event) {
+	body, err := json.Marshal(WebhookPayload{Event: event.Kind, Showtime: event.Showtime})
+	if err != nil {
+		log.Printf("Error encoding webhook payload: %v", err)
+		return
+	}
+
+	for _, url := range n.urls {
+		go func(url string) {
+			if err := postWebhook(url, body); err != nil {
+				log.Printf("Webhook %s failed for %s event: %v", url, event.Kind, err)
+			}
+		}(url)
+	}
+}
+
+func postWebhook(url string, body []byte) error {
+	resp, err := webhookClient.Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
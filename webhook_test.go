@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier(t *testing.T) {
+	received := make(chan WebhookPayload, 2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload WebhookPayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("failed to decode payload: %v", err)
+		}
+		received <- payload
+	}))
+	defer server.Close()
+
+	notifier := webhookNotifier{urls: []string{server.URL, server.URL}}
+	showtime := Showtime{ID: "movie", Title: "Casablanca", DateTime: time.Date(2025, 6, 13, 19, 0, 0, 0, time.UTC)}
+	notifier.Notify(Event{Kind: "created", Showtime: showtime})
+
+	for i := 0; i < 2; i++ {
+		select {
+		case payload := <-received:
+			if payload.Event != "created" || payload.Showtime.ID != "movie" || payload.Showtime.Title != "Casablanca" {
+				t.Errorf("unexpected payload %+v", payload)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for webhook")
+		}
+	}
+}
+
+func TestPostWebhook_ErrorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	if err := postWebhook(server.URL, []byte("{}")); err == nil {
+		t.Fatal("expected error for 500 response, got nil")
+	}
+}